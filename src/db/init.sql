@@ -53,7 +53,7 @@ create table if not exists product
     name varchar(100),
     category varchar(100),
     price float,
-    available_stock int, --// число закупленных экземпляров товара
+    available_stock int check (available_stock >= 0), --// число закупленных экземпляров товара
     last_update_date timestamp, --// число последней закупки
     supplier_id uuid,
     foreign key (supplier_id) references supplier(id),
@@ -72,4 +72,467 @@ create table if not exists client
     registration_date timestamp,
     address_id        uuid,
     foreign key (address_id) references address(id)
-);
\ No newline at end of file
+);
+
+--     users
+-- {
+--     id
+--     login
+--     password_hash
+--     role // admin | catalog_manager | warehouse | read_only
+--     created_at
+-- }
+
+create extension if not exists pgcrypto;
+
+create table if not exists users
+(
+    id            uuid primary key,
+    login         varchar(100) not null unique,
+    password_hash varchar(100) not null,
+    role          varchar(20)  not null default 'read_only',
+    created_at    timestamp    not null default now(),
+    check (role in ('admin', 'catalog_manager', 'warehouse', 'read_only'))
+);
+
+-- первого администратора создаёт сервис из ADMIN_LOGIN и ADMIN_PASSWORD
+
+
+--     auth_token
+-- {
+--     token_hash // sha256 от выданного токена
+--     kind       // access | refresh
+--     user_id
+--     family_id  // общий для всех токенов одного входа
+--     expires_at
+--     used_at    // когда refresh-токен был обменян на новую пару
+--     created_at
+-- }
+
+create table if not exists auth_token
+(
+    token_hash char(64) primary key,
+    kind       varchar(10) not null,
+    user_id    uuid        not null,
+    family_id  uuid        not null,
+    expires_at timestamp   not null,
+    used_at    timestamp,
+    created_at timestamp   not null default now(),
+    foreign key (user_id) references users(id) on delete cascade
+);
+
+create index if not exists auth_token_user_id_idx on auth_token (user_id);
+create index if not exists auth_token_family_id_idx on auth_token (family_id);
+create index if not exists auth_token_expires_at_idx on auth_token (expires_at);
+
+
+--     api_key
+-- {
+--     id
+--     name
+--     prefix       // видимая часть ключа
+--     key_hash     // sha256 от ключа целиком
+--     scopes
+--     created_by
+--     created_at
+--     last_used_at
+--     revoked_at
+-- }
+
+create table if not exists api_key
+(
+    id           uuid primary key,
+    name         varchar(100) not null,
+    prefix       varchar(20)  not null,
+    key_hash     char(64)     not null unique,
+    scopes       text[]       not null default '{}',
+    created_by   uuid,
+    created_at   timestamp    not null default now(),
+    last_used_at timestamp,
+    revoked_at   timestamp,
+    foreign key (created_by) references users(id) on delete set null
+);
+
+
+--     orders
+-- {
+--     id
+--     client_id
+--     status // new | paid | shipped | delivered | cancelled
+--     total
+--     created_at
+--     updated_at
+-- }
+
+create table if not exists orders
+(
+    id         uuid primary key,
+    client_id  uuid        not null,
+    status     varchar(20) not null default 'new',
+    total      float       not null default 0,
+    created_at timestamp   not null default now(),
+    updated_at timestamp   not null default now(),
+    foreign key (client_id) references client(id),
+    check (status in ('new', 'paid', 'shipped', 'delivered', 'cancelled'))
+);
+
+create index if not exists orders_client_id_idx on orders (client_id);
+
+--     order_item
+-- {
+--     id
+--     order_id
+--     product_id // null, если товар удалён физически; позиция и цена остаются
+--     quantity
+--     unit_price // цена товара на момент заказа
+-- }
+
+create table if not exists order_item
+(
+    id         uuid primary key,
+    order_id   uuid  not null,
+    product_id uuid,
+    quantity   int   not null check (quantity > 0),
+    unit_price float not null,
+    foreign key (order_id) references orders(id) on delete cascade,
+    foreign key (product_id) references product(id) on delete set null
+);
+
+create index if not exists order_item_order_id_idx on order_item (order_id);
+
+
+--     cart_item
+-- {
+--     client_id
+--     product_id // без внешнего ключа: удалённый товар остаётся в корзине и помечается
+--     quantity
+--     added_at
+-- }
+
+create table if not exists cart_item
+(
+    client_id  uuid      not null,
+    product_id uuid      not null,
+    quantity   int       not null check (quantity > 0),
+    added_at   timestamp not null default now(),
+    primary key (client_id, product_id),
+    foreign key (client_id) references client(id) on delete cascade
+);
+
+
+--     stock_movement
+-- {
+--     id
+--     product_id // null, если товар удалён физически
+--     delta // изменение остатка со знаком
+--     reason
+--     actor // id пользователя или apikey:<id>
+--     created_at
+-- }
+
+create table if not exists stock_movement
+(
+    id         uuid primary key,
+    product_id uuid,
+    delta      int          not null check (delta <> 0),
+    reason     varchar(20)  not null check (reason in ('purchase', 'sale', 'adjustment', 'return', 'write-off')),
+    actor      varchar(100) not null,
+    created_at timestamp    not null default now(),
+    foreign key (product_id) references product(id) on delete set null
+);
+
+create index if not exists stock_movement_product_id_created_at_idx on stock_movement (product_id, created_at);
+
+
+--     purchase_order
+-- {
+--     id
+--     supplier_id
+--     status // open, partially_received, received, cancelled
+--     created_at
+--     updated_at
+-- }
+
+create table if not exists purchase_order
+(
+    id          uuid primary key,
+    supplier_id uuid        not null,
+    status      varchar(20) not null default 'open' check (status in ('open', 'partially_received', 'received', 'cancelled')),
+    created_at  timestamp   not null default now(),
+    updated_at  timestamp   not null default now(),
+    foreign key (supplier_id) references supplier(id)
+);
+
+create index if not exists purchase_order_supplier_id_status_idx on purchase_order (supplier_id, status);
+
+
+--     purchase_order_item
+-- {
+--     id
+--     purchase_order_id
+--     product_id
+--     quantity
+--     received_quantity // сколько уже принято на склад
+--     unit_cost // закупочная цена
+-- }
+
+create table if not exists purchase_order_item
+(
+    id                uuid primary key,
+    purchase_order_id uuid  not null,
+    product_id        uuid  not null,
+    quantity          int   not null check (quantity > 0),
+    received_quantity int   not null default 0 check (received_quantity >= 0 and received_quantity <= quantity),
+    unit_cost         float not null default 0 check (unit_cost >= 0),
+    foreign key (purchase_order_id) references purchase_order(id) on delete cascade,
+    foreign key (product_id) references product(id),
+    unique (purchase_order_id, product_id)
+);
+
+
+-- индексы для keyset-пагинации и фильтров списка товаров
+create index if not exists product_name_id_idx on product (name, id);
+create index if not exists product_price_id_idx on product (price, id);
+create index if not exists product_available_stock_id_idx on product (available_stock, id);
+create index if not exists product_last_update_date_id_idx on product (last_update_date, id);
+create index if not exists product_category_idx on product (category);
+create index if not exists product_supplier_id_idx on product (supplier_id);
+
+
+-- полнотекстовый поиск по товарам: tsvector по названию и категории и триграммы для опечаток
+create extension if not exists pg_trgm;
+
+alter table product add column if not exists search_vector tsvector
+    generated always as (
+        setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(category, '')), 'B')
+    ) stored;
+
+create index if not exists product_search_vector_idx on product using gin (search_vector);
+create index if not exists product_name_trgm_idx on product using gin (name gin_trgm_ops);
+create index if not exists product_name_lower_pattern_idx on product (lower(name) text_pattern_ops);
+
+
+--     client_address
+-- {
+--     id
+--     client_id
+--     address_id
+--     label // home, work ...
+--     is_default_shipping
+--     is_default_billing
+--     created_at
+-- }
+
+create table if not exists client_address
+(
+    id                  uuid primary key,
+    client_id           uuid        not null,
+    address_id          uuid        not null,
+    label               varchar(30) not null,
+    is_default_shipping boolean     not null default false,
+    is_default_billing  boolean     not null default false,
+    created_at          timestamp   not null default now(),
+    foreign key (client_id) references client(id) on delete cascade,
+    foreign key (address_id) references address(id)
+);
+
+create index if not exists client_address_client_id_idx on client_address (client_id);
+-- не больше одного адреса по умолчанию каждого вида на клиента
+create unique index if not exists client_address_default_shipping_idx on client_address (client_id) where is_default_shipping;
+create unique index if not exists client_address_default_billing_idx on client_address (client_id) where is_default_billing;
+
+-- адресные книги клиентов, созданных до её появления, заполняет команда migrate
+
+-- заказ хранит копии адресов, чтобы правка адресной книги не меняла историю
+alter table orders add column if not exists shipping_country varchar(10);
+alter table orders add column if not exists shipping_city varchar(30);
+alter table orders add column if not exists shipping_street varchar(100);
+alter table orders add column if not exists billing_country varchar(10);
+alter table orders add column if not exists billing_city varchar(30);
+alter table orders add column if not exists billing_street varchar(100);
+
+--     soft delete
+-- {
+--     deleted_at // когда запись помечена удалённой, null — активна
+-- }
+
+alter table client add column if not exists deleted_at timestamp;
+alter table supplier add column if not exists deleted_at timestamp;
+alter table product add column if not exists deleted_at timestamp;
+
+--     images metadata
+-- {
+--     filename  // имя файла при загрузке
+--     mime_type // определяется по содержимому
+--     size      // байт
+--     width
+--     height
+--     sha256
+-- }
+
+alter table images add column if not exists filename varchar(255);
+alter table images add column if not exists mime_type varchar(50);
+alter table images add column if not exists size bigint;
+alter table images add column if not exists width int;
+alter table images add column if not exists height int;
+alter table images add column if not exists sha256 char(64);
+
+-- метаданные изображений, загруженных до их появления, заполняет команда migrate
+
+--     product_image
+-- {
+--     product_id
+--     image_id
+--     position   // порядок в галерее, с нуля
+--     is_primary // основное изображение, у товара не больше одного
+-- }
+-- product.image_id остаётся копией основного изображения для старых клиентов
+
+create table if not exists product_image
+(
+    product_id uuid not null references product (id) on delete cascade,
+    image_id   uuid not null references images (id) on delete cascade,
+    position   int not null default 0,
+    is_primary boolean not null default false,
+    primary key (product_id, image_id)
+);
+
+create index if not exists product_image_image_id_idx on product_image (image_id);
+create unique index if not exists product_image_primary_idx on product_image (product_id) where is_primary;
+
+-- изображение из product.image_id становится первым и основным в галерее
+insert into product_image (product_id, image_id, position, is_primary)
+select p.id, p.image_id, 0, true
+from product p
+where p.image_id is not null
+  and not exists (select 1 from product_image pi where pi.product_id = p.id);
+
+--     image_variant
+-- {
+--     image_id
+--     variant       // ключ варианта, например 200x200-cover.webp
+--     source_sha256 // sha256 исходника; вариант от старого файла не отдаётся
+--     blob_key      // ключ файла варианта в хранилище изображений
+--     mime_type
+--     size
+--     width
+--     height
+--     created_at
+-- }
+-- кэш уменьшенных изображений, заполняется при первом запросе варианта
+
+create table if not exists image_variant
+(
+    image_id      uuid not null references images (id) on delete cascade,
+    variant       varchar(50) not null,
+    source_sha256 char(64) not null,
+    blob_key      varchar(200) not null,
+    mime_type     varchar(50) not null,
+    size          bigint not null,
+    width         int not null,
+    height        int not null,
+    created_at    timestamp not null default now(),
+    primary key (image_id, variant)
+);
+
+--     image_blob
+-- {
+--     key  // ключ файла изображения, images.blob_key
+--     data
+-- }
+-- содержимое изображений хранится отдельно от images; в этой таблице — если
+-- выбрано хранилище postgres (BLOB_STORE), иначе на диске или в S3
+
+create table if not exists image_blob
+(
+    key  varchar(200) primary key,
+    data bytea not null
+);
+
+alter table images add column if not exists blob_key varchar(200);
+create unique index if not exists images_blob_key_idx on images (blob_key);
+-- файлы, загруженные до появления blob_key, переносит из images.image команда migrate
+
+--     image_blob_deletion
+-- {
+--     key        // файл удалённого или заменённого изображения
+--     created_at
+-- }
+-- очередь файлов на удаление из хранилища; её разбирает фоновая задача сервиса
+
+create table if not exists image_blob_deletion
+(
+    key        varchar(200) primary key,
+    created_at timestamp not null default now()
+);
+
+create or replace function enqueue_image_blob_deletion() returns trigger as
+$$
+begin
+    if old.blob_key is not null then
+        insert into image_blob_deletion (key) values (old.blob_key) on conflict (key) do nothing;
+    end if;
+    return old;
+end;
+$$ language plpgsql;
+
+drop trigger if exists images_blob_deletion on images;
+create trigger images_blob_deletion
+    after delete on images
+    for each row execute function enqueue_image_blob_deletion();
+
+--     images updated_at
+-- {
+--     updated_at // когда загружен текущий файл, для Last-Modified
+-- }
+
+alter table images add column if not exists updated_at timestamp not null default now();
+
+--     stock_movement product_id
+-- {
+--     product_id // при физическом удалении товара журнал остаётся
+-- }
+
+alter table stock_movement alter column product_id drop not null;
+alter table stock_movement drop constraint if exists stock_movement_product_id_fkey;
+alter table stock_movement add constraint stock_movement_product_id_fkey
+    foreign key (product_id) references product(id) on delete set null;
+
+--     image_variant blob_key
+-- {
+--     blob_key // ключ файла варианта в хранилище изображений; в таблице остаются метаданные
+-- }
+-- варианты без blob_key, закэшированные в самой таблице, не отдаются; их удаляет команда migrate
+
+alter table image_variant add column if not exists blob_key varchar(200);
+alter table image_variant drop column if exists image;
+
+-- файлы сброшенных вариантов, в том числе удалённых вместе с изображением, ставятся в очередь на удаление
+drop trigger if exists image_variant_blob_deletion on image_variant;
+create trigger image_variant_blob_deletion
+    after delete on image_variant
+    for each row execute function enqueue_image_blob_deletion();
+
+--     order_item product_id
+-- {
+--     product_id // при физическом удалении товара позиции отменённых заказов остаются
+-- }
+
+alter table order_item alter column product_id drop not null;
+alter table order_item drop constraint if exists order_item_product_id_fkey;
+alter table order_item add constraint order_item_product_id_fkey
+    foreign key (product_id) references product(id) on delete set null;
+
+--     data_migration
+-- {
+--     name       // шаг переноса данных из cmd/migrate
+--     applied_at
+-- }
+-- init.sql описывает только схему; переносы данных выполняет команда migrate, по одному разу
+
+create table if not exists data_migration
+(
+    name       varchar(100) primary key,
+    applied_at timestamp not null default now()
+);
@@ -0,0 +1,122 @@
+// Команда blobmigrate переносит файлы изображений из одного хранилища в другое.
+//
+//	blobmigrate -from postgres -to s3 [-delete]
+//
+// Настройки хранилищ берутся из тех же переменных окружения, что и у сервиса
+// (BLOB_DIR, S3_*), база — из DATABASE_URL. Переносятся файлы всех изображений
+// из таблицы images и их вариантов из image_variant; уже перенесённые
+// пропускаются, поэтому команду можно запускать повторно. Порядок переезда:
+// перенести файлы, переключить BLOB_STORE сервиса на новое хранилище и
+// запустить перенос ещё раз, чтобы забрать загруженное за это время. С -delete файл удаляется из старого
+// хранилища после того, как записан в новое.
+package main
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/blobstore"
+	"backend2/internal/db"
+	"backend2/internal/repository"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+)
+
+func main() {
+	from := flag.String("from", "", "хранилище, из которого переносятся файлы: postgres, fs или s3")
+	to := flag.String("to", "", "хранилище, в которое переносятся файлы: postgres, fs или s3")
+	deleteSource := flag.Bool("delete", false, "удалять файл из старого хранилища после переноса")
+	batch := flag.Int("batch", 100, "сколько ключей читать из базы за раз")
+	flag.Parse()
+
+	if *from == "" || *to == "" || *from == *to || *batch <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	database, err := db.Connection()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer database.Close()
+
+	cfg := blobstore.ConfigFromEnv()
+	cfg.Kind = *from
+	src, err := blobstore.Open(cfg, database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg.Kind = *to
+	dst, err := blobstore.Open(cfg, database)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	stats, err := migrate(context.Background(), repository.NewImageRepo(database, dst), src, dst, *batch, *deleteSource)
+	log.Printf("moved %d, already moved %d, missing %d", stats.moved, stats.skipped, stats.missing)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if stats.missing > 0 {
+		os.Exit(1)
+	}
+}
+
+type migrateStats struct {
+	moved, skipped, missing int
+}
+
+func migrate(ctx context.Context, images *repository.ImageRepo, src, dst blobstore.Store, batch int, deleteSource bool) (migrateStats, error) {
+	var stats migrateStats
+	after := ""
+	for {
+		keys, err := images.BlobKeys(ctx, after, batch)
+		if err != nil {
+			return stats, err
+		}
+		if len(keys) == 0 {
+			return stats, nil
+		}
+		after = keys[len(keys)-1]
+
+		for _, key := range keys {
+			data, err := src.Get(ctx, key)
+			if errors.Is(err, apperr.ErrBlobNotFound) {
+				// файла нет в старом хранилище: либо он уже перенесён, либо потерян
+				if _, err := dst.Get(ctx, key); err == nil {
+					stats.skipped++
+					continue
+				}
+				log.Printf("%s: file is missing in both stores", key)
+				stats.missing++
+				continue
+			}
+			if err != nil {
+				return stats, err
+			}
+
+			// ключ изображения заканчивается sha256 содержимого; у варианта в ключе
+			// sha256 исходника, сверить его с содержимым нельзя
+			sum := sha256.Sum256(data)
+			if !strings.Contains(key, "/variants/") && path.Base(key) != hex.EncodeToString(sum[:]) {
+				return stats, fmt.Errorf("%s: content does not match its sha256", key)
+			}
+
+			if err = dst.Put(ctx, key, data); err != nil {
+				return stats, err
+			}
+			if deleteSource {
+				if err = src.Delete(ctx, key); err != nil {
+					return stats, err
+				}
+			}
+			stats.moved++
+		}
+	}
+}
@@ -2,10 +2,23 @@ package main
 
 import (
 	_ "backend2/docs"
+	"backend2/internal/auth"
+	"backend2/internal/blobstore"
+	"backend2/internal/entity"
+	addresshandler "backend2/internal/handlers/address"
+	apikeyhandler "backend2/internal/handlers/apikey"
+	authhandler "backend2/internal/handlers/auth"
+	carthandler "backend2/internal/handlers/cart"
 	"backend2/internal/handlers/image"
+	orderhandler "backend2/internal/handlers/order"
+	purchaseorderhandler "backend2/internal/handlers/purchaseorder"
+	"backend2/internal/middleware"
+	"context"
 	httpSwagger "github.com/swaggo/http-swagger"
+	"log"
+	"os"
+	"time"
 
-	//"backend2/internal/auth"
 	"backend2/internal/db"
 	_ "backend2/internal/dto"
 	_ "backend2/internal/handlers/client"
@@ -26,72 +39,191 @@ import (
 // @description  Документация для API интернет-магазина
 // @host         localhost:8080
 // @BasePath     /api/v1
+// @securityDefinitions.apikey BearerAuth
+// @in           header
+// @name         Authorization
+// @securityDefinitions.apikey APIKeyAuth
+// @in           header
+// @name         X-API-Key
 func main() {
 	database, err := db.Connection()
 	if err != nil {
 		panic(err)
 	}
 
-	//tokenStore := auth.NewInMemoryTokenStore()
-	//authUsecase := auth.NewAuthUsecase([]byte("my-secret-key"), tokenStore)
-	//authHandler := a.NewAuthHandler(authUsecase)
+	// без секрета токены подписывались бы известным ключом, поэтому сервис не запускается
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		panic("JWT_SECRET is not set")
+	}
+
+	// таймауты запросов: HTTP_TIMEOUT — по умолчанию, HTTP_ROUTE_TIMEOUTS — для отдельных маршрутов
+	timeouts := middleware.RouteTimeouts{Default: 5 * time.Second}
+	if v := os.Getenv("HTTP_TIMEOUT"); v != "" {
+		timeouts.Default, err = time.ParseDuration(v)
+		if err != nil {
+			panic(err)
+		}
+	}
+	timeouts.Routes, err = middleware.ParseRouteTimeouts(os.Getenv("HTTP_ROUTE_TIMEOUTS"))
+	if err != nil {
+		panic(err)
+	}
+
+	// хранилище файлов изображений выбирается BLOB_STORE: postgres, fs или s3
+	blobs, err := blobstore.Open(blobstore.ConfigFromEnv(), database)
+	if err != nil {
+		panic(err)
+	}
 
+	userRepo := repository.NewUserRepo(database)
+	tokenStore := repository.NewTokenRepo(database)
+	go auth.RunSweeper(context.Background(), tokenStore, 10*time.Minute)
+	apiKeyRepo := repository.NewAPIKeyRepo(database)
+	authUsecase := auth.NewAuthUsecase([]byte(secret), tokenStore, userRepo, apiKeyRepo)
+	authHandler := authhandler.NewAuthHandler(authUsecase)
+	// первый администратор создаётся из ADMIN_LOGIN (по умолчанию admin) и ADMIN_PASSWORD
+	if password := os.Getenv("ADMIN_PASSWORD"); password != "" {
+		login := os.Getenv("ADMIN_LOGIN")
+		if login == "" {
+			login = "admin"
+		}
+		created, err := authUsecase.BootstrapAdmin(context.Background(), login, password)
+		if err != nil {
+			panic(err)
+		}
+		if created {
+			log.Printf("created admin user %s", login)
+		}
+	}
+	apiKeyHandler := apikeyhandler.NewAPIKeyHandler(authUsecase)
+
+	txManager := repository.NewTxManager(database, blobs)
 	repoAdr := repository.NewAddressRepo(database)
 	//
 	clientRepo := repository.NewClientRepo(database)
-	client := usecases.NewClient(clientRepo, repoAdr)
+	client := usecases.NewClient(clientRepo, txManager)
 	clientHandler := clienthandler.NewClientHandler(client)
+	clientAddress := usecases.NewClientAddress(repoAdr, clientRepo)
+	addressHandler := addresshandler.NewAddressHandler(clientAddress)
 	//
 	supplierRepo := repository.NewSupplier(database)
-	supplier := usecases.NewSupplier(supplierRepo, repoAdr)
+	supplier := usecases.NewSupplier(supplierRepo, txManager)
 	supplierHandler := suplierhandler.NewSupplierHandler(supplier)
 	//
-	imgRepo := repository.NewImageRepo(database)
+	imgRepo := repository.NewImageRepo(database, blobs)
 	img := usecases.NewImage(imgRepo)
+	go img.RunBlobSweeper(context.Background(), time.Minute)
 	imgHandler := image.NewImageHandler(img)
 	//
 	productRepo := repository.NewProductRepo(database)
-	product := usecases.NewProduct(productRepo, supplierRepo, imgRepo)
+	movementRepo := repository.NewStockMovementRepo(database)
+	product := usecases.NewProduct(productRepo, supplierRepo, imgRepo, movementRepo, txManager)
 	productHandler := producthandler.NewProductHandler(product)
 	//
+	orderRepo := repository.NewOrderRepo(database)
+	order := usecases.NewOrder(orderRepo)
+	orderHandler := orderhandler.NewOrderHandler(order)
+	//
+	cartRepo := repository.NewCartRepo(database)
+	cart := usecases.NewCart(cartRepo, productRepo, clientRepo, txManager)
+	cartHandler := carthandler.NewCartHandler(cart)
+	//
+	purchaseOrderRepo := repository.NewPurchaseOrderRepo(database)
+	purchaseOrder := usecases.NewPurchaseOrder(purchaseOrderRepo, supplierRepo, productRepo)
+	purchaseOrderHandler := purchaseorderhandler.NewPurchaseOrderHandler(purchaseOrder)
+	//
 	// основной роутер
 	router := mux.NewRouter()
 	router.StrictSlash(true)
+	router.Use(middleware.Timeout(timeouts))
 
-	// открытый маршрут
-	//router.HandleFunc("/token", authHandler.GetToken).Methods(http.MethodGet)
-	//
-	//// защищённые маршруты
-	//protected := router.PathPrefix("/").Subrouter()
-	//protected.Use(middleware.AuthMiddleware(authUsecase))
-	//clients
-	router.HandleFunc("/api/v1/clients", clientHandler.GetAllClients).Methods(http.MethodGet)          //+
-	router.HandleFunc("/api/v1/client", clientHandler.CreateClient).Methods(http.MethodPost)           //+
-	router.HandleFunc("/api/v1/client/{id}", clientHandler.UpdateClient).Methods(http.MethodPatch)     //+
-	router.HandleFunc("/api/v1/client", clientHandler.GetClientsByNameSurname).Methods(http.MethodGet) //+
-	router.HandleFunc("/api/v1/client/{id}", clientHandler.DeleteClient).Methods(http.MethodDelete)    //+
-	//products
+	// открытые маршруты
+	router.HandleFunc("/api/v1/login", authHandler.Login).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/token/refresh", authHandler.RefreshToken).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/clients", clientHandler.GetAllClients).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/client", clientHandler.GetClientsByNameSurname).Methods(http.MethodGet)
 	router.HandleFunc("/api/v1/products", productHandler.GetProducts).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/products/search", productHandler.SearchProducts).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/products/suggest", productHandler.SuggestProducts).Methods(http.MethodGet)
 	router.HandleFunc("/api/v1/product/{id}", productHandler.GetProductById).Methods(http.MethodGet)
-	router.HandleFunc("/api/v1/product", productHandler.CreateProduct).Methods(http.MethodPost)
-	router.HandleFunc("/api/v1/product/{id}", productHandler.DeleteProduct).Methods(http.MethodDelete)
-	router.HandleFunc("/api/v1/product/{id}", productHandler.ReduceProduct).Methods(http.MethodPatch)
-	// supplier
-	router.HandleFunc("/api/v1/supplier", supplierHandler.CreateSupplier).Methods(http.MethodPost)
 	router.HandleFunc("/api/v1/supplier/{id}", supplierHandler.GetSupplierById).Methods(http.MethodGet)
 	router.HandleFunc("/api/v1/suppliers", supplierHandler.GetAllSuppliers).Methods(http.MethodGet)
-	router.HandleFunc("/api/v1/supplier/{id}", supplierHandler.UpdateAddress).Methods(http.MethodPatch)
-	router.HandleFunc("/supplier/{id}", supplierHandler.DeleteSupplierById).Methods(http.MethodDelete)
-
-	//image
-	router.HandleFunc("/api/v1/image/{id}", imgHandler.AddImage).Methods(http.MethodPost)
-	router.HandleFunc("/api/v1/image/{id}", imgHandler.GetImageById).Methods(http.MethodGet)
-	router.HandleFunc("/api/v1/image/{id}", imgHandler.UpdateImage).Methods(http.MethodPatch)
-	router.HandleFunc("/api/v1/products/{id}/image", imgHandler.GetProductImageById).Methods(http.MethodGet)
-	router.HandleFunc("/api/v1/image/{id}", imgHandler.DeleteImage).Methods(http.MethodDelete)
+	router.HandleFunc("/api/v1/supplier/{id}/products", productHandler.GetSupplierProducts).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/image/{id}", imgHandler.GetImageById).Methods(http.MethodGet, http.MethodHead)
+	router.HandleFunc("/api/v1/products/{id}/image", imgHandler.GetProductImageById).Methods(http.MethodGet, http.MethodHead)
+	router.HandleFunc("/api/v1/products/{id}/images", imgHandler.GetProductImages).Methods(http.MethodGet)
 
 	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
+	// права доступа по ролям
+	admin := middleware.RequireRoles(entity.RoleAdmin)
+	catalog := middleware.RequireRoles(entity.RoleAdmin, entity.RoleCatalogManager)
+	warehouse := middleware.RequireRoles(entity.RoleAdmin, entity.RoleWarehouse)
+	staff := middleware.RequireRoles(entity.RoleAdmin, entity.RoleCatalogManager, entity.RoleWarehouse)
+	reader := middleware.RequireRoles(entity.RoleAdmin, entity.RoleCatalogManager, entity.RoleWarehouse, entity.RoleReadOnly)
+
+	// защищённые маршруты
+	protected := router.PathPrefix("/").Subrouter()
+	protected.Use(middleware.AuthMiddleware(authUsecase))
+	//users
+	protected.HandleFunc("/api/v1/logout", authHandler.Logout).Methods(http.MethodPost)
+	protected.Handle("/api/v1/user", admin(authHandler.CreateUser)).Methods(http.MethodPost)
+	protected.Handle("/api/v1/user/{id}/tokens", admin(authHandler.RevokeUserTokens)).Methods(http.MethodDelete)
+	//api keys
+	protected.Handle("/api/v1/apikey", admin(apiKeyHandler.CreateAPIKey)).Methods(http.MethodPost)
+	protected.Handle("/api/v1/apikeys", admin(apiKeyHandler.GetAPIKeys)).Methods(http.MethodGet)
+	protected.Handle("/api/v1/apikey/{id}/rotate", admin(apiKeyHandler.RotateAPIKey)).Methods(http.MethodPost)
+	protected.Handle("/api/v1/apikey/{id}", admin(apiKeyHandler.RevokeAPIKey)).Methods(http.MethodDelete)
+	//clients
+	protected.Handle("/api/v1/client", catalog(clientHandler.CreateClient)).Methods(http.MethodPost)
+	protected.Handle("/api/v1/client/{id}", catalog(clientHandler.UpdateClient)).Methods(http.MethodPatch)
+	protected.Handle("/api/v1/client/{id}", admin(clientHandler.DeleteClient)).Methods(http.MethodDelete)
+	protected.Handle("/api/v1/client/{id}/restore", admin(clientHandler.RestoreClient)).Methods(http.MethodPost)
+	//products
+	protected.Handle("/api/v1/product", catalog(productHandler.CreateProduct)).Methods(http.MethodPost)
+	protected.Handle("/api/v1/product/{id}", catalog(productHandler.DeleteProduct)).Methods(http.MethodDelete)
+	protected.Handle("/api/v1/product/{id}/restore", catalog(productHandler.RestoreProduct)).Methods(http.MethodPost)
+	protected.Handle("/api/v1/product/{id}", warehouse(productHandler.ReduceProduct)).Methods(http.MethodPatch)
+	protected.Handle("/api/v1/product/{id}/movements", staff(productHandler.GetMovements)).Methods(http.MethodGet)
+	// supplier
+	protected.Handle("/api/v1/supplier", catalog(supplierHandler.CreateSupplier)).Methods(http.MethodPost)
+	protected.Handle("/api/v1/supplier/{id}", catalog(supplierHandler.ReplaceSupplier)).Methods(http.MethodPut)
+	protected.Handle("/api/v1/supplier/{id}", catalog(supplierHandler.UpdateSupplier)).Methods(http.MethodPatch)
+	protected.Handle("/api/v1/supplier/{id}", admin(supplierHandler.DeleteSupplierById)).Methods(http.MethodDelete)
+	protected.Handle("/api/v1/supplier/{id}/restore", admin(supplierHandler.RestoreSupplier)).Methods(http.MethodPost)
+	//orders
+	protected.Handle("/api/v1/order", staff(orderHandler.CreateOrder)).Methods(http.MethodPost)
+	protected.Handle("/api/v1/order/{id}", staff(orderHandler.GetOrderById)).Methods(http.MethodGet)
+	protected.Handle("/api/v1/client/{id}/orders", staff(orderHandler.GetClientOrders)).Methods(http.MethodGet)
+	protected.Handle("/api/v1/order/{id}", staff(orderHandler.UpdateOrderStatus)).Methods(http.MethodPatch)
+	//client addresses
+	protected.Handle("/api/v1/client/{id}/addresses", staff(addressHandler.GetAddresses)).Methods(http.MethodGet)
+	protected.Handle("/api/v1/client/{id}/addresses", catalog(addressHandler.AddAddress)).Methods(http.MethodPost)
+	protected.Handle("/api/v1/client/{id}/addresses/{address_id}", staff(addressHandler.GetAddress)).Methods(http.MethodGet)
+	protected.Handle("/api/v1/client/{id}/addresses/{address_id}", catalog(addressHandler.UpdateAddress)).Methods(http.MethodPut)
+	protected.Handle("/api/v1/client/{id}/addresses/{address_id}", catalog(addressHandler.DeleteAddress)).Methods(http.MethodDelete)
+	//cart
+	// оформление корзины создаёт заказ и списывает остаток, поэтому права как у создания заказа
+	protected.Handle("/api/v1/client/{id}/cart", reader(cartHandler.GetCart)).Methods(http.MethodGet)
+	protected.Handle("/api/v1/client/{id}/cart", staff(cartHandler.AddItem)).Methods(http.MethodPost)
+	protected.Handle("/api/v1/client/{id}/cart/checkout", staff(cartHandler.Checkout)).Methods(http.MethodPost)
+	protected.Handle("/api/v1/client/{id}/cart/{product_id}", staff(cartHandler.UpdateItem)).Methods(http.MethodPatch)
+	protected.Handle("/api/v1/client/{id}/cart/{product_id}", staff(cartHandler.RemoveItem)).Methods(http.MethodDelete)
+	//purchase orders
+	protected.Handle("/api/v1/purchase-order", staff(purchaseOrderHandler.CreatePurchaseOrder)).Methods(http.MethodPost)
+	protected.Handle("/api/v1/purchase-order/{id}", staff(purchaseOrderHandler.GetPurchaseOrderById)).Methods(http.MethodGet)
+	protected.Handle("/api/v1/purchase-order/{id}/receive", warehouse(purchaseOrderHandler.ReceivePurchaseOrder)).Methods(http.MethodPost)
+	protected.Handle("/api/v1/purchase-order/{id}/cancel", staff(purchaseOrderHandler.CancelPurchaseOrder)).Methods(http.MethodPost)
+	protected.Handle("/api/v1/supplier/{id}/purchase-orders", staff(purchaseOrderHandler.GetSupplierPurchaseOrders)).Methods(http.MethodGet)
+	//image
+	protected.Handle("/api/v1/image/{id}", catalog(imgHandler.AddImage)).Methods(http.MethodPost)
+	protected.Handle("/api/v1/image/{id}", catalog(imgHandler.UpdateImage)).Methods(http.MethodPatch)
+	protected.Handle("/api/v1/image/{id}", catalog(imgHandler.DeleteImage)).Methods(http.MethodDelete)
+	protected.Handle("/api/v1/products/{id}/images/order", catalog(imgHandler.ReorderProductImages)).Methods(http.MethodPut)
+	protected.Handle("/api/v1/products/{id}/images/{image_id}/primary", catalog(imgHandler.SetPrimaryImage)).Methods(http.MethodPut)
+	protected.Handle("/api/v1/products/{id}/images/{image_id}", catalog(imgHandler.DetachProductImage)).Methods(http.MethodDelete)
+
 	err = http.ListenAndServe(":8080", router)
 	if err != nil {
 		panic(err)
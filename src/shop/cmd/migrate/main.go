@@ -0,0 +1,77 @@
+// Команда migrate переносит данные, оставшиеся от прежних версий схемы.
+//
+//	migrate
+//
+// db/init.sql описывает только таблицы и выполняется при каждом запуске базы,
+// а переносы данных делаются здесь, по одному разу: выполненный шаг
+// записывается в таблицу data_migration, и при следующем запуске пропускается.
+// Шаги выполняются по порядку, каждый можно безопасно прервать и запустить
+// заново. База берётся из DATABASE_URL, хранилище файлов изображений — из тех
+// же переменных окружения, что и у сервиса (BLOB_STORE, BLOB_DIR, S3_*).
+// Контейнер сервиса запускает команду перед стартом.
+package main
+
+import (
+	"backend2/internal/blobstore"
+	"backend2/internal/db"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// migration — шаг переноса данных. name записывается в data_migration и не меняется.
+type migration struct {
+	name string
+	run  func(ctx context.Context, env migrationEnv) error
+}
+
+type migrationEnv struct {
+	db    *sql.DB
+	blobs blobstore.Store
+}
+
+func main() {
+	database, err := db.Connection()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer database.Close()
+
+	blobs, err := blobstore.Open(blobstore.ConfigFromEnv(), database)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	env := migrationEnv{db: database, blobs: blobs}
+	if err = migrate(context.Background(), env, migrations); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func migrate(ctx context.Context, env migrationEnv, steps []migration) error {
+	for _, step := range steps {
+		var applied bool
+		err := env.db.QueryRowContext(ctx,
+			`SELECT EXISTS(SELECT 1 FROM data_migration WHERE name = $1)`, step.name,
+		).Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("failed to check migration %s: %w", step.name, err)
+		}
+		if applied {
+			continue
+		}
+
+		if err = step.run(ctx, env); err != nil {
+			return fmt.Errorf("migration %s: %w", step.name, err)
+		}
+		_, err = env.db.ExecContext(ctx,
+			`INSERT INTO data_migration (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, step.name,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", step.name, err)
+		}
+		log.Printf("applied migration %s", step.name)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"backend2/internal/repository"
+	"context"
+	"log"
+)
+
+// migrations — шаги в порядке выполнения. Метаданные считаются по images.image,
+// поэтому идут раньше переноса файлов в хранилище.
+var migrations = []migration{
+	{name: "image_metadata", run: sqlMigration(imageMetadata)},
+	{name: "image_files_to_blob_store", run: moveImageFiles},
+	{name: "client_address_book", run: sqlMigration(clientAddressBook)},
+}
+
+// sqlMigration — шаг из одного SQL-запроса.
+func sqlMigration(query string) func(ctx context.Context, env migrationEnv) error {
+	return func(ctx context.Context, env migrationEnv) error {
+		_, err := env.db.ExecContext(ctx, query)
+		return err
+	}
+}
+
+// clientAddressBook делает адрес из карточки клиента первым адресом в его адресной книге.
+const clientAddressBook = `
+	INSERT INTO client_address (id, client_id, address_id, label, is_default_shipping, is_default_billing, created_at)
+	SELECT gen_random_uuid(), c.id, c.address_id, 'home', true, true, coalesce(c.registration_date, now())
+	FROM client c
+	WHERE c.address_id IS NOT NULL
+	  AND NOT EXISTS (SELECT 1 FROM client_address ca WHERE ca.client_id = c.id)
+`
+
+// moveImageFiles переносит файлы изображений из images.image в хранилище,
+// выбранное BLOB_STORE, и удаляет варианты, закэшированные в самой таблице.
+func moveImageFiles(ctx context.Context, env migrationEnv) error {
+	moved, err := repository.NewImageRepo(env.db, env.blobs).MoveLegacyFiles(ctx, 20)
+	if moved > 0 {
+		log.Printf("moved %d image files to the blob store", moved)
+	}
+	return err
+}
+
+// imageMetadata заполняет метаданные изображений, загруженных до их появления;
+// размеры в пикселях остаются пустыми.
+const imageMetadata = `
+	UPDATE images SET
+		size = octet_length(image),
+		sha256 = encode(sha256(image), 'hex'),
+		mime_type = CASE
+			WHEN substring(image FROM 1 FOR 3) = '\xffd8ff'::bytea THEN 'image/jpeg'
+			WHEN substring(image FROM 1 FOR 8) = '\x89504e470d0a1a0a'::bytea THEN 'image/png'
+			WHEN substring(image FROM 1 FOR 4) = '\x47494638'::bytea THEN 'image/gif'
+			WHEN substring(image FROM 1 FOR 4) = '\x52494646'::bytea
+			 AND substring(image FROM 9 FOR 4) = '\x57454250'::bytea THEN 'image/webp'
+			ELSE 'application/octet-stream'
+		END
+	WHERE mime_type IS NULL AND image IS NOT NULL
+`
@@ -1,6 +1,10 @@
 package apperr
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 // client errors
 var (
@@ -16,6 +20,8 @@ var (
 	ErrProductInsert   = errors.New("failed to insert product")
 	ErrProductUpdate   = errors.New("failed to update product")
 	ErrProductDelete   = errors.New("failed to delete product")
+	ErrInvalidCursor   = errors.New("invalid cursor")
+	ErrEmptySearch     = errors.New("empty search query")
 )
 
 // supplier errors
@@ -32,4 +38,109 @@ var (
 	ErrImageInsert   = errors.New("failed to insert image")
 	ErrImageUpdate   = errors.New("failed to update image")
 	ErrImageDelete   = errors.New("failed to delete image")
+	// ErrImageUnsupported — загружено не изображение или формат не из JPEG, PNG, WebP, GIF.
+	ErrImageUnsupported = errors.New("unsupported image type")
+	// ErrImageOrderMismatch — новый порядок галереи должен перечислять все её изображения ровно по разу.
+	ErrImageOrderMismatch = errors.New("image order must list every product image exactly once")
+	// ErrImageTooLarge — в изображении слишком много пикселей, чтобы безопасно его декодировать.
+	ErrImageTooLarge = errors.New("image dimensions are too large")
+	// ErrImageVariantInvalid — запрошены размеры, способ вписывания или формат вне допустимых.
+	ErrImageVariantInvalid = errors.New("invalid image variant")
+)
+
+// blob storage errors
+var (
+	ErrBlobNotFound = errors.New("blob not found")
+	ErrBlobStore    = errors.New("blob storage failure")
+)
+
+// auth errors
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUserExists         = errors.New("user already exists")
+	ErrUserInsert         = errors.New("failed to insert user")
+	ErrInvalidCredentials = errors.New("invalid login or password")
+	ErrInvalidToken       = errors.New("invalid token")
+)
+
+// refresh token errors
+var (
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+)
+
+// api key errors
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrAPIKeyInsert   = errors.New("failed to insert api key")
+	ErrAPIKeyUpdate   = errors.New("failed to update api key")
+)
+
+// order errors
+var (
+	ErrOrderNotFound       = errors.New("order not found")
+	ErrOrderInsert         = errors.New("failed to insert order")
+	ErrOrderUpdate         = errors.New("failed to update order")
+	ErrOrderEmpty          = errors.New("order has no items")
+	ErrOrderStatus         = errors.New("order status transition not allowed")
+	ErrOrderStatusConflict = errors.New("order status was changed concurrently")
+	ErrInsufficientStock   = errors.New("insufficient product stock")
 )
+
+// cart errors
+var (
+	ErrCartItemNotFound = errors.New("cart item not found")
+	ErrCartEmpty        = errors.New("cart is empty")
+	ErrCartUnavailable  = errors.New("cart has unavailable items")
+	ErrCartUpdate       = errors.New("failed to update cart")
+)
+
+// purchase order errors
+var (
+	ErrPurchaseOrderNotFound         = errors.New("purchase order not found")
+	ErrPurchaseOrderInsert           = errors.New("failed to insert purchase order")
+	ErrPurchaseOrderUpdate           = errors.New("failed to update purchase order")
+	ErrPurchaseOrderEmpty            = errors.New("purchase order has no items")
+	ErrPurchaseOrderClosed           = errors.New("purchase order is already closed")
+	ErrPurchaseOrderItemNotFound     = errors.New("product is not in purchase order")
+	ErrPurchaseOrderOverReceive      = errors.New("received quantity exceeds ordered quantity")
+	ErrPurchaseOrderSupplierMismatch = errors.New("product belongs to another supplier")
+)
+
+// address errors
+var (
+	ErrAddressNotFound = errors.New("address not found")
+	ErrAddressInsert   = errors.New("failed to insert address")
+	ErrAddressUpdate   = errors.New("failed to update address")
+	ErrAddressDelete   = errors.New("failed to delete address")
+)
+
+// delete errors
+var (
+	ErrHasDependents = errors.New("resource has dependents")
+)
+
+// Dependent — записи одного вида, из-за которых нельзя удалить ресурс.
+// Ids содержит не больше первых сотни id, Count — сколько их всего.
+type Dependent struct {
+	Kind  string
+	Count int
+	Ids   []string
+}
+
+// DependentsError возвращается при удалении без cascade, если на ресурс ссылаются другие записи.
+type DependentsError struct {
+	Dependents []Dependent
+}
+
+func (e *DependentsError) Error() string {
+	parts := make([]string, 0, len(e.Dependents))
+	for _, d := range e.Dependents {
+		parts = append(parts, fmt.Sprintf("%d %s", d.Count, d.Kind))
+	}
+	return fmt.Sprintf("%v: %s", ErrHasDependents, strings.Join(parts, ", "))
+}
+
+func (e *DependentsError) Unwrap() error {
+	return ErrHasDependents
+}
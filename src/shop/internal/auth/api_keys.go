@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"backend2/internal/utils"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Ключ имеет вид shop_<prefix>_<secret>. Полностью он показывается только при
+// создании и ротации, в базе хранится sha256 от него и видимый префикс.
+const apiKeyPrefix = "shop_"
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateAPIKey() (key, prefix string, err error) {
+	id, err := utils.GenerateUUID()
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	prefix = apiKeyPrefix + id[:8]
+	return prefix + "_" + secret, prefix, nil
+}
+
+// CreateAPIKey создаёт ключ и возвращает его вместе с открытым значением.
+// Пустой createdBy означает, что ключ создан не пользователем, а другим ключом.
+func (a *AuthUsecase) CreateAPIKey(ctx context.Context, name string, scopes []string, createdBy string) (entity.APIKey, string, error) {
+	id, err := utils.GenerateUUID()
+	if err != nil {
+		return entity.APIKey{}, "", fmt.Errorf("failed to generate api key id: %w", err)
+	}
+
+	rawKey, prefix, err := generateAPIKey()
+	if err != nil {
+		return entity.APIKey{}, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	var creator *string
+	if createdBy != "" {
+		creator = &createdBy
+	}
+
+	key, err := a.apiKeys.CreateAPIKey(ctx, entity.APIKey{
+		Id:        id,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   hashAPIKey(rawKey),
+		Scopes:    scopes,
+		CreatedBy: creator,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return entity.APIKey{}, "", fmt.Errorf("failed to create api key: %w", err)
+	}
+	return key, rawKey, nil
+}
+
+func (a *AuthUsecase) GetAPIKeys(ctx context.Context) ([]entity.APIKey, error) {
+	keys, err := a.apiKeys.GetAPIKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RotateAPIKey выдаёт ключу новый секрет, старый перестаёт работать сразу.
+func (a *AuthUsecase) RotateAPIKey(ctx context.Context, id string) (entity.APIKey, string, error) {
+	rawKey, prefix, err := generateAPIKey()
+	if err != nil {
+		return entity.APIKey{}, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	key, err := a.apiKeys.RotateAPIKey(ctx, id, prefix, hashAPIKey(rawKey))
+	if err != nil {
+		return entity.APIKey{}, "", fmt.Errorf("failed to rotate api key: %w", err)
+	}
+	return key, rawKey, nil
+}
+
+func (a *AuthUsecase) RevokeAPIKey(ctx context.Context, id string) error {
+	err := a.apiKeys.RevokeAPIKey(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	return nil
+}
+
+func (a *AuthUsecase) ValidateAPIKey(ctx context.Context, rawKey string) (entity.Principal, bool) {
+	key, err := a.apiKeys.UseAPIKey(ctx, hashAPIKey(rawKey))
+	if err != nil {
+		if !errors.Is(err, apperr.ErrAPIKeyNotFound) {
+			log.Printf("failed to validate api key: %v", err)
+		}
+		return entity.Principal{}, false
+	}
+	return entity.Principal{APIKeyID: key.Id, Scopes: key.Scopes}, true
+}
@@ -1,45 +1,126 @@
 package auth
 
-import "sync"
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"context"
+	"sync"
+	"time"
+)
 
 type TokenStore interface {
-	Save(token, userID string)
-	IsValid(token string) bool
-	GetUserID(token string) (string, bool)
-	Delete(token string)
+	Save(ctx context.Context, token entity.Token) error
+	IsValid(ctx context.Context, token string) bool
+	GetUserID(ctx context.Context, token string) (string, bool)
+	Delete(ctx context.Context, token string) error
+	DeleteFamily(ctx context.Context, familyID string) error
+	DeleteByUserID(ctx context.Context, userID string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+	// UseRefreshToken атомарно помечает refresh-токен использованным.
+	// Для уже использованного токена возвращает его вместе с apperr.ErrRefreshTokenReused.
+	UseRefreshToken(ctx context.Context, token string) (entity.Token, error)
+}
+
+type UserRepository interface {
+	CreateUser(ctx context.Context, user entity.User) (entity.User, error)
+	GetUserByLogin(ctx context.Context, login string) (entity.User, error)
+	GetUserById(ctx context.Context, id string) (entity.User, error)
+}
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key entity.APIKey) (entity.APIKey, error)
+	GetAPIKeys(ctx context.Context) ([]entity.APIKey, error)
+	RotateAPIKey(ctx context.Context, id, prefix, keyHash string) (entity.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+	UseAPIKey(ctx context.Context, keyHash string) (entity.APIKey, error)
 }
 
 type InMemoryTokenStore struct {
-	tokens map[string]string
+	tokens map[string]entity.Token
 	mu     sync.RWMutex
 }
 
 func NewInMemoryTokenStore() *InMemoryTokenStore {
-	return &InMemoryTokenStore{tokens: make(map[string]string)}
+	return &InMemoryTokenStore{tokens: make(map[string]entity.Token)}
 }
 
-func (s *InMemoryTokenStore) Save(token, userID string) {
+func (s *InMemoryTokenStore) Save(ctx context.Context, token entity.Token) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.tokens[token] = userID
+	s.tokens[token.Token] = token
+	return nil
 }
 
-func (s *InMemoryTokenStore) IsValid(token string) bool {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	_, ok := s.tokens[token]
+func (s *InMemoryTokenStore) IsValid(ctx context.Context, token string) bool {
+	_, ok := s.GetUserID(ctx, token)
 	return ok
 }
 
-func (s *InMemoryTokenStore) GetUserID(token string) (string, bool) {
+func (s *InMemoryTokenStore) GetUserID(ctx context.Context, token string) (string, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	uid, ok := s.tokens[token]
-	return uid, ok
+	t, ok := s.tokens[token]
+	if !ok || t.Kind != entity.TokenKindAccess || time.Now().After(t.ExpiresAt) {
+		return "", false
+	}
+	return t.UserID, true
 }
 
-func (s *InMemoryTokenStore) Delete(token string) {
+func (s *InMemoryTokenStore) Delete(ctx context.Context, token string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	delete(s.tokens, token)
+	return nil
+}
+
+func (s *InMemoryTokenStore) DeleteFamily(ctx context.Context, familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token, t := range s.tokens {
+		if t.FamilyID == familyID {
+			delete(s.tokens, token)
+		}
+	}
+	return nil
+}
+
+func (s *InMemoryTokenStore) DeleteByUserID(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token, t := range s.tokens {
+		if t.UserID == userID {
+			delete(s.tokens, token)
+		}
+	}
+	return nil
+}
+
+func (s *InMemoryTokenStore) DeleteExpired(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var removed int64
+	now := time.Now()
+	for token, t := range s.tokens {
+		if now.After(t.ExpiresAt) {
+			delete(s.tokens, token)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (s *InMemoryTokenStore) UseRefreshToken(ctx context.Context, token string) (entity.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tokens[token]
+	if !ok || t.Kind != entity.TokenKindRefresh || time.Now().After(t.ExpiresAt) {
+		return entity.Token{}, apperr.ErrRefreshTokenInvalid
+	}
+	if t.UsedAt != nil {
+		return t, apperr.ErrRefreshTokenReused
+	}
+	now := time.Now()
+	t.UsedAt = &now
+	s.tokens[token] = t
+	return t, nil
 }
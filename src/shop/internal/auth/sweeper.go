@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunSweeper периодически удаляет из хранилища истёкшие токены, пока не отменён ctx.
+func RunSweeper(ctx context.Context, store TokenStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			removed, err := store.DeleteExpired(ctx)
+			if err != nil {
+				log.Printf("token sweeper: %v", err)
+				continue
+			}
+			if removed > 0 {
+				log.Printf("token sweeper: removed %d expired tokens", removed)
+			}
+		}
+	}
+}
@@ -1,36 +1,106 @@
 package auth
 
 import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"backend2/internal/utils"
+	"context"
+	"errors"
+	"fmt"
 	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
 	"time"
 )
 
+const (
+	accessTokenTTL  = time.Hour
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
 type AuthUsecase struct {
 	secret     []byte
 	tokenStore TokenStore
+	users      UserRepository
+	apiKeys    APIKeyRepository
+}
+
+func NewAuthUsecase(secret []byte, tokenStore TokenStore, users UserRepository, apiKeys APIKeyRepository) *AuthUsecase {
+	return &AuthUsecase{secret: secret, tokenStore: tokenStore, users: users, apiKeys: apiKeys}
 }
 
-func NewAuthUsecase(secret []byte, tokenStore TokenStore) *AuthUsecase {
-	return &AuthUsecase{secret: secret, tokenStore: tokenStore}
+// GenerateToken выдаёт пользователю новую пару access/refresh токенов,
+// открывая новое семейство токенов.
+func (a *AuthUsecase) GenerateToken(ctx context.Context, user entity.User) (entity.TokenPair, error) {
+	familyID, err := utils.GenerateUUID()
+	if err != nil {
+		return entity.TokenPair{}, fmt.Errorf("failed to generate token family: %w", err)
+	}
+	return a.issueTokens(ctx, user, familyID)
 }
-func (a *AuthUsecase) GenerateToken(userID string) (string, error) {
+
+// issueTokens выдаёт пару токенов в семействе familyID. jti делает каждый access-токен
+// уникальным, даже если refresh пришёл в ту же секунду, что и вход.
+func (a *AuthUsecase) issueTokens(ctx context.Context, user entity.User, familyID string) (entity.TokenPair, error) {
+	jti, err := utils.GenerateUUID()
+	if err != nil {
+		return entity.TokenPair{}, fmt.Errorf("failed to generate token id: %w", err)
+	}
+
+	now := time.Now()
+	accessExpiresAt := now.Add(accessTokenTTL)
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 1).Unix(),
+		"jti":       jti,
+		"user_id":   user.Id,
+		"role":      user.Role,
+		"family_id": familyID,
+		"iat":       now.Unix(),
+		"exp":       accessExpiresAt.Unix(),
 	})
 
-	tokenStr, err := token.SignedString(a.secret)
+	accessToken, err := token.SignedString(a.secret)
 	if err != nil {
-		return "", err
+		return entity.TokenPair{}, err
 	}
 
-	a.tokenStore.Save(tokenStr, userID)
-	return tokenStr, nil
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return entity.TokenPair{}, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refreshExpiresAt := now.Add(refreshTokenTTL)
+
+	err = a.tokenStore.Save(ctx, entity.Token{
+		Token:     accessToken,
+		Kind:      entity.TokenKindAccess,
+		UserID:    user.Id,
+		FamilyID:  familyID,
+		ExpiresAt: accessExpiresAt,
+	})
+	if err != nil {
+		return entity.TokenPair{}, fmt.Errorf("failed to save access token: %w", err)
+	}
 
+	err = a.tokenStore.Save(ctx, entity.Token{
+		Token:     refreshToken,
+		Kind:      entity.TokenKindRefresh,
+		UserID:    user.Id,
+		FamilyID:  familyID,
+		ExpiresAt: refreshExpiresAt,
+	})
+	if err != nil {
+		return entity.TokenPair{}, fmt.Errorf("failed to save refresh token: %w", err)
+	}
+
+	return entity.TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
 }
 
-func (a *AuthUsecase) ValidateToken(tokenStr string) (string, bool) {
-	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+func (a *AuthUsecase) parseToken(tokenStr string) (jwt.MapClaims, bool) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, jwt.ErrSignatureInvalid
 		}
@@ -38,9 +108,156 @@ func (a *AuthUsecase) ValidateToken(tokenStr string) (string, bool) {
 	})
 
 	if err != nil || !token.Valid {
-		return "", false
+		return nil, false
+	}
+	return claims, true
+}
+
+func (a *AuthUsecase) ValidateToken(ctx context.Context, tokenStr string) (entity.Principal, bool) {
+	claims, ok := a.parseToken(tokenStr)
+	if !ok {
+		return entity.Principal{}, false
+	}
+
+	userID, ok := a.tokenStore.GetUserID(ctx, tokenStr)
+	if !ok {
+		return entity.Principal{}, false
+	}
+
+	role, _ := claims["role"].(string)
+	return entity.Principal{UserID: userID, Role: role}, true
+}
+
+// RefreshToken меняет refresh-токен на новую пару токенов того же семейства.
+// Повторное предъявление уже использованного refresh-токена считается
+// признаком кражи, и всё семейство отзывается.
+func (a *AuthUsecase) RefreshToken(ctx context.Context, refreshToken string) (entity.TokenPair, error) {
+	used, err := a.tokenStore.UseRefreshToken(ctx, refreshToken)
+	if err != nil {
+		if errors.Is(err, apperr.ErrRefreshTokenReused) {
+			if delErr := a.tokenStore.DeleteFamily(ctx, used.FamilyID); delErr != nil {
+				return entity.TokenPair{}, fmt.Errorf("failed to revoke token family: %w", delErr)
+			}
+			return entity.TokenPair{}, apperr.ErrRefreshTokenReused
+		}
+		if errors.Is(err, apperr.ErrRefreshTokenInvalid) {
+			return entity.TokenPair{}, apperr.ErrRefreshTokenInvalid
+		}
+		return entity.TokenPair{}, fmt.Errorf("failed to use refresh token: %w", err)
 	}
 
-	userID, ok := a.tokenStore.GetUserID(tokenStr)
-	return userID, ok
+	// роль берётся из базы, чтобы её изменение применялось при обновлении токена
+	user, err := a.users.GetUserById(ctx, used.UserID)
+	if err != nil {
+		return entity.TokenPair{}, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	pair, err := a.issueTokens(ctx, user, used.FamilyID)
+	if err != nil {
+		return entity.TokenPair{}, fmt.Errorf("failed to rotate tokens: %w", err)
+	}
+	return pair, nil
+}
+
+// Login проверяет логин и пароль пользователя и выдаёт ему токен.
+func (a *AuthUsecase) Login(ctx context.Context, login, password string) (entity.TokenPair, error) {
+	user, err := a.users.GetUserByLogin(ctx, login)
+	if err != nil {
+		if errors.Is(err, apperr.ErrUserNotFound) {
+			return entity.TokenPair{}, apperr.ErrInvalidCredentials
+		}
+		return entity.TokenPair{}, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	if err != nil {
+		return entity.TokenPair{}, apperr.ErrInvalidCredentials
+	}
+
+	pair, err := a.GenerateToken(ctx, user)
+	if err != nil {
+		return entity.TokenPair{}, fmt.Errorf("failed to generate token: %w", err)
+	}
+	return pair, nil
+}
+
+func (a *AuthUsecase) CreateUser(ctx context.Context, login, password, role string) (entity.User, error) {
+	if role == "" {
+		role = entity.RoleReadOnly
+	}
+
+	id, err := utils.GenerateUUID()
+	if err != nil {
+		return entity.User{}, fmt.Errorf("failed to generate user id: %w", err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return entity.User{}, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user, err := a.users.CreateUser(ctx, entity.User{
+		Id:           id,
+		Login:        login,
+		PasswordHash: string(hash),
+		Role:         role,
+		CreatedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		return entity.User{}, fmt.Errorf("failed to create user: %w", err)
+	}
+	return user, nil
+}
+
+// BootstrapAdmin создаёт администратора с логином login, если такого пользователя
+// ещё нет, и сообщает, был ли он создан. Существующий пользователь не меняется.
+func (a *AuthUsecase) BootstrapAdmin(ctx context.Context, login, password string) (bool, error) {
+	if len(password) < 8 {
+		return false, errors.New("admin password must be at least 8 characters long")
+	}
+	_, err := a.CreateUser(ctx, login, password, entity.RoleAdmin)
+	if errors.Is(err, apperr.ErrUserExists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Logout отзывает текущий токен вместе с refresh-токенами его семейства.
+func (a *AuthUsecase) Logout(ctx context.Context, tokenStr string) error {
+	claims, ok := a.parseToken(tokenStr)
+	if !ok {
+		return apperr.ErrInvalidToken
+	}
+
+	familyID, _ := claims["family_id"].(string)
+	if familyID == "" {
+		err := a.tokenStore.Delete(ctx, tokenStr)
+		if err != nil {
+			return fmt.Errorf("failed to revoke token: %w", err)
+		}
+		return nil
+	}
+
+	err := a.tokenStore.DeleteFamily(ctx, familyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token family: %w", err)
+	}
+	return nil
+}
+
+// RevokeUserTokens отзывает все выданные пользователю токены.
+func (a *AuthUsecase) RevokeUserTokens(ctx context.Context, userID string) error {
+	_, err := a.users.GetUserById(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	err = a.tokenStore.DeleteByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+	return nil
 }
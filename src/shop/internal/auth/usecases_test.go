@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"backend2/internal/entity"
+	"context"
+	"testing"
+)
+
+// Токены одного семейства, выданные в одну секунду, не должны совпадать:
+// иначе второй не сохранится в хранилище токенов.
+func TestIssueTokensUnique(t *testing.T) {
+	a := NewAuthUsecase([]byte("test-secret"), NewInMemoryTokenStore(), nil, nil)
+	user := entity.User{Id: "c3b1f7a2-4d5e-4f60-8a9b-0c1d2e3f4a5b", Role: entity.RoleAdmin}
+	ctx := context.Background()
+
+	first, err := a.issueTokens(ctx, user, "family")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := a.issueTokens(ctx, user, "family")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.AccessToken == second.AccessToken {
+		t.Error("access tokens issued in the same second are identical")
+	}
+
+	claims, ok := a.parseToken(second.AccessToken)
+	if !ok {
+		t.Fatal("issued token does not parse")
+	}
+	for _, claim := range []string{"jti", "iat"} {
+		if _, ok := claims[claim]; !ok {
+			t.Errorf("token has no %s claim", claim)
+		}
+	}
+}
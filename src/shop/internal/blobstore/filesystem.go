@@ -0,0 +1,102 @@
+package blobstore
+
+import (
+	"backend2/internal/apperr"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Filesystem хранит файлы в каталоге на диске, ключ — относительный путь.
+type Filesystem struct {
+	root string
+}
+
+// NewFilesystem создаёт каталог root, если его ещё нет.
+func NewFilesystem(root string) (*Filesystem, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperr.ErrBlobStore, err)
+	}
+	return &Filesystem{root: root}, nil
+}
+
+func (f *Filesystem) path(key string) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(f.root, filepath.FromSlash(key)), nil
+}
+
+// Put пишет файл во временный рядом с целевым и переименовывает его,
+// поэтому читатель никогда не видит недописанный файл.
+func (f *Filesystem) Put(ctx context.Context, key string, data []byte) error {
+	name, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(name)
+	var tmp *os.File
+	// каталог может удалить Delete соседнего ключа, тогда он создаётся ещё раз
+	for attempt := 0; attempt < 2; attempt++ {
+		if err = os.MkdirAll(dir, 0o750); err != nil {
+			return fmt.Errorf("%w: %v", apperr.ErrBlobStore, err)
+		}
+		tmp, err = os.CreateTemp(dir, ".blob-*")
+		if !errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrBlobStore, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrBlobStore, err)
+	}
+	return nil
+}
+
+func (f *Filesystem) Get(ctx context.Context, key string) ([]byte, error) {
+	name, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, apperr.ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", apperr.ErrBlobStore, err)
+	}
+	return data, nil
+}
+
+// Delete удаляет файл и опустевший каталог над ним.
+func (f *Filesystem) Delete(ctx context.Context, key string) error {
+	name, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %v", apperr.ErrBlobStore, err)
+	}
+	if dir := filepath.Dir(name); dir != filepath.Clean(f.root) {
+		// в каталоге могут остаться другие файлы, тогда он не удаляется
+		os.Remove(dir)
+	}
+	return nil
+}
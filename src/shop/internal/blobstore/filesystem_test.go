@@ -0,0 +1,78 @@
+package blobstore
+
+import (
+	"backend2/internal/apperr"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilesystemPutGetDelete(t *testing.T) {
+	f, err := NewFilesystem(filepath.Join(t.TempDir(), "images"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	key := "1b4e28ba/variants/200x200-cover.webp-abc"
+	data := []byte("image data")
+
+	if err = f.Put(ctx, key, data); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	got, err := f.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("get = %q, want %q", got, data)
+	}
+
+	if err = f.Delete(ctx, key); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err = f.Get(ctx, key); !errors.Is(err, apperr.ErrBlobNotFound) {
+		t.Errorf("get after delete: %v, want ErrBlobNotFound", err)
+	}
+	if err = f.Delete(ctx, key); err != nil {
+		t.Errorf("delete of missing key: %v", err)
+	}
+}
+
+func TestFilesystemRejectsTraversal(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewFilesystem(filepath.Join(dir, "images"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	keys := []string{
+		"",
+		"../escape",
+		"a/../../escape",
+		"a/./b",
+		"a//b",
+		"a/",
+		"/escape",
+		`..\escape`,
+		"..",
+	}
+	for _, key := range keys {
+		if err := f.Put(ctx, key, []byte("x")); err == nil {
+			t.Errorf("put %q: no error", key)
+		}
+		if _, err := f.Get(ctx, key); err == nil || errors.Is(err, apperr.ErrBlobNotFound) {
+			t.Errorf("get %q: %v, want invalid key", key, err)
+		}
+		if err := f.Delete(ctx, key); err == nil {
+			t.Errorf("delete %q: no error", key)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "escape")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("file written outside the store root: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package blobstore
+
+import (
+	"backend2/internal/apperr"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Postgres хранит файлы в таблице image_blob той же базы.
+type Postgres struct {
+	db *sql.DB
+}
+
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+func (p *Postgres) Put(ctx context.Context, key string, data []byte) error {
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO image_blob (key, data) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET data = excluded.data
+	`, key, data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrBlobStore, err)
+	}
+	return nil
+}
+
+func (p *Postgres) Get(ctx context.Context, key string) ([]byte, error) {
+	var data []byte
+	err := p.db.QueryRowContext(ctx, `SELECT data FROM image_blob WHERE key = $1`, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", apperr.ErrBlobStore, err)
+	}
+	return data, nil
+}
+
+func (p *Postgres) Delete(ctx context.Context, key string) error {
+	_, err := p.db.ExecContext(ctx, `DELETE FROM image_blob WHERE key = $1`, key)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrBlobStore, err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package blobstore
+
+import (
+	"backend2/internal/apperr"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 хранит объекты в памяти и отвечает на PUT, GET и DELETE как S3.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") ||
+		r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(body) {
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[r.URL.Path] = body
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestS3(t *testing.T) (*S3, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	s, err := NewS3(S3Config{
+		Endpoint:        srv.URL,
+		Bucket:          "images",
+		Prefix:          "/shop/",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, fake
+}
+
+func TestS3PutGetDelete(t *testing.T) {
+	s, fake := newTestS3(t)
+	ctx := context.Background()
+	key := "1b4e28ba/variants/200x200-cover.webp-abc"
+	data := []byte("image data")
+
+	if err := s.Put(ctx, key, data); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if _, ok := fake.objects["/images/shop/"+key]; !ok {
+		t.Fatalf("object is not stored under bucket and prefix: %v", fake.objects)
+	}
+
+	got, err := s.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("get = %q, want %q", got, data)
+	}
+
+	if err = s.Delete(ctx, key); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err = s.Get(ctx, key); !errors.Is(err, apperr.ErrBlobNotFound) {
+		t.Errorf("get after delete: %v, want ErrBlobNotFound", err)
+	}
+	if err = s.Delete(ctx, key); err != nil {
+		t.Errorf("delete of missing key: %v", err)
+	}
+}
+
+func TestS3GetMissing(t *testing.T) {
+	s, _ := newTestS3(t)
+	_, err := s.Get(context.Background(), "missing/key")
+	if !errors.Is(err, apperr.ErrBlobNotFound) {
+		t.Errorf("get missing: %v, want ErrBlobNotFound", err)
+	}
+}
+
+func TestS3Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "InternalError", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	s, err := NewS3(S3Config{Endpoint: srv.URL, Bucket: "images", AccessKeyID: "key", SecretAccessKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err = s.Put(ctx, "a/b", []byte("x")); !errors.Is(err, apperr.ErrBlobStore) {
+		t.Errorf("put: %v, want ErrBlobStore", err)
+	}
+	if _, err = s.Get(ctx, "a/b"); !errors.Is(err, apperr.ErrBlobStore) {
+		t.Errorf("get: %v, want ErrBlobStore", err)
+	}
+	if err = s.Delete(ctx, "a/b"); !errors.Is(err, apperr.ErrBlobStore) {
+		t.Errorf("delete: %v, want ErrBlobStore", err)
+	}
+}
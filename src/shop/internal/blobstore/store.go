@@ -0,0 +1,85 @@
+// Package blobstore хранит содержимое файлов изображений вне таблицы images:
+// в отдельной таблице Postgres, в каталоге на диске или в S3-совместимом хранилище.
+package blobstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Store хранит файлы по ключу. Get отсутствующего ключа возвращает
+// apperr.ErrBlobNotFound, Delete отсутствующего ключа ошибкой не считается.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Виды хранилищ для Config.Kind.
+const (
+	KindPostgres   = "postgres"
+	KindFilesystem = "fs"
+	KindS3         = "s3"
+)
+
+// Config — настройки хранилищ. Kind выбирает хранилище, остальные поля
+// нужны только своему виду.
+type Config struct {
+	Kind string
+	Dir  string
+	S3   S3Config
+}
+
+// ConfigFromEnv читает настройки из окружения: BLOB_STORE (postgres по умолчанию),
+// BLOB_DIR для fs и S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_PREFIX,
+// S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY для s3.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Kind: strings.ToLower(os.Getenv("BLOB_STORE")),
+		Dir:  os.Getenv("BLOB_DIR"),
+		S3: S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			Prefix:          os.Getenv("S3_PREFIX"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		},
+	}
+	if cfg.Kind == "" {
+		cfg.Kind = KindPostgres
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = "data/images"
+	}
+	return cfg
+}
+
+// Open создаёт хранилище вида cfg.Kind. db нужна только хранилищу postgres.
+func Open(cfg Config, db *sql.DB) (Store, error) {
+	switch cfg.Kind {
+	case KindPostgres:
+		return NewPostgres(db), nil
+	case KindFilesystem:
+		return NewFilesystem(cfg.Dir)
+	case KindS3:
+		return NewS3(cfg.S3)
+	}
+	return nil, fmt.Errorf("unknown blob store %q, want %s, %s or %s", cfg.Kind, KindPostgres, KindFilesystem, KindS3)
+}
+
+// checkKey отклоняет ключи, которые нельзя безопасно превратить в путь или имя объекта.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
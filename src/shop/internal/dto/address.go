@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 type AddressDTO struct {
 	ID      string `json:"id" example:"a123b456-c789-d012-e345-67890abcdef1"`
 	Country string `json:"country" example:"UK" validate:"required"`
@@ -12,3 +14,27 @@ type AddressCreateDTO struct {
 	City    string `json:"city" example:"London" validate:"required"`
 	Street  string `json:"street" example:"Privet Drive" validate:"required"`
 }
+
+// ClientAddressRequestDTO — адрес из адресной книги клиента, для создания и полной замены.
+type ClientAddressRequestDTO struct {
+	Label             string `json:"label" validate:"required,max=30" example:"home"`
+	Country           string `json:"country" validate:"required,max=10" example:"UK"`
+	City              string `json:"city" validate:"required,max=30" example:"London"`
+	Street            string `json:"street" validate:"required,max=100" example:"Privet Drive"`
+	IsDefaultShipping bool   `json:"is_default_shipping" example:"true"`
+	IsDefaultBilling  bool   `json:"is_default_billing" example:"false"`
+}
+
+type ClientAddressResponseDTO struct {
+	Id                string     `json:"id" example:"9e8d7c6b-5a4f-4e3d-8c2b-1a0f9e8d7c6b"`
+	ClientId          string     `json:"client_id" example:"f19a3a7-12f5-4332-9582-624519c3eaea"`
+	Label             string     `json:"label" example:"home"`
+	IsDefaultShipping bool       `json:"is_default_shipping" example:"true"`
+	IsDefaultBilling  bool       `json:"is_default_billing" example:"false"`
+	CreatedAt         time.Time  `json:"created_at" example:"2025-07-01T15:04:05Z"`
+	Address           AddressDTO `json:"address"`
+}
+
+type ClientAddressesResponseDTO struct {
+	Addresses []ClientAddressResponseDTO `json:"addresses"`
+}
@@ -0,0 +1,29 @@
+package dto
+
+import "time"
+
+type APIKeyCreateRequestDTO struct {
+	Name   string   `json:"name" validate:"required,max=100" example:"warehouse scanners"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=catalog_manager warehouse read_only" example:"warehouse"`
+}
+
+type APIKeyResponseDTO struct {
+	Id         string     `json:"id" example:"7d1c2b3a-4e5f-4a6b-8c7d-9e0f1a2b3c4d"`
+	Name       string     `json:"name" example:"warehouse scanners"`
+	Prefix     string     `json:"prefix" example:"shop_7d1c2b3a"`
+	Scopes     []string   `json:"scopes" example:"warehouse"`
+	CreatedBy  *string    `json:"created_by,omitempty" example:"c3b1f7a2-4d5e-4f60-8a9b-0c1d2e3f4a5b"`
+	CreatedAt  time.Time  `json:"created_at" example:"2025-07-01T15:04:05Z"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" example:"2025-07-02T09:00:00Z"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyCreatedResponseDTO содержит открытое значение ключа, оно показывается один раз.
+type APIKeyCreatedResponseDTO struct {
+	APIKeyResponseDTO
+	Key string `json:"key" example:"shop_7d1c2b3a_q3T0mX9k1cVb6rYz..."`
+}
+
+type APIKeysResponseDTO struct {
+	APIKeys []APIKeyResponseDTO `json:"api_keys"`
+}
@@ -0,0 +1,31 @@
+package dto
+
+import "time"
+
+type LoginRequestDTO struct {
+	Login    string `json:"login" validate:"required" example:"admin"`
+	Password string `json:"password" validate:"required" example:"admin"`
+}
+
+type TokenResponseDTO struct {
+	Token        string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken string `json:"refresh_token" example:"q3T0mX9k1cVb6rYz..."`
+	ExpiresIn    int64  `json:"expires_in" example:"3600"`
+}
+
+type RefreshTokenRequestDTO struct {
+	RefreshToken string `json:"refresh_token" validate:"required" example:"q3T0mX9k1cVb6rYz..."`
+}
+
+type UserCreateRequestDTO struct {
+	Login    string `json:"login" validate:"required" example:"hermione"`
+	Password string `json:"password" validate:"required,min=8" example:"leviosa123"`
+	Role     string `json:"role" validate:"omitempty,oneof=admin catalog_manager warehouse read_only" example:"catalog_manager"`
+}
+
+type UserResponseDTO struct {
+	Id        string    `json:"id" example:"c3b1f7a2-4d5e-4f60-8a9b-0c1d2e3f4a5b"`
+	Login     string    `json:"login" example:"hermione"`
+	Role      string    `json:"role" example:"catalog_manager"`
+	CreatedAt time.Time `json:"created_at" example:"2025-07-01T15:04:05Z"`
+}
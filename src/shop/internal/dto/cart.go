@@ -0,0 +1,34 @@
+package dto
+
+type CartItemAddRequestDTO struct {
+	ProductId string `json:"product_id" validate:"required" example:"product-xyz-789"`
+	Quantity  int    `json:"quantity" validate:"required,gt=0" example:"1"`
+}
+
+type CartItemUpdateRequestDTO struct {
+	Quantity int `json:"quantity" validate:"required,gt=0" example:"3"`
+}
+
+type CartLineResponseDTO struct {
+	ProductId      string  `json:"product_id" example:"product-xyz-789"`
+	Name           string  `json:"name,omitempty" example:"Potion of Healing"`
+	Quantity       int     `json:"quantity" example:"3"`
+	UnitPrice      float64 `json:"unit_price" example:"49.99"`
+	AvailableStock int     `json:"available_stock" example:"120"`
+	LineTotal      float64 `json:"line_total" example:"149.97"`
+	Problem        string  `json:"problem,omitempty" example:"insufficient_stock"`
+}
+
+type CartResponseDTO struct {
+	ClientId    string                `json:"client_id" example:"f19a3a7-12f5-4332-9582-624519c3eaea"`
+	Items       []CartLineResponseDTO `json:"items"`
+	Total       float64               `json:"total" example:"149.97"`
+	CanCheckout bool                  `json:"can_checkout" example:"true"`
+}
+
+// CartConflictResponse возвращается, когда корзину нельзя оформить из-за помеченных позиций.
+type CartConflictResponse struct {
+	Message string          `json:"status" example:"cart has unavailable items"`
+	Code    int             `json:"code" example:"409"`
+	Cart    CartResponseDTO `json:"cart"`
+}
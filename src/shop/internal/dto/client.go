@@ -13,10 +13,15 @@ type ClientCreateRequestDTO struct {
 	Address       AddressCreateDTO `json:"address"`
 }
 
+// ClientUpdateRequestDTO — изменяемые поля клиента и его основного адреса для PATCH.
 type ClientUpdateRequestDTO struct {
-	Country string `json:"country" validate:"required" example:"UK"`
-	City    string `json:"city" validate:"required" example:"London"`
-	Street  string `json:"street" validate:"required" example:"Grimmauld Place"`
+	ClientName    *string    `json:"client_name" validate:"omitnil,min=1,max=50" example:"Harry"`
+	ClientSurname *string    `json:"client_sure_name" validate:"omitnil,min=1,max=50" example:"Potter"`
+	BirthDate     *time.Time `json:"birth_date" validate:"omitnil" example:"2000-07-31T00:00:00Z"`
+	Gender        *string    `json:"gender" validate:"omitnil,min=1,max=10" example:"male"`
+	Country       *string    `json:"country" validate:"omitnil,min=1,max=10" example:"UK"`
+	City          *string    `json:"city" validate:"omitnil,min=1,max=30" example:"London"`
+	Street        *string    `json:"street" validate:"omitnil,min=1,max=100" example:"Grimmauld Place"`
 }
 
 type ClientResponseDTO struct {
@@ -38,3 +43,11 @@ type ClientsNotFound struct {
 	Clients []entity.Client `json:"clients"  swaggertype:"array,object"`
 	Message string          `json:"message"`
 }
+
+// ClientsPageDTO — страница клиентов; total — число найденных без учёта limit и offset.
+type ClientsPageDTO struct {
+	Items  []ClientResponseDTO `json:"items"`
+	Total  int                 `json:"total" example:"42"`
+	Limit  int                 `json:"limit" example:"20"`
+	Offset int                 `json:"offset" example:"0"`
+}
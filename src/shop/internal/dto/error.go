@@ -19,3 +19,17 @@ type Error500 struct {
 	Message string `json:"status" example:"internal server error"`
 	Code    int    `json:"code" example:"500"`
 }
+
+// Error409Dependents — ответ на удаление ресурса, на который ссылаются другие записи.
+type Error409Dependents struct {
+	Message    string         `json:"status" example:"resource has dependents"`
+	Code       int            `json:"code" example:"409"`
+	Dependents []DependentDTO `json:"dependents"`
+}
+
+// DependentDTO — записи одного вида, мешающие удалению; ids — не больше первых ста.
+type DependentDTO struct {
+	Kind  string   `json:"kind" example:"product"`
+	Count int      `json:"count" example:"3"`
+	Ids   []string `json:"ids" example:"a123b456-c789-d012-e345-67890abcdef1"`
+}
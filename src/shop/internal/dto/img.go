@@ -1,6 +1,37 @@
 package dto
 
 type ImageDTO struct {
-	Id    string `json:"id"`
-	Image []byte `json:"image"`
+	Id       string `json:"id"`
+	Image    []byte `json:"image"`
+	Filename string `json:"filename,omitempty"`
+	MimeType string `json:"mime_type"`
+	Size     int64  `json:"size"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Sha256   string `json:"sha256"`
+	// Url — адрес файла с версией содержимого, его можно кэшировать навсегда
+	Url string `json:"url" example:"/api/v1/image/0b7e2f4a-5c1d-4e8f-9a3b-2d6c8e1f4a7b?v=9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+}
+
+// ProductImageDTO — изображение в галерее товара. Сам файл отдаётся по /image/{id}.
+type ProductImageDTO struct {
+	Id        string `json:"id" example:"0b7e2f4a-5c1d-4e8f-9a3b-2d6c8e1f4a7b"`
+	Filename  string `json:"filename,omitempty" example:"front.jpg"`
+	MimeType  string `json:"mime_type" example:"image/jpeg"`
+	Size      int64  `json:"size" example:"204800"`
+	Width     int    `json:"width" example:"1200"`
+	Height    int    `json:"height" example:"800"`
+	Sha256    string `json:"sha256"`
+	Url       string `json:"url" example:"/api/v1/image/0b7e2f4a-5c1d-4e8f-9a3b-2d6c8e1f4a7b?v=9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	Position  int    `json:"position" example:"0"`
+	IsPrimary bool   `json:"is_primary" example:"true"`
+}
+
+type ProductImagesResponseDTO struct {
+	Images []ProductImageDTO `json:"images"`
+}
+
+// ProductImagesOrderRequestDTO — новый порядок галереи: все её изображения, каждое по разу.
+type ProductImagesOrderRequestDTO struct {
+	ImageIds []string `json:"image_ids" validate:"required,dive,uuid" example:"0b7e2f4a-5c1d-4e8f-9a3b-2d6c8e1f4a7b"`
 }
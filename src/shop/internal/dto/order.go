@@ -0,0 +1,50 @@
+package dto
+
+import "time"
+
+type OrderItemRequestDTO struct {
+	ProductId string `json:"product_id" validate:"required" example:"product-xyz-789"`
+	Quantity  int    `json:"quantity" validate:"required,gt=0" example:"2"`
+}
+
+type OrderCreateRequestDTO struct {
+	ClientId          string                `json:"client_id" validate:"required" example:"f19a3a7-12f5-4332-9582-624519c3eaea"`
+	Items             []OrderItemRequestDTO `json:"items" validate:"required,min=1,dive"`
+	ShippingAddressId string                `json:"shipping_address_id,omitempty" example:"9e8d7c6b-5a4f-4e3d-8c2b-1a0f9e8d7c6b"`
+	BillingAddressId  string                `json:"billing_address_id,omitempty" example:"9e8d7c6b-5a4f-4e3d-8c2b-1a0f9e8d7c6b"`
+}
+
+// OrderAddressDTO — копия адреса на момент оформления заказа.
+type OrderAddressDTO struct {
+	Country string `json:"country" example:"UK"`
+	City    string `json:"city" example:"London"`
+	Street  string `json:"street" example:"Privet Drive"`
+}
+
+type OrderStatusUpdateRequestDTO struct {
+	Status string `json:"status" validate:"required,oneof=paid shipped delivered cancelled" example:"paid"`
+}
+
+type OrderItemResponseDTO struct {
+	Id        string  `json:"id" example:"5b6c7d8e-9f01-4a2b-8c3d-4e5f6a7b8c9d"`
+	ProductId string  `json:"product_id,omitempty" example:"product-xyz-789"` // пустой, если товар удалён физически
+	Quantity  int     `json:"quantity" example:"2"`
+	UnitPrice float64 `json:"unit_price" example:"49.99"`
+}
+
+type OrderResponseDTO struct {
+	Id        string                 `json:"id" example:"0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d"`
+	ClientId  string                 `json:"client_id" example:"f19a3a7-12f5-4332-9582-624519c3eaea"`
+	Status    string                 `json:"status" example:"new"`
+	Total     float64                `json:"total" example:"99.98"`
+	CreatedAt time.Time              `json:"created_at" example:"2025-07-01T15:04:05Z"`
+	UpdatedAt time.Time              `json:"updated_at" example:"2025-07-01T15:04:05Z"`
+	Items     []OrderItemResponseDTO `json:"items"`
+
+	ShippingAddress *OrderAddressDTO `json:"shipping_address"`
+	BillingAddress  *OrderAddressDTO `json:"billing_address"`
+}
+
+type OrdersResponseDTO struct {
+	Orders []OrderResponseDTO `json:"orders"`
+}
@@ -1,9 +1,6 @@
 package dto
 
-import (
-	"backend2/internal/entity"
-	"time"
-)
+import "time"
 
 type ProductCreateRequest struct {
 	Name           string  `json:"name" validate:"required" example:"Potion of Healing"`
@@ -28,7 +25,49 @@ type ProductsResponse struct {
 	Products []ProductResponse `json:"products"`
 }
 
-type ProductsNotFound struct {
-	Products []entity.Product `json:"products" swaggertype:"array,object"`
-	Message  string           `json:"message"`
+// ProductsPageResponse — страница товаров; total — число товаров под фильтром,
+// next_cursor пустой на последней странице.
+type ProductsPageResponse struct {
+	Items      []ProductResponse `json:"items"`
+	Total      int               `json:"total" example:"1250"`
+	Limit      int               `json:"limit" example:"20"`
+	Offset     int               `json:"offset" example:"0"`
+	NextCursor string            `json:"next_cursor" example:"eyJzIjoibmFtZSIsImQiOmZhbHNlLCJ2IjoiUG90aW9uIiwiaWQiOiIxIn0"`
+}
+
+type StockMovementResponse struct {
+	Id        string    `json:"id" example:"7c1e2f3a-4b5c-4d6e-8f90-a1b2c3d4e5f6"`
+	ProductId string    `json:"product_id" example:"product-xyz-789"`
+	Delta     int       `json:"delta" example:"-2"`
+	Reason    string    `json:"reason" example:"sale"`
+	Actor     string    `json:"actor" example:"f19a3a7-12f5-4332-9582-624519c3eaea"`
+	CreatedAt time.Time `json:"created_at" example:"2025-07-01T15:04:05Z"`
+}
+
+type StockMovementsResponse struct {
+	Movements []StockMovementResponse `json:"movements"`
+}
+
+// ProductHighlightResponse — безопасный HTML: текст товара экранирован, разметка в нём —
+// только <mark> вокруг совпадений.
+type ProductHighlightResponse struct {
+	Name     string `json:"name" example:"<mark>Potion</mark> of Healing"`
+	Category string `json:"category" example:"Alchemy"`
+}
+
+type ProductSearchResultResponse struct {
+	Product   ProductResponse          `json:"product"`
+	Rank      float64                  `json:"rank" example:"0.42"`
+	Highlight ProductHighlightResponse `json:"highlight"`
+}
+
+type ProductSearchResponse struct {
+	Results []ProductSearchResultResponse `json:"results"`
+	Total   int                           `json:"total" example:"3"`
+	Limit   int                           `json:"limit" example:"20"`
+	Offset  int                           `json:"offset" example:"0"`
+}
+
+type ProductSuggestResponse struct {
+	Suggestions []string `json:"suggestions" example:"Potion of Healing,Potion of Mana"`
 }
@@ -0,0 +1,45 @@
+package dto
+
+import "time"
+
+type PurchaseOrderItemRequestDTO struct {
+	ProductId string  `json:"product_id" validate:"required" example:"product-xyz-789"`
+	Quantity  int     `json:"quantity" validate:"required,gt=0" example:"50"`
+	UnitCost  float64 `json:"unit_cost" validate:"gte=0" example:"30.5"`
+}
+
+type PurchaseOrderCreateRequestDTO struct {
+	SupplierId string                        `json:"supplier_id" validate:"required" example:"supplier-abc-123"`
+	Items      []PurchaseOrderItemRequestDTO `json:"items" validate:"required,min=1,dive"`
+}
+
+type PurchaseOrderReceiveItemDTO struct {
+	ProductId string `json:"product_id" validate:"required" example:"product-xyz-789"`
+	Quantity  int    `json:"quantity" validate:"required,gt=0" example:"20"`
+}
+
+// PurchaseOrderReceiveRequestDTO — пустой список items принимает всё, что ещё не пришло.
+type PurchaseOrderReceiveRequestDTO struct {
+	Items []PurchaseOrderReceiveItemDTO `json:"items" validate:"dive"`
+}
+
+type PurchaseOrderItemResponseDTO struct {
+	Id               string  `json:"id" example:"5b6c7d8e-9f01-4a2b-8c3d-4e5f6a7b8c9d"`
+	ProductId        string  `json:"product_id" example:"product-xyz-789"`
+	Quantity         int     `json:"quantity" example:"50"`
+	ReceivedQuantity int     `json:"received_quantity" example:"20"`
+	UnitCost         float64 `json:"unit_cost" example:"30.5"`
+}
+
+type PurchaseOrderResponseDTO struct {
+	Id         string                         `json:"id" example:"0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d"`
+	SupplierId string                         `json:"supplier_id" example:"supplier-abc-123"`
+	Status     string                         `json:"status" example:"partially_received"`
+	CreatedAt  time.Time                      `json:"created_at" example:"2025-07-01T15:04:05Z"`
+	UpdatedAt  time.Time                      `json:"updated_at" example:"2025-07-01T15:04:05Z"`
+	Items      []PurchaseOrderItemResponseDTO `json:"items"`
+}
+
+type PurchaseOrdersResponseDTO struct {
+	PurchaseOrders []PurchaseOrderResponseDTO `json:"purchase_orders"`
+}
@@ -1,17 +1,18 @@
 package dto
 
-import "backend2/internal/entity"
-
 type SupplierCreateRequestDTO struct {
 	Name        string           `json:"name" validate:"required" example:"Magic Supplies Inc."`
 	PhoneNumber string           `json:"phone" validate:"required" example:"+44-123-456-789"`
 	Address     AddressCreateDTO `json:"address"`
 }
 
-type SupplierUpdateAddressRequestDTO struct {
-	City    string `json:"city" validate:"required" example:"Edinburgh"`
-	Street  string `json:"street" validate:"required" example:"Royal Mile"`
-	Country string `json:"country" validate:"required" example:"UK"`
+// SupplierUpdateRequestDTO — поля поставщика, которые меняет PATCH; nil оставляет поле как есть.
+type SupplierUpdateRequestDTO struct {
+	Name        *string `json:"name" validate:"omitnil,min=1,max=300" example:"Magic Supplies Inc."`
+	PhoneNumber *string `json:"phone" validate:"omitnil,min=1,max=50" example:"+44-123-456-789"`
+	City        *string `json:"city" validate:"omitnil,min=1" example:"Edinburgh"`
+	Street      *string `json:"street" validate:"omitnil,min=1" example:"Royal Mile"`
+	Country     *string `json:"country" validate:"omitnil,min=1" example:"UK"`
 }
 
 type SupplierResponseDTO struct {
@@ -26,7 +27,10 @@ type SuppliersResponse struct {
 	Suppliers []SupplierResponseDTO `json:"suppliers"`
 }
 
-type SuppliersNotFound struct {
-	Suppliers []entity.Supplier `json:"suppliers" swaggertype:"array,object"`
-	Message   string            `json:"message"`
+// SuppliersPageDTO — страница поставщиков; total — число найденных без учёта limit и offset.
+type SuppliersPageDTO struct {
+	Items  []SupplierResponseDTO `json:"items"`
+	Total  int                   `json:"total" example:"12"`
+	Limit  int                   `json:"limit" example:"20"`
+	Offset int                   `json:"offset" example:"0"`
 }
@@ -1,12 +1,108 @@
 package entity
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 //}images
 //{
 //id : UUID
 //image: bytea
+//filename // имя файла при загрузке
+//mime_type
+//size // байт
+//width
+//height
+//sha256
+//updated_at // когда загружен текущий файл
 //}
 
+// Форматы изображений, которые принимает магазин.
+const (
+	ImageMimeJPEG = "image/jpeg"
+	ImageMimePNG  = "image/png"
+	ImageMimeWebP = "image/webp"
+	ImageMimeGIF  = "image/gif"
+)
+
 type Image struct {
-	Id    string
-	Image []byte
+	Id       string
+	Image    []byte
+	Filename string
+	MimeType string
+	Size     int64
+	Width    int
+	Height   int
+	Sha256   string
+	// SourceSha256 — у варианта хэш исходного файла, из которого он получен; у исходника пуст
+	SourceSha256 string
+	UpdatedAt    time.Time
+}
+
+//}product_image
+//{
+//product_id
+//image_id
+//position // порядок в галерее, с нуля
+//is_primary // основное изображение, у товара не больше одного
+//}
+
+// ProductImage — изображение в галерее товара, без содержимого файла.
+type ProductImage struct {
+	Image     Image
+	Position  int
+	IsPrimary bool
+}
+
+//}image_variant
+//{
+//image_id
+//variant // ключ варианта, см. ImageVariant.Key
+//source_sha256 // sha256 исходника, из которого получен вариант
+//image
+//mime_type
+//size
+//width
+//height
+//created_at
+//}
+
+// Способы вписать изображение в размеры варианта.
+const (
+	ImageFitContain = "contain"
+	ImageFitCover   = "cover"
+	ImageFitFill    = "fill"
+)
+
+// ImageVariantSizes — допустимые ширина и высота производных изображений.
+// Набор ограничен, чтобы нельзя было заставить сервер хранить и считать
+// произвольное число вариантов одного файла.
+var ImageVariantSizes = []int{64, 128, 200, 256, 400, 512, 800, 1024, 1600}
+
+// ImageVariantPresets — именованные варианты для витрины.
+var ImageVariantPresets = map[string]ImageVariant{
+	"thumbnail": {Width: 128, Height: 128, Fit: ImageFitCover},
+	"medium":    {Width: 512, Height: 512, Fit: ImageFitContain},
+	"large":     {Width: 1024, Height: 1024, Fit: ImageFitContain},
+}
+
+// ImageVariant — параметры производного изображения. Нулевая сторона не
+// ограничена, пустой Format означает формат исходника.
+type ImageVariant struct {
+	Width  int
+	Height int
+	Fit    string
+	Format string
+}
+
+// Key — ключ варианта в кэше, например "200x200-cover.webp" или "200x0-contain"
+// для формата исходника.
+func (v ImageVariant) Key() string {
+	key := fmt.Sprintf("%dx%d-%s", v.Width, v.Height, v.Fit)
+	if v.Format != "" {
+		key += "." + strings.TrimPrefix(v.Format, "image/")
+	}
+	return key
 }
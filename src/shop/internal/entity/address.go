@@ -1,5 +1,7 @@
 package entity
 
+import "time"
+
 //{
 //id
 //country
@@ -13,3 +15,27 @@ type Address struct {
 	City    string
 	Street  string
 }
+
+// {
+// id
+// client_id
+// address_id
+// label // home, work ...
+// is_default_shipping
+// is_default_billing
+// created_at
+// }
+
+const AddressLabelHome = "home"
+
+// ClientAddress — адрес из адресной книги клиента. У клиента не больше одного
+// адреса доставки и одного платёжного адреса по умолчанию.
+type ClientAddress struct {
+	Id                string
+	ClientId          string
+	Label             string
+	IsDefaultShipping bool
+	IsDefaultBilling  bool
+	CreatedAt         time.Time
+	Address           Address
+}
@@ -0,0 +1,27 @@
+package entity
+
+import "time"
+
+//{
+//id
+//name
+//prefix // первые символы ключа, по ним ключ можно узнать в списке
+//key_hash
+//scopes
+//created_by
+//created_at
+//last_used_at
+//revoked_at
+//}
+
+type APIKey struct {
+	Id         string
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	CreatedBy  *string // nil, если ключ создан другим ключом или автор удалён
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+// {
+// client_id
+// product_id
+// quantity
+// added_at
+// }
+
+const (
+	CartProblemProductDeleted    = "product_deleted"
+	CartProblemInsufficientStock = "insufficient_stock"
+)
+
+type CartItem struct {
+	ClientId  string
+	ProductId string
+	Quantity  int
+	AddedAt   time.Time
+}
+
+// CartLine — позиция корзины с актуальной ценой и остатком товара.
+// Problem заполняется, если позицию сейчас нельзя купить.
+type CartLine struct {
+	ProductId      string
+	Name           string
+	Quantity       int
+	UnitPrice      float64
+	AvailableStock int
+	LineTotal      float64
+	Problem        string
+}
+
+type Cart struct {
+	ClientId    string
+	Lines       []CartLine
+	Total       float64
+	CanCheckout bool
+}
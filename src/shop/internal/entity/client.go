@@ -12,3 +12,31 @@ type Client struct {
 	AddressId        string
 	Address
 }
+
+// ClientFilter — параметры поиска клиентов. Пустые поля не фильтруют,
+// строковые сравниваются без учёта регистра, имя и фамилия — по подстроке.
+type ClientFilter struct {
+	Query          string // подстрока имени или фамилии
+	Name           string
+	Surname        string
+	Gender         string
+	BirthFrom      time.Time
+	BirthTo        time.Time
+	RegisteredFrom time.Time
+	RegisteredTo   time.Time
+	Country        string
+	City           string
+	Limit          int
+	Offset         int
+}
+
+// ClientPatch — частичное обновление клиента: nil-поля не меняются.
+type ClientPatch struct {
+	ClientName    *string
+	ClientSurname *string
+	BirthDate     *time.Time
+	Gender        *string
+	Country       *string
+	City          *string
+	Street        *string
+}
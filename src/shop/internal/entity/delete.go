@@ -0,0 +1,8 @@
+package entity
+
+// DeleteOptions — режим удаления. По умолчанию запись только помечается удалённой
+// (deleted_at) и может быть восстановлена.
+type DeleteOptions struct {
+	Hard    bool // удалить строку физически
+	Cascade bool // при Hard удалить и зависимые записи вместо отказа
+}
@@ -0,0 +1,48 @@
+package entity
+
+import "time"
+
+// {
+// id
+// client_id
+// status // new -> paid -> shipped -> delivered, new/paid -> cancelled
+// total
+// shipping_country, shipping_city, shipping_street // снимок адреса доставки
+// billing_country, billing_city, billing_street // снимок платёжного адреса
+// created_at
+// updated_at
+// }
+
+const (
+	OrderStatusNew       = "new"
+	OrderStatusPaid      = "paid"
+	OrderStatusShipped   = "shipped"
+	OrderStatusDelivered = "delivered"
+	OrderStatusCancelled = "cancelled"
+)
+
+type Order struct {
+	Id        string
+	ClientId  string
+	Status    string
+	Total     float64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Items     []OrderItem
+
+	// id адресов из адресной книги клиента; пустые — адреса по умолчанию
+	ShippingAddressId string
+	BillingAddressId  string
+	// копии адресов на момент заказа, nil — адреса не было
+	ShippingAddress *Address
+	BillingAddress  *Address
+}
+
+// OrderItem хранит цену товара на момент оформления заказа.
+type OrderItem struct {
+	Id        string
+	OrderId   string
+	ProductId string
+	Quantity  int
+	UnitPrice float64
+}
@@ -0,0 +1,9 @@
+package entity
+
+// Page — страница выборки и общее число строк под тем же фильтром.
+type Page[T any] struct {
+	Items  []T
+	Total  int
+	Limit  int
+	Offset int
+}
@@ -10,7 +10,7 @@ import "time"
 // available_stock // число закупленных экземпляров товара
 // last_update_date // число последней закупки
 // supplier_id
-// image_id: UUID
+// image_id: UUID // основное изображение, копия из product_image
 // }
 type Product struct {
 	Id             string
@@ -22,3 +22,52 @@ type Product struct {
 	SupplierId     string
 	ImageId        string
 }
+
+// поля, по которым можно сортировать список товаров
+const (
+	ProductSortName       = "name"
+	ProductSortPrice      = "price"
+	ProductSortStock      = "stock"
+	ProductSortLastUpdate = "last_update_date"
+)
+
+// ProductFilter — параметры выборки списка товаров. Пустые поля не фильтруют.
+type ProductFilter struct {
+	Category   string
+	SupplierId string
+	MinPrice   *float64
+	MaxPrice   *float64
+	InStock    bool
+	Sort       string
+	Desc       bool
+	Limit      int
+	Offset     int
+	After      *ProductCursor
+}
+
+// ProductCursor — позиция последнего отданного товара: значение поля сортировки и id.
+type ProductCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d"`
+	Value string `json:"v"`
+	Id    string `json:"id"`
+}
+
+type ProductPage struct {
+	Page[Product]
+	NextCursor string
+}
+
+// ProductSearchResult — найденный товар с релевантностью и подсвеченными совпадениями.
+// Подсветка — экранированный HTML с <mark>.
+type ProductSearchResult struct {
+	Product           Product
+	Rank              float64
+	NameHighlight     string
+	CategoryHighlight string
+}
+
+type ProductSearchPage struct {
+	Results []ProductSearchResult
+	Total   int
+}
@@ -0,0 +1,37 @@
+package entity
+
+import "time"
+
+// {
+// id
+// supplier_id
+// status // open -> partially_received -> received, open -> cancelled
+// created_at
+// updated_at
+// }
+
+const (
+	PurchaseOrderStatusOpen              = "open"
+	PurchaseOrderStatusPartiallyReceived = "partially_received"
+	PurchaseOrderStatusReceived          = "received"
+	PurchaseOrderStatusCancelled         = "cancelled"
+)
+
+type PurchaseOrder struct {
+	Id         string
+	SupplierId string
+	Status     string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Items      []PurchaseOrderItem
+}
+
+// PurchaseOrderItem — строка закупки: сколько заказано у поставщика и сколько уже принято на склад.
+type PurchaseOrderItem struct {
+	Id               string
+	PurchaseOrderId  string
+	ProductId        string
+	Quantity         int
+	ReceivedQuantity int
+	UnitCost         float64
+}
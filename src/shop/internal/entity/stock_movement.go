@@ -0,0 +1,29 @@
+package entity
+
+import "time"
+
+// {
+// id
+// product_id
+// delta // на сколько изменился остаток, со знаком
+// reason
+// actor // id пользователя или apikey:<id>
+// created_at
+// }
+
+const (
+	StockReasonPurchase   = "purchase"
+	StockReasonSale       = "sale"
+	StockReasonAdjustment = "adjustment"
+	StockReasonReturn     = "return"
+	StockReasonWriteOff   = "write-off"
+)
+
+type StockMovement struct {
+	Id        string
+	ProductId string
+	Delta     int
+	Reason    string
+	Actor     string
+	CreatedAt time.Time
+}
@@ -14,3 +14,21 @@ type Supplier struct {
 	PhoneNumber string
 	Address     Address
 }
+
+// SupplierPatch — изменяемые поля поставщика; nil означает «не менять».
+type SupplierPatch struct {
+	Name        *string
+	PhoneNumber *string
+	Country     *string
+	City        *string
+	Street      *string
+}
+
+// SupplierFilter — параметры списка поставщиков. Нулевой Limit — без ограничения.
+type SupplierFilter struct {
+	Name    string // подстрока названия
+	Country string
+	City    string
+	Limit   int
+	Offset  int
+}
@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+const (
+	TokenKindAccess  = "access"
+	TokenKindRefresh = "refresh"
+)
+
+// Token — выданный токен. Все токены, полученные от одного входа и его
+// последующих обновлений, объединены общим FamilyID.
+type Token struct {
+	Token     string
+	Kind      string
+	UserID    string
+	FamilyID  string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+type TokenPair struct {
+	AccessToken      string
+	RefreshToken     string
+	AccessExpiresAt  time.Time
+	RefreshExpiresAt time.Time
+}
@@ -0,0 +1,58 @@
+package entity
+
+import "time"
+
+//{
+//id
+//login
+//password_hash
+//role
+//created_at
+//}
+
+const (
+	RoleAdmin          = "admin"
+	RoleCatalogManager = "catalog_manager"
+	RoleWarehouse      = "warehouse"
+	RoleReadOnly       = "read_only"
+)
+
+type User struct {
+	Id           string
+	Login        string
+	PasswordHash string
+	Role         string
+	CreatedAt    time.Time
+}
+
+// Principal — тот, от чьего имени выполняется запрос: пользователь с ролью
+// или API-ключ со списком скоупов.
+type Principal struct {
+	UserID   string
+	APIKeyID string
+	Role     string
+	Scopes   []string
+}
+
+// Actor — строка, которой действие подписывается в журналах.
+func (p Principal) Actor() string {
+	if p.APIKeyID != "" {
+		return "apikey:" + p.APIKeyID
+	}
+	return p.UserID
+}
+
+// HasAnyRole проверяет, что роль пользователя или один из скоупов ключа входит в roles.
+func (p Principal) HasAnyRole(roles ...string) bool {
+	for _, role := range roles {
+		if p.Role != "" && p.Role == role {
+			return true
+		}
+		for _, scope := range p.Scopes {
+			if scope == role {
+				return true
+			}
+		}
+	}
+	return false
+}
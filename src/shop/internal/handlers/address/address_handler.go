@@ -0,0 +1,261 @@
+package address
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/dto"
+	"backend2/internal/entity"
+	"backend2/internal/mapper"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+type ClientAddress interface {
+	GetAddresses(ctx context.Context, clientId string) ([]entity.ClientAddress, error)
+	GetAddress(ctx context.Context, clientId, id string) (entity.ClientAddress, error)
+	AddAddress(ctx context.Context, clientId string, ca entity.ClientAddress) (entity.ClientAddress, error)
+	UpdateAddress(ctx context.Context, clientId, id string, ca entity.ClientAddress) (entity.ClientAddress, error)
+	DeleteAddress(ctx context.Context, clientId, id string) error
+}
+
+type AddressHandler struct {
+	address ClientAddress
+}
+
+func NewAddressHandler(address ClientAddress) *AddressHandler {
+	return &AddressHandler{address: address}
+}
+
+// GetAddresses godoc
+// @Summary      Адресная книга клиента
+// @Tags         addresses
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path     string  true  "ID клиента"
+// @Success      200  {object} dto.ClientAddressesResponseDTO
+// @Failure      400  {object} dto.Error400
+// @Failure      403  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.Error404
+// @Failure      500  {object} dto.Error500
+// @Router       /client/{id}/addresses [get]
+func (a *AddressHandler) GetAddresses(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	addresses, err := a.address.GetAddresses(r.Context(), id)
+	if err != nil {
+		writeAddressError(w, err)
+		return
+	}
+
+	res := mapper.ClientAddressesEntityToDTO(addresses)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// GetAddress godoc
+// @Summary      Получить адрес клиента
+// @Tags         addresses
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id          path     string  true  "ID клиента"
+// @Param        address_id  path     string  true  "ID адреса"
+// @Success      200  {object} dto.ClientAddressResponseDTO
+// @Failure      400  {object} dto.Error400
+// @Failure      403  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.Error404
+// @Failure      500  {object} dto.Error500
+// @Router       /client/{id}/addresses/{address_id} [get]
+func (a *AddressHandler) GetAddress(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	if vars["id"] == "" || vars["address_id"] == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	ca, err := a.address.GetAddress(r.Context(), vars["id"], vars["address_id"])
+	if err != nil {
+		writeAddressError(w, err)
+		return
+	}
+
+	res := mapper.ClientAddressEntityToDTO(ca)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// AddAddress godoc
+// @Summary      Добавить адрес клиенту
+// @Description  Первый адрес клиента автоматически становится адресом доставки и платёжным по умолчанию.
+// @Tags         addresses
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path     string                       true  "ID клиента"
+// @Param        address  body     dto.ClientAddressRequestDTO  true  "Адрес"
+// @Success      201  {object} dto.ClientAddressResponseDTO
+// @Failure      400  {object} dto.Error400
+// @Failure      403  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.Error404
+// @Failure      500  {object} dto.Error500
+// @Router       /client/{id}/addresses [post]
+func (a *AddressHandler) AddAddress(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	request, ok := decodeAddressRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ca, err := a.address.AddAddress(r.Context(), id, mapper.ClientAddressRequestToEntity(request))
+	if err != nil {
+		writeAddressError(w, err)
+		return
+	}
+
+	res := mapper.ClientAddressEntityToDTO(ca)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(res)
+}
+
+// UpdateAddress godoc
+// @Summary      Изменить адрес клиента
+// @Description  Полная замена адреса, метки и флагов. Уже оформленные заказы хранят свою копию адреса и не меняются.
+// @Tags         addresses
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id          path     string                       true  "ID клиента"
+// @Param        address_id  path     string                       true  "ID адреса"
+// @Param        address     body     dto.ClientAddressRequestDTO  true  "Адрес"
+// @Success      200  {object} dto.ClientAddressResponseDTO
+// @Failure      400  {object} dto.Error400
+// @Failure      403  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.Error404
+// @Failure      500  {object} dto.Error500
+// @Router       /client/{id}/addresses/{address_id} [put]
+func (a *AddressHandler) UpdateAddress(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	if vars["id"] == "" || vars["address_id"] == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	request, ok := decodeAddressRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ca, err := a.address.UpdateAddress(r.Context(), vars["id"], vars["address_id"], mapper.ClientAddressRequestToEntity(request))
+	if err != nil {
+		writeAddressError(w, err)
+		return
+	}
+
+	res := mapper.ClientAddressEntityToDTO(ca)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// DeleteAddress godoc
+// @Summary      Удалить адрес клиента
+// @Tags         addresses
+// @Security     BearerAuth
+// @Param        id          path  string  true  "ID клиента"
+// @Param        address_id  path  string  true  "ID адреса"
+// @Success      204
+// @Failure      400  {object} dto.Error400
+// @Failure      403  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.Error404
+// @Failure      500  {object} dto.Error500
+// @Router       /client/{id}/addresses/{address_id} [delete]
+func (a *AddressHandler) DeleteAddress(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	if vars["id"] == "" || vars["address_id"] == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	err := a.address.DeleteAddress(r.Context(), vars["id"], vars["address_id"])
+	if err != nil {
+		writeAddressError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeAddressRequest(w http.ResponseWriter, r *http.Request) (dto.ClientAddressRequestDTO, bool) {
+	var request dto.ClientAddressRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid JSON",
+		})
+		return dto.ClientAddressRequestDTO{}, false
+	}
+
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.(validator.ValidationErrors).Error(),
+		})
+		return dto.ClientAddressRequestDTO{}, false
+	}
+	return request, true
+}
+
+func writeAddressError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	message := "internal server error"
+	switch {
+	case errors.Is(err, apperr.ErrClientNotFound):
+		code, message = http.StatusNotFound, "client not found"
+	case errors.Is(err, apperr.ErrAddressNotFound):
+		code, message = http.StatusNotFound, "address not found"
+	}
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(dto.ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
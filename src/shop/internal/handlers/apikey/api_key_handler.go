@@ -0,0 +1,206 @@
+package apikey
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/dto"
+	"backend2/internal/entity"
+	"backend2/internal/mapper"
+	"backend2/internal/middleware"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+type APIKeys interface {
+	CreateAPIKey(ctx context.Context, name string, scopes []string, createdBy string) (entity.APIKey, string, error)
+	GetAPIKeys(ctx context.Context) ([]entity.APIKey, error)
+	RotateAPIKey(ctx context.Context, id string) (entity.APIKey, string, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+}
+
+type APIKeyHandler struct {
+	keys APIKeys
+}
+
+func NewAPIKeyHandler(keys APIKeys) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys}
+}
+
+// CreateAPIKey godoc
+// @Summary      Создать API-ключ
+// @Tags         api keys
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        key  body     dto.APIKeyCreateRequestDTO  true  "Имя и скоупы ключа"
+// @Success      201  {object} dto.APIKeyCreatedResponseDTO
+// @Failure      400  {object} dto.Error400
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      403  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.Error500
+// @Router       /apikey [post]
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	var request dto.APIKeyCreateRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid JSON",
+		})
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.(validator.ValidationErrors).Error(),
+		})
+		return
+	}
+
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	key, rawKey, err := h.keys.CreateAPIKey(r.Context(), request.Name, request.Scopes, userID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "internal server error",
+		})
+		return
+	}
+
+	res := mapper.APIKeyCreatedToDTO(key, rawKey)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(res)
+}
+
+// GetAPIKeys godoc
+// @Summary      Получить список API-ключей
+// @Tags         api keys
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object} dto.APIKeysResponseDTO
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      403  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.Error500
+// @Router       /apikeys [get]
+func (h *APIKeyHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	keys, err := h.keys.GetAPIKeys(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "internal server error",
+		})
+		return
+	}
+
+	res := mapper.APIKeysEntityToDTO(keys)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// RotateAPIKey godoc
+// @Summary      Перевыпустить секрет API-ключа
+// @Tags         api keys
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path     string  true  "ID ключа"
+// @Success      200  {object} dto.APIKeyCreatedResponseDTO
+// @Failure      400  {object} dto.Error400
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      403  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.Error404
+// @Failure      500  {object} dto.Error500
+// @Router       /apikey/{id}/rotate [post]
+func (h *APIKeyHandler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	key, rawKey, err := h.keys.RotateAPIKey(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, apperr.ErrAPIKeyNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: "api key not found",
+			})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "internal server error",
+		})
+		return
+	}
+
+	res := mapper.APIKeyCreatedToDTO(key, rawKey)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// RevokeAPIKey godoc
+// @Summary      Отозвать API-ключ
+// @Tags         api keys
+// @Security     BearerAuth
+// @Param        id   path  string  true  "ID ключа"
+// @Success      204
+// @Failure      400  {object} dto.Error400
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      403  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.Error404
+// @Failure      500  {object} dto.Error500
+// @Router       /apikey/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	err := h.keys.RevokeAPIKey(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, apperr.ErrAPIKeyNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: "api key not found",
+			})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "internal server error",
+		})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
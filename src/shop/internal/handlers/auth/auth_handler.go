@@ -1,32 +1,288 @@
 package auth
 
 import (
-	"backend2/internal/auth"
-	"backend2/internal/utils"
+	"backend2/internal/apperr"
+	"backend2/internal/dto"
+	"backend2/internal/entity"
+	"backend2/internal/mapper"
+	"backend2/internal/middleware"
+	"context"
 	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
 	"net/http"
 )
 
+type Auth interface {
+	Login(ctx context.Context, login, password string) (entity.TokenPair, error)
+	RefreshToken(ctx context.Context, refreshToken string) (entity.TokenPair, error)
+	CreateUser(ctx context.Context, login, password, role string) (entity.User, error)
+	Logout(ctx context.Context, tokenStr string) error
+	RevokeUserTokens(ctx context.Context, userID string) error
+}
+
 type AuthHandler struct {
-	a *auth.AuthUsecase
+	a Auth
 }
 
-func NewAuthHandler(a *auth.AuthUsecase) *AuthHandler {
+func NewAuthHandler(a Auth) *AuthHandler {
 	return &AuthHandler{a: a}
 }
 
-func (h *AuthHandler) GetToken(w http.ResponseWriter, r *http.Request) {
+// Login godoc
+// @Summary      Войти и получить токен
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        credentials  body     dto.LoginRequestDTO  true  "Логин и пароль"
+// @Success      200          {object} dto.TokenResponseDTO
+// @Failure      400          {object} dto.Error400
+// @Failure      401          {object} dto.ErrorResponse
+// @Failure      500          {object} dto.Error500
+// @Router       /login [post]
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	var credentials dto.LoginRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&credentials)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid JSON",
+		})
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(credentials)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.(validator.ValidationErrors).Error(),
+		})
+		return
+	}
+
+	pair, err := h.a.Login(r.Context(), credentials.Login, credentials.Password)
+	if err != nil {
+		if errors.Is(err, apperr.ErrInvalidCredentials) {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusUnauthorized,
+				Message: "invalid login or password",
+			})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "internal server error",
+		})
+		return
+	}
+
+	res := mapper.TokenPairToDTO(pair)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// RefreshToken godoc
+// @Summary      Обменять refresh-токен на новую пару токенов
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        token  body     dto.RefreshTokenRequestDTO  true  "Refresh-токен"
+// @Success      200    {object} dto.TokenResponseDTO
+// @Failure      400    {object} dto.Error400
+// @Failure      401    {object} dto.ErrorResponse
+// @Failure      500    {object} dto.Error500
+// @Router       /token/refresh [post]
+func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	var request dto.RefreshTokenRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid JSON",
+		})
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.(validator.ValidationErrors).Error(),
+		})
+		return
+	}
+
+	pair, err := h.a.RefreshToken(r.Context(), request.RefreshToken)
+	if err != nil {
+		if errors.Is(err, apperr.ErrRefreshTokenInvalid) || errors.Is(err, apperr.ErrRefreshTokenReused) {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusUnauthorized,
+				Message: err.Error(),
+			})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "internal server error",
+		})
+		return
+	}
+
+	res := mapper.TokenPairToDTO(pair)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// CreateUser godoc
+// @Summary      Создать пользователя
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user  body     dto.UserCreateRequestDTO  true  "Создаваемый пользователь"
+// @Success      201   {object} dto.UserResponseDTO
+// @Failure      400   {object} dto.Error400
+// @Failure      401   {object} dto.ErrorResponse
+// @Failure      409   {object} dto.ErrorResponse
+// @Failure      500   {object} dto.Error500
+// @Router       /user [post]
+func (h *AuthHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var user dto.UserCreateRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&user)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid JSON",
+		})
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(user)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.(validator.ValidationErrors).Error(),
+		})
+		return
+	}
+
+	created, err := h.a.CreateUser(r.Context(), user.Login, user.Password, user.Role)
+	if err != nil {
+		if errors.Is(err, apperr.ErrUserExists) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusConflict,
+				Message: "user already exists",
+			})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "internal server error",
+		})
+		return
+	}
+
+	res := mapper.UserEntityToDTO(created)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(res)
+}
+
+// Logout godoc
+// @Summary      Выйти и отозвать текущий токен
+// @Tags         auth
+// @Security     BearerAuth
+// @Success      204
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.Error500
+// @Router       /logout [post]
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-	uuid, _ := utils.GenerateUUID()
-	userID := "user-" + uuid
+	token, ok := middleware.TokenFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "unauthorized: missing token",
+		})
+		return
+	}
 
-	token, err := h.a.GenerateToken(userID)
+	err := h.a.Logout(r.Context(), token)
 	if err != nil {
-		http.Error(w, "failed to generate token", http.StatusInternalServerError)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "internal server error",
+		})
 		return
 	}
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	resp := map[string]string{"token": token}
+// RevokeUserTokens godoc
+// @Summary      Отозвать все токены пользователя
+// @Tags         auth
+// @Security     BearerAuth
+// @Param        id   path  string  true  "ID пользователя"
+// @Success      204
+// @Failure      400  {object} dto.Error400
+// @Failure      401  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.Error404
+// @Failure      500  {object} dto.Error500
+// @Router       /user/{id}/tokens [delete]
+func (h *AuthHandler) RevokeUserTokens(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	id := mux.Vars(r)["id"]
+	if validator.New().Var(id, "required,uuid") != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	err := h.a.RevokeUserTokens(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, apperr.ErrUserNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: "user not found",
+			})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "internal server error",
+		})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
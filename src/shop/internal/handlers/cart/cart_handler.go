@@ -0,0 +1,311 @@
+package cart
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/dto"
+	"backend2/internal/entity"
+	"backend2/internal/mapper"
+	"backend2/internal/middleware"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+type Cart interface {
+	GetCart(ctx context.Context, clientId string) (entity.Cart, error)
+	AddItem(ctx context.Context, clientId, productId string, quantity int) (entity.Cart, error)
+	UpdateItem(ctx context.Context, clientId, productId string, quantity int) (entity.Cart, error)
+	RemoveItem(ctx context.Context, clientId, productId string) (entity.Cart, error)
+	Checkout(ctx context.Context, clientId, actor string) (entity.Order, entity.Cart, error)
+}
+
+type CartHandler struct {
+	cart Cart
+}
+
+func NewCartHandler(cart Cart) *CartHandler {
+	return &CartHandler{cart: cart}
+}
+
+// GetCart godoc
+// @Summary      Получить корзину клиента
+// @Description  Цены и остатки берутся актуальные. Удалённые товары и товары с недостаточным остатком помечаются в поле problem.
+// @Tags         cart
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path     string  true  "ID клиента"
+// @Success      200  {object} dto.CartResponseDTO
+// @Failure      400  {object} dto.Error400
+// @Failure      403  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.Error404
+// @Failure      500  {object} dto.Error500
+// @Router       /client/{id}/cart [get]
+func (c *CartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	cart, err := c.cart.GetCart(r.Context(), id)
+	if err != nil {
+		writeCartError(w, err)
+		return
+	}
+
+	res := mapper.CartEntityToDTO(cart)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// AddItem godoc
+// @Summary      Добавить товар в корзину
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path     string                     true  "ID клиента"
+// @Param        item  body     dto.CartItemAddRequestDTO  true  "Товар и количество"
+// @Success      200   {object} dto.CartResponseDTO
+// @Failure      400   {object} dto.Error400
+// @Failure      403   {object} dto.ErrorResponse
+// @Failure      404   {object} dto.Error404
+// @Failure      500   {object} dto.Error500
+// @Router       /client/{id}/cart [post]
+func (c *CartHandler) AddItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	var request dto.CartItemAddRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid JSON",
+		})
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.(validator.ValidationErrors).Error(),
+		})
+		return
+	}
+
+	cart, err := c.cart.AddItem(r.Context(), id, request.ProductId, request.Quantity)
+	if err != nil {
+		writeCartError(w, err)
+		return
+	}
+
+	res := mapper.CartEntityToDTO(cart)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// UpdateItem godoc
+// @Summary      Изменить количество товара в корзине
+// @Tags         cart
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id          path     string                        true  "ID клиента"
+// @Param        product_id  path     string                        true  "ID товара"
+// @Param        item        body     dto.CartItemUpdateRequestDTO  true  "Новое количество"
+// @Success      200         {object} dto.CartResponseDTO
+// @Failure      400         {object} dto.Error400
+// @Failure      403         {object} dto.ErrorResponse
+// @Failure      404         {object} dto.Error404
+// @Failure      500         {object} dto.Error500
+// @Router       /client/{id}/cart/{product_id} [patch]
+func (c *CartHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	productId := mux.Vars(r)["product_id"]
+	if id == "" || productId == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	var request dto.CartItemUpdateRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid JSON",
+		})
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.(validator.ValidationErrors).Error(),
+		})
+		return
+	}
+
+	cart, err := c.cart.UpdateItem(r.Context(), id, productId, request.Quantity)
+	if err != nil {
+		writeCartError(w, err)
+		return
+	}
+
+	res := mapper.CartEntityToDTO(cart)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// RemoveItem godoc
+// @Summary      Удалить товар из корзины
+// @Tags         cart
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id          path     string  true  "ID клиента"
+// @Param        product_id  path     string  true  "ID товара"
+// @Success      200         {object} dto.CartResponseDTO
+// @Failure      400         {object} dto.Error400
+// @Failure      403         {object} dto.ErrorResponse
+// @Failure      404         {object} dto.Error404
+// @Failure      500         {object} dto.Error500
+// @Router       /client/{id}/cart/{product_id} [delete]
+func (c *CartHandler) RemoveItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	productId := mux.Vars(r)["product_id"]
+	if id == "" || productId == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	cart, err := c.cart.RemoveItem(r.Context(), id, productId)
+	if err != nil {
+		writeCartError(w, err)
+		return
+	}
+
+	res := mapper.CartEntityToDTO(cart)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// Checkout godoc
+// @Summary      Оформить заказ из корзины
+// @Tags         cart
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path     string  true  "ID клиента"
+// @Success      201  {object} dto.OrderResponseDTO
+// @Failure      400  {object} dto.Error400 "cart is empty"
+// @Failure      403  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.Error404
+// @Failure      409  {object} dto.CartConflictResponse "cart has unavailable items"
+// @Failure      500  {object} dto.Error500
+// @Router       /client/{id}/cart/checkout [post]
+func (c *CartHandler) Checkout(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+	order, cart, err := c.cart.Checkout(r.Context(), id, principal.Actor())
+	if err != nil {
+		if errors.Is(err, apperr.ErrCartUnavailable) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(dto.CartConflictResponse{
+				Code:    http.StatusConflict,
+				Message: "cart has unavailable items",
+				Cart:    mapper.CartEntityToDTO(cart),
+			})
+			return
+		}
+		writeCartError(w, err)
+		return
+	}
+
+	res := mapper.OrderEntityToDTO(order)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(res)
+}
+
+func writeCartError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, apperr.ErrClientNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "client not found",
+		})
+	case errors.Is(err, apperr.ErrProductNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "product not found",
+		})
+	case errors.Is(err, apperr.ErrCartItemNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "cart item not found",
+		})
+	case errors.Is(err, apperr.ErrCartEmpty):
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "cart is empty",
+		})
+	case errors.Is(err, apperr.ErrInsufficientStock):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusConflict,
+			Message: "insufficient stock",
+		})
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "internal server error",
+		})
+	}
+}
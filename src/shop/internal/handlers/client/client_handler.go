@@ -4,21 +4,31 @@ import (
 	"backend2/internal/apperr"
 	"backend2/internal/dto"
 	"backend2/internal/entity"
+	"backend2/internal/handlers"
 	"backend2/internal/mapper"
+	"backend2/internal/middleware"
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type ClientUsecases interface {
-	CreateClient(client entity.Client) (entity.Client, error)
-	UpdateClient(id string, client entity.Client) (entity.Client, error)
-	DeleteClient(id string) error
-	GetAllClients(limit, offset int) ([]entity.Client, error)
-	GetClientsByNameSurname(name, surname string) ([]entity.Client, error)
+	CreateClient(ctx context.Context, client entity.Client) (entity.Client, error)
+	UpdateClient(ctx context.Context, id string, patch entity.ClientPatch) (entity.Client, error)
+	DeleteClient(ctx context.Context, id string, opts entity.DeleteOptions, actor string) error
+	RestoreClient(ctx context.Context, id string) (entity.Client, error)
+	GetAllClients(ctx context.Context, filter entity.ClientFilter) (entity.Page[entity.Client], error)
+	GetClientsByNameSurname(ctx context.Context, name, surname string) ([]entity.Client, error)
 }
 
 type ClientHandler struct {
@@ -68,7 +78,7 @@ func (c *ClientHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
 	}
 
 	entityClient := mapper.ClientCreateRequestToEntity(client)
-	entityClient, err := c.client.CreateClient(entityClient)
+	entityClient, err := c.client.CreateClient(r.Context(), entityClient)
 
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -86,8 +96,10 @@ func (c *ClientHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
 
 // UpdateClient godoc
 // @Summary      Обновить клиента
+// @Description  Частичное обновление (JSON Merge Patch): меняются только переданные поля, клиент и адрес обновляются в одной транзакции. null для полей не допускается.
 // @Tags         clients
 // @Accept       json
+// @Accept       application/merge-patch+json
 // @Produce      json
 // @Param        id      path     string  true  "ID клиента"
 // @Param        client  body     dto.ClientUpdateRequestDTO  true  "Обновляемые поля"
@@ -112,6 +124,7 @@ func (c *ClientHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
 //
 // @Failure      400     {object} dto.Error400 "Bad request: invalid JSON or validation failed
 // @Failure      404     {object} dto.Error404 "client not found"
+// @Failure      415     {object} dto.ErrorResponse "unsupported content type"
 // @Failure      500     {object} dto.Error500 "internal error"
 // @Router       /client/{id} [patch]
 func (c *ClientHandler) UpdateClient(w http.ResponseWriter, r *http.Request) {
@@ -128,14 +141,23 @@ func (c *ClientHandler) UpdateClient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var client dto.ClientUpdateRequestDTO
-	if err := json.NewDecoder(r.Body).Decode(&client); err != nil {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "" && mediaType != "application/json" && mediaType != mergePatchContentType {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Message: "content type must be application/json or " + mergePatchContentType,
+			Code:    http.StatusUnsupportedMediaType,
+		})
+		return
+	}
+
+	client, err := decodeClientPatch(r)
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Message: "invalid JSON",
+			Message: err.Error(),
 			Code:    http.StatusBadRequest,
 		})
-		//http.Error(w, `{"status":400,"error":"invalid JSON"}`, http.StatusBadRequest)
 		return
 	}
 
@@ -150,8 +172,7 @@ func (c *ClientHandler) UpdateClient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	entityClient := mapper.ClientUpdateRequestToEntity(client)
-	entityClient, err := c.client.UpdateClient(id, entityClient)
+	entityClient, err := c.client.UpdateClient(r.Context(), id, mapper.ClientUpdateRequestToEntity(client))
 	if err != nil {
 		if errors.Is(err, apperr.ErrClientNotFound) {
 			w.WriteHeader(http.StatusNotFound)
@@ -161,6 +182,14 @@ func (c *ClientHandler) UpdateClient(w http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
+		if errors.Is(err, apperr.ErrAddressNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Message: "client address not found",
+				Code:    http.StatusNotFound,
+			})
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(dto.ErrorResponse{
 			Message: "internal server error",
@@ -174,13 +203,46 @@ func (c *ClientHandler) UpdateClient(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(res)
 }
 
+const mergePatchContentType = "application/merge-patch+json"
+
+// decodeClientPatch разбирает тело PATCH. В merge patch null означает удаление поля,
+// а у клиента все поля обязательны, поэтому null отклоняется, как и неизвестные поля.
+func decodeClientPatch(r *http.Request) (dto.ClientUpdateRequestDTO, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return dto.ClientUpdateRequestDTO{}, errors.New("invalid JSON")
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return dto.ClientUpdateRequestDTO{}, errors.New("invalid JSON")
+	}
+	for name, value := range fields {
+		if string(value) == "null" {
+			return dto.ClientUpdateRequestDTO{}, fmt.Errorf("field %s cannot be null", name)
+		}
+	}
+
+	var client dto.ClientUpdateRequestDTO
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&client); err != nil {
+		return dto.ClientUpdateRequestDTO{}, fmt.Errorf("invalid JSON: %v", err)
+	}
+	return client, nil
+}
+
 // DeleteClient godoc
 // @Summary      Удалить клиента
+// @Description  По умолчанию клиент помечается удалённым и может быть восстановлен. hard=true удаляет его вместе с адресами и корзиной; если есть заказы, возвращается 409 со списком, а cascade=true удаляет и заказы.
 // @Tags         clients
-// @Param        id   path  string  true  "ID клиента"
+// @Param        id       path   string  true   "ID клиента"
+// @Param        hard     query  bool    false  "Удалить физически"
+// @Param        cascade  query  bool    false  "Удалить физически вместе с заказами"
 // @Success      200
 // @Failure 400 {object} dto.Error400 "Bad request"
 // @Failure 404 {object} dto.Error404 "Client not found"
+// @Failure 409 {object} dto.Error409Dependents "Client has orders"
 // @Failure 500 {object} dto.Error500 "Internal error"
 // @Router       /client/{id} [delete]
 func (c *ClientHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
@@ -198,7 +260,18 @@ func (c *ClientHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := c.client.DeleteClient(id)
+	opts, err := handlers.ParseDeleteOptions(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+	err = c.client.DeleteClient(r.Context(), id, opts, principal.Actor())
 	if err != nil {
 		if errors.Is(err, apperr.ErrClientNotFound) {
 			w.WriteHeader(http.StatusNotFound)
@@ -208,6 +281,12 @@ func (c *ClientHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
+		var dependents *apperr.DependentsError
+		if errors.As(err, &dependents) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(mapper.DependentsErrorToDTO(dependents))
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(dto.ErrorResponse{
 			Message: "internal server error",
@@ -219,81 +298,89 @@ func (c *ClientHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
 
 }
 
-// GetAllClients godoc
-// @Summary      Получить всех клиентов
+// RestoreClient godoc
+// @Summary      Восстановить удалённого клиента
 // @Tags         clients
 // @Produce      json
-// @Success      200  {array}  dto.ClientResponseDTO
-// @Success      400  {object}  dto.Error400
-// @Failure      404  {object} dto.ClientsNotFound
-// @Failure 	 500 {object} dto.Error500 "Internal error"
-// @Param 		 limit  query string false "количество отоброжаемых клиентов"
-// @Param 		 offset  query string false "Смещение выборки"
-// @Router       /clients [get]
-func (c *ClientHandler) GetAllClients(w http.ResponseWriter, r *http.Request) {
+// @Param        id   path  string  true  "ID клиента"
+// @Success      200  {object} dto.ClientResponseDTO
+// @Failure 400 {object} dto.Error400 "Bad request"
+// @Failure 404 {object} dto.Error404 "Client not found"
+// @Failure 500 {object} dto.Error500 "Internal error"
+// @Router       /client/{id}/restore [post]
+func (c *ClientHandler) RestoreClient(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Cache-Control", "public, max-age=60")
-
-	var limit, offset int
-	var err error
-	if r.URL.Query().Get("limit") != "" {
-		limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
-		if err != nil {
-
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(dto.ErrorResponse{
-				Message: "internal server error",
-				Code:    http.StatusInternalServerError,
-			})
-			return
-		}
-	} else {
-		limit = 0
-	}
-
-	if limit < 0 {
 
+	id := mux.Vars(r)["id"]
+	if id == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Message: "limit cannot be negative",
+			Message: "invalid id",
 			Code:    http.StatusBadRequest,
 		})
 		return
 	}
 
-	if r.URL.Query().Get("offset") != "" {
-		offset, err = strconv.Atoi(r.URL.Query().Get("offset"))
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+	client, err := c.client.RestoreClient(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, apperr.ErrClientNotFound) {
+			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(dto.ErrorResponse{
-				Message: "internal server error",
-				Code:    http.StatusInternalServerError,
+				Message: "client not found",
+				Code:    http.StatusNotFound,
 			})
 			return
 		}
-	} else {
-		offset = 0
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Message: "internal server error",
+			Code:    http.StatusInternalServerError,
+		})
+		return
 	}
 
-	if offset < 0 {
+	res := mapper.ClientCreateResponse(client)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// GetAllClients godoc
+// @Summary      Поиск клиентов
+// @Description  Все фильтры необязательны. Имя и фамилия ищутся по подстроке без учёта регистра, даты — YYYY-MM-DD или RFC3339, границы включаются.
+// @Tags         clients
+// @Produce      json
+// @Success      200  {object} dto.ClientsPageDTO
+// @Success      400  {object}  dto.Error400
+// @Failure 	 500 {object} dto.Error500 "Internal error"
+// @Param 		 limit  query string false "количество отоброжаемых клиентов"
+// @Param 		 offset  query string false "Смещение выборки"
+// @Param        q                query  string  false  "Подстрока имени или фамилии"
+// @Param        name             query  string  false  "Подстрока имени"
+// @Param        surname          query  string  false  "Подстрока фамилии"
+// @Param        gender           query  string  false  "Пол"
+// @Param        birth_from       query  string  false  "Дата рождения с"
+// @Param        birth_to         query  string  false  "Дата рождения по"
+// @Param        registered_from  query  string  false  "Дата регистрации с"
+// @Param        registered_to    query  string  false  "Дата регистрации по"
+// @Param        country          query  string  false  "Страна"
+// @Param        city             query  string  false  "Город"
+// @Router       /clients [get]
+func (c *ClientHandler) GetAllClients(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=60")
+
+	filter, err := parseClientFilter(r)
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Message: "offset cannot be negative",
+			Message: err.Error(),
 			Code:    http.StatusBadRequest,
 		})
 		return
 	}
 
-	clients, err := c.client.GetAllClients(limit, offset)
+	page, err := c.client.GetAllClients(r.Context(), filter)
 	if err != nil {
-		if errors.Is(err, apperr.ErrClientNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(dto.ClientsNotFound{
-				Clients: make([]entity.Client, 0),
-				Message: "clients not found"})
-			return
-		}
-
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(dto.ErrorResponse{
 			Message: "internal server error",
@@ -302,18 +389,86 @@ func (c *ClientHandler) GetAllClients(w http.ResponseWriter, r *http.Request) {
 
 		return
 	}
-	result := mapper.GetClientsResponse(clients)
+	result := mapper.ClientsPageToDTO(page)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(result)
 
 }
 
+func parseClientFilter(r *http.Request) (entity.ClientFilter, error) {
+	q := r.URL.Query()
+	filter := entity.ClientFilter{
+		Query:   strings.TrimSpace(q.Get("q")),
+		Name:    strings.TrimSpace(q.Get("name")),
+		Surname: strings.TrimSpace(q.Get("surname")),
+		Gender:  q.Get("gender"),
+		Country: q.Get("country"),
+		City:    q.Get("city"),
+	}
+
+	var err error
+	if v := q.Get("limit"); v != "" {
+		filter.Limit, err = strconv.Atoi(v)
+		if err != nil {
+			return entity.ClientFilter{}, errors.New("invalid limit")
+		}
+		if filter.Limit < 0 {
+			return entity.ClientFilter{}, errors.New("limit cannot be negative")
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		filter.Offset, err = strconv.Atoi(v)
+		if err != nil {
+			return entity.ClientFilter{}, errors.New("invalid offset")
+		}
+		if filter.Offset < 0 {
+			return entity.ClientFilter{}, errors.New("offset cannot be negative")
+		}
+	}
+
+	dates := []struct {
+		param string
+		dst   *time.Time
+		end   bool
+	}{
+		{"birth_from", &filter.BirthFrom, false},
+		{"birth_to", &filter.BirthTo, true},
+		{"registered_from", &filter.RegisteredFrom, false},
+		{"registered_to", &filter.RegisteredTo, true},
+	}
+	for _, d := range dates {
+		v := q.Get(d.param)
+		if v == "" {
+			continue
+		}
+		*d.dst, err = parseDate(v, d.end)
+		if err != nil {
+			return entity.ClientFilter{}, fmt.Errorf("invalid %s", d.param)
+		}
+	}
+	return filter, nil
+}
+
+// parseDate принимает YYYY-MM-DD или RFC3339. Для верхней границы дата без времени
+// означает конец дня.
+func parseDate(v string, end bool) (time.Time, error) {
+	t, err := time.Parse(time.DateOnly, v)
+	if err == nil {
+		if end {
+			t = t.Add(24*time.Hour - time.Microsecond)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
 // GetClientsByNameSurname godoc
 // @Summary      Поиск клиента по имени и фамилии
+// @Description  Частичное совпадение без учёта регистра, нужен хотя бы один из параметров. Расширенный поиск — GET /clients.
 // @Tags         clients
 // @Produce      json
-// @Param        name     query    string  true  "Имя"
-// @Param        surname  query    string  true  "Фамилия"
+// @Param        name     query    string  false  "Подстрока имени"
+// @Param        surname  query    string  false  "Подстрока фамилии"
 // @Success      200      {array}  dto.ClientResponseDTO
 // @Failure 400 {object} dto.Error400 "Bad request"
 // @Failure      404      {object} dto.ClientsNotFound "not found"
@@ -330,28 +485,19 @@ func (c *ClientHandler) GetAllClients(w http.ResponseWriter, r *http.Request) {
 func (c *ClientHandler) GetClientsByNameSurname(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Cache-Control", "public, max-age=60")
-	name := r.URL.Query().Get("name")
-	surname := r.URL.Query().Get("surname")
-	if name == "" {
-
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Message: "missing name",
-			Code:    http.StatusBadRequest,
-		})
-		return
-	}
-	if surname == "" {
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	surname := strings.TrimSpace(r.URL.Query().Get("surname"))
+	if name == "" && surname == "" {
 
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Message: "missing surname",
+			Message: "missing name or surname",
 			Code:    http.StatusBadRequest,
 		})
 		return
 	}
 
-	clients, err := c.client.GetClientsByNameSurname(name, surname)
+	clients, err := c.client.GetClientsByNameSurname(r.Context(), name, surname)
 	if err != nil {
 		if errors.Is(err, apperr.ErrClientNotFound) {
 			w.WriteHeader(http.StatusNotFound)
@@ -0,0 +1,28 @@
+// Package handlers содержит помощники, общие для HTTP-обработчиков ресурсов.
+package handlers
+
+import (
+	"backend2/internal/entity"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// ParseDeleteOptions читает hard и cascade; cascade подразумевает hard.
+func ParseDeleteOptions(r *http.Request) (entity.DeleteOptions, error) {
+	var opts entity.DeleteOptions
+	var err error
+	q := r.URL.Query()
+	if v := q.Get("hard"); v != "" {
+		if opts.Hard, err = strconv.ParseBool(v); err != nil {
+			return entity.DeleteOptions{}, errors.New("invalid hard")
+		}
+	}
+	if v := q.Get("cascade"); v != "" {
+		if opts.Cascade, err = strconv.ParseBool(v); err != nil {
+			return entity.DeleteOptions{}, errors.New("invalid cascade")
+		}
+	}
+	opts.Hard = opts.Hard || opts.Cascade
+	return opts, nil
+}
@@ -5,22 +5,35 @@ import (
 	"backend2/internal/dto"
 	"backend2/internal/entity"
 	"backend2/internal/mapper"
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
 	"io"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type Image interface {
-	AddImage(productID string, img []byte) (entity.Image, error)
-	GetImageById(id string) (entity.Image, error)
-	UpdateImage(id string, img []byte) (entity.Image, error)
-	GetProductImageById(productId string) (entity.Image, error)
-	DeleteImage(id string) error
+	AddImage(ctx context.Context, productID, filename string, img []byte) (entity.Image, error)
+	GetImageById(ctx context.Context, id string) (entity.Image, error)
+	UpdateImage(ctx context.Context, id, filename string, img []byte) (entity.Image, error)
+	GetProductImageById(ctx context.Context, productId string) (entity.Image, error)
+	DeleteImage(ctx context.Context, id string) error
+	GetProductImages(ctx context.Context, productId string) ([]entity.ProductImage, error)
+	ReorderProductImages(ctx context.Context, productId string, imageIds []string) ([]entity.ProductImage, error)
+	SetPrimaryImage(ctx context.Context, productId, imageId string) ([]entity.ProductImage, error)
+	DetachProductImage(ctx context.Context, productId, imageId string) error
+	GetImageVariant(ctx context.Context, id string, variant entity.ImageVariant) (entity.Image, error)
 }
 
+// maxImageSize — наибольший размер загружаемого файла.
+const maxImageSize = 10 << 20
+
 type ImageHandler struct {
 	img Image
 }
@@ -33,6 +46,8 @@ func NewImageHandler(img Image) *ImageHandler {
 
 // AddImage godoc
 // @Summary      Загрузить изображение
+// @Description  Изображение добавляется в конец галереи товара; первое изображение товара становится основным.
+// @Description  Принимаются JPEG, PNG, WebP и GIF до 10 МБ. Формат определяется по содержимому файла.
 // @Tags         images
 // @Accept       multipart/form-data
 // @Produce      json
@@ -40,6 +55,9 @@ func NewImageHandler(img Image) *ImageHandler {
 // @Param        image  formData file    true  "Файл изображения"
 // @Success      200    {object} dto.ImageDTO
 // @Failure      400    {object} dto.ErrorResponse
+// @Failure      404    {object} dto.ErrorResponse
+// @Failure      413    {object} dto.ErrorResponse
+// @Failure      415    {object} dto.ErrorResponse "not a JPEG, PNG, WebP or GIF image"
 // @Failure      500    {object} dto.ErrorResponse
 // @Router       /image/{id} [post]
 func (i *ImageHandler) AddImage(w http.ResponseWriter, r *http.Request) {
@@ -52,56 +70,14 @@ func (i *ImageHandler) AddImage(w http.ResponseWriter, r *http.Request) {
 			Message: "id is required"})
 		return
 	}
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: "internal server error",
-		})
-		return
-	}
-
-	file, _, err := r.FormFile("image")
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: "image upload error",
-		})
-		//http.Error(w, `{"status":500,"error":"image upload error"}`, http.StatusInternalServerError)
+	filename, imgBytes, ok := readImageUpload(w, r)
+	if !ok {
 		return
 	}
-	defer file.Close()
 
-	imgBytes, err := io.ReadAll(file)
+	img, err := i.img.AddImage(r.Context(), id, filename, imgBytes)
 	if err != nil {
-		//http.Error(w, `{"status":500,"error":"failed to read file"}`, http.StatusInternalServerError)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: "internal server error",
-		})
-		return
-	}
-
-	img, err := i.img.AddImage(id, imgBytes)
-	if err != nil {
-		if errors.Is(err, apperr.ErrProductNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(dto.ErrorResponse{
-				Code:    http.StatusNotFound,
-				Message: "product not found",
-			})
-			return
-		}
-
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: "internal server error",
-		})
-
+		writeImageError(w, err)
 		return
 	}
 	res := mapper.ImgEntityToDTO(img)
@@ -111,16 +87,20 @@ func (i *ImageHandler) AddImage(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetProductImageById godoc
-// @Summary      Получить изображение товара
+// @Summary      Получить основное изображение товара
+// @Description  Поддерживаются условные запросы (If-None-Match, If-Modified-Since) и Range.
 // @Tags         images
-// @Produce      octet-stream
-// @Param        id   path  string  true  "ID продукта"
+// @Produce      image/jpeg,image/png,image/webp,image/gif
+// @Param        id   path   string  true   "ID продукта"
+// @Param        v    query  string  false  "sha256 файла; если совпадает, ответ кэшируется навсегда"
 // @Success      200  {file} binary
+// @Success      206  {file} binary
+// @Success      304
 // @Failure      404  {object} dto.ErrorResponse
+// @Failure      416
 // @Router       /products/{id}/image [get]
 func (i *ImageHandler) GetProductImageById(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Cache-Control", "public, max-age=60")
 	id := mux.Vars(r)["id"]
 	if id == "" {
 		w.WriteHeader(http.StatusBadRequest)
@@ -131,45 +111,41 @@ func (i *ImageHandler) GetProductImageById(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	productImage, err := i.img.GetProductImageById(id)
+	productImage, err := i.img.GetProductImageById(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, apperr.ErrImageNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(dto.ErrorResponse{
-				Code:    http.StatusNotFound,
-				Message: "image not found",
-			})
-			return
-		}
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: "internal server error",
-		})
+		writeImageError(w, err)
 		return
 	}
 
-	img := productImage.Image
-
-	w.Header().Set("Content-Type", "application/octet-stream,image/jpeg,image/png")
-	//w.Header().Set("Content-Disposition", `attachment; filename="image.jpg"`)
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(img)))
-
-	w.WriteHeader(http.StatusOK)
-	w.Write(img)
+	writeImage(w, r, productImage)
 }
 
 // GetImageById godoc
 // @Summary      Получить изображение по ID
+// @Description  Без параметров отдаётся исходный файл. С параметрами отдаётся уменьшенный вариант:
+// @Description  изображение не увеличивается, варианты кэшируются после первого запроса.
+// @Description  Размеры — 64, 128, 200, 256, 400, 512, 800, 1024, 1600; если задана одна сторона, пропорции сохраняются.
+// @Description  Готовые варианты: thumbnail (128×128, cover), medium (512×512), large (1024×1024); w, h, fit и format их уточняют.
+// @Description  ETag — sha256 отдаваемого файла; поддерживаются If-None-Match, If-Modified-Since и Range.
 // @Tags         images
-// @Produce      octet-stream
-// @Param        id   path  string  true  "ID изображения"
+// @Produce      image/jpeg,image/png,image/webp,image/gif
+// @Param        id       path   string  true   "ID изображения"
+// @Param        variant  query  string  false  "Готовый вариант"  Enums(thumbnail, medium, large)
+// @Param        w        query  int     false  "Ширина"
+// @Param        h        query  int     false  "Высота"
+// @Param        fit      query  string  false  "Как вписать в размеры, по умолчанию contain"  Enums(contain, cover, fill)
+// @Param        format   query  string  false  "Формат, по умолчанию как у исходника"  Enums(jpeg, png, webp, gif)
+// @Param        v        query  string  false  "sha256 исходного файла; если совпадает, ответ кэшируется навсегда"
 // @Success      200  {file} binary
+// @Success      206  {file} binary
+// @Success      304
+// @Failure      400  {object} dto.ErrorResponse
 // @Failure      404  {object} dto.ErrorResponse
+// @Failure      413  {object} dto.ErrorResponse
+// @Failure      416
 // @Router       /image/{id} [get]
 func (i *ImageHandler) GetImageById(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Cache-Control", "public, max-age=60")
 	id := mux.Vars(r)["id"]
 	if id == "" {
 		w.WriteHeader(http.StatusBadRequest)
@@ -181,36 +157,66 @@ func (i *ImageHandler) GetImageById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	productImage, err := i.img.GetImageById(id)
+	variant, ok, err := parseImageVariant(r)
 	if err != nil {
-		if errors.Is(err, apperr.ErrImageNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(dto.ErrorResponse{
-				Code:    http.StatusNotFound,
-				Message: "image not found",
-			})
-			return
-		}
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: "internal server error",
-		})
+		writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var productImage entity.Image
+	if ok {
+		productImage, err = i.img.GetImageVariant(r.Context(), id, variant)
+	} else {
+		productImage, err = i.img.GetImageById(r.Context(), id)
+	}
+	if err != nil {
+		writeImageError(w, err)
 		return
 	}
 
-	img := productImage.Image
+	writeImage(w, r, productImage)
+}
 
-	w.Header().Set("Content-Type", "application/octet-stream,image/jpeg,image/png")
-	//w.Header().Set("Content-Disposition", `attachment; filename="image.jpg"`)
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(img)))
+// variantMessage перечисляет допустимые параметры варианта для ответа 400.
+var variantMessage = fmt.Sprintf(
+	"variant must be thumbnail, medium or large; w and h must be one of %s; fit must be contain, cover or fill; format must be jpeg, png, webp or gif",
+	strings.Trim(fmt.Sprint(entity.ImageVariantSizes), "[]"),
+)
 
-	w.WriteHeader(http.StatusOK)
-	w.Write(img)
+// parseImageVariant читает параметры варианта из строки запроса.
+// ok == false, если вариант не запрошен и нужен исходный файл.
+func parseImageVariant(r *http.Request) (entity.ImageVariant, bool, error) {
+	q := r.URL.Query()
+	var variant entity.ImageVariant
+	ok := false
+	if name := q.Get("variant"); name != "" {
+		preset, found := entity.ImageVariantPresets[name]
+		if !found {
+			return entity.ImageVariant{}, false, errors.New(variantMessage)
+		}
+		variant, ok = preset, true
+	}
+	for param, side := range map[string]*int{"w": &variant.Width, "h": &variant.Height} {
+		if v := q.Get(param); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return entity.ImageVariant{}, false, errors.New(variantMessage)
+			}
+			*side, ok = n, true
+		}
+	}
+	if fit := q.Get("fit"); fit != "" {
+		variant.Fit, ok = fit, true
+	}
+	if format := q.Get("format"); format != "" {
+		variant.Format, ok = format, true
+	}
+	return variant, ok, nil
 }
 
 // DeleteImage godoc
 // @Summary      Удалить изображение
+// @Description  Изображение убирается из всех галерей. Если оно было основным, основным становится следующее.
 // @Tags         images
 // @Param        id   path  string  true  "ID изображения"
 // @Success      200
@@ -227,21 +233,9 @@ func (i *ImageHandler) DeleteImage(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
-	err := i.img.DeleteImage(id)
+	err := i.img.DeleteImage(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, apperr.ErrImageNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(dto.ErrorResponse{
-				Code:    http.StatusNotFound,
-				Message: "image not found",
-			})
-			return
-		}
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: "internal server error",
-		})
+		writeImageError(w, err)
 		return
 	}
 
@@ -252,15 +246,18 @@ func (i *ImageHandler) DeleteImage(w http.ResponseWriter, r *http.Request) {
 // @Summary      Обновить изображение
 // @Tags         images
 // @Accept       multipart/form-data
-// @Produce      octet-stream
+// @Produce      image/jpeg,image/png,image/webp,image/gif
 // @Param        id     path     string  true  "ID изображения"
 // @Param        image  formData file    true  "Новый файл"
 // @Success      200    {file}   binary
 // @Failure      400    {object} dto.ErrorResponse
+// @Failure      404    {object} dto.ErrorResponse
+// @Failure      413    {object} dto.ErrorResponse
+// @Failure      415    {object} dto.ErrorResponse "not a JPEG, PNG, WebP or GIF image"
 // @Failure      500    {object} dto.ErrorResponse
 // @Router       /image/{id} [patch]
 func (i *ImageHandler) UpdateImage(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/octet-stream,image/jpeg,image/png,application/json")
+	w.Header().Set("Content-Type", "application/json")
 	id := mux.Vars(r)["id"]
 	if id == "" {
 		w.WriteHeader(http.StatusBadRequest)
@@ -271,54 +268,126 @@ func (i *ImageHandler) UpdateImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: "internal server error",
-		})
+	filename, imgBytes, ok := readImageUpload(w, r)
+	if !ok {
 		return
 	}
 
-	file, _, err := r.FormFile("image")
+	img, err := i.img.UpdateImage(r.Context(), id, filename, imgBytes)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: "internal server error",
-		})
+		writeImageError(w, err)
 		return
 	}
+	writeImage(w, r, img)
+}
+
+// readImageUpload читает файл из поля image формы. При ошибке ответ уже записан.
+func readImageUpload(w http.ResponseWriter, r *http.Request) (string, []byte, bool) {
+	// запас на заголовки и границы multipart
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageSize+1<<20)
+	if err := r.ParseMultipartForm(maxImageSize); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeErrorResponse(w, http.StatusRequestEntityTooLarge, "image is too large")
+			return "", nil, false
+		}
+		writeErrorResponse(w, http.StatusBadRequest, "invalid multipart form")
+		return "", nil, false
+	}
 
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "image is required")
+		return "", nil, false
+	}
 	defer file.Close()
+
+	if header.Size > maxImageSize {
+		writeErrorResponse(w, http.StatusRequestEntityTooLarge, "image is too large")
+		return "", nil, false
+	}
+
 	imgBytes, err := io.ReadAll(file)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: "internal server error",
-		})
-		return
+		writeErrorResponse(w, http.StatusInternalServerError, "internal server error")
+		return "", nil, false
 	}
+	return header.Filename, imgBytes, true
+}
 
-	img, err := i.img.UpdateImage(id, imgBytes)
-	if err != nil {
-		if errors.Is(err, apperr.ErrImageNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(dto.ErrorResponse{
-				Code:    http.StatusNotFound,
-				Message: "image not found",
-			})
-			return
-		}
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: "internal server error",
-		})
+func writeErrorResponse(w http.ResponseWriter, code int, message string) {
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(dto.ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
+
+// Cache-Control изображений. По адресу с версией содержимого (?v=sha256, см.
+// dto.ImageDTO.Url) файл никогда не меняется, его можно кэшировать навсегда.
+const (
+	imageCacheControl     = "public, max-age=60"
+	immutableCacheControl = "public, max-age=31536000, immutable"
+)
+
+// writeImage отдаёт файл изображения с его MIME-типом. Условные запросы
+// (If-None-Match, If-Modified-Since) и Range обрабатывает http.ServeContent:
+// ETag — sha256 содержимого, Last-Modified — время загрузки файла.
+func writeImage(w http.ResponseWriter, r *http.Request, img entity.Image) {
+	cacheControl := imageCacheControl
+	if v := r.URL.Query().Get("v"); v != "" && v == imageVersion(img) {
+		cacheControl = immutableCacheControl
+	}
+	w.Header().Set("Cache-Control", cacheControl)
+	if img.Sha256 != "" {
+		w.Header().Set("ETag", `"`+img.Sha256+`"`)
+	}
+	w.Header().Set("Content-Type", img.MimeType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if img.Filename != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": img.Filename}))
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		// на изменение отвечаем новым файлом целиком, без условий и диапазонов
+		w.Header().Set("Content-Length", strconv.Itoa(len(img.Image)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(img.Image)
 		return
 	}
-	res := mapper.ImgEntityToDTO(img)
-	w.WriteHeader(http.StatusOK)
-	w.Write(res.Image)
+	http.ServeContent(w, r, "", img.UpdatedAt, bytes.NewReader(img.Image))
+}
+
+// imageVersion — хэш файла, который указывается в ?v=. У варианта это хэш
+// исходника: вариант однозначно получается из него и параметров запроса.
+func imageVersion(img entity.Image) string {
+	if img.SourceSha256 != "" {
+		return img.SourceSha256
+	}
+	return img.Sha256
+}
+
+func writeImageError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	message := "internal server error"
+	switch {
+	case errors.Is(err, apperr.ErrImageNotFound):
+		code, message = http.StatusNotFound, "image not found"
+	case errors.Is(err, apperr.ErrProductNotFound):
+		code, message = http.StatusNotFound, "product not found"
+	case errors.Is(err, apperr.ErrImageUnsupported):
+		code, message = http.StatusUnsupportedMediaType, "image must be JPEG, PNG, WebP or GIF"
+	case errors.Is(err, apperr.ErrImageOrderMismatch):
+		code, message = http.StatusBadRequest, apperr.ErrImageOrderMismatch.Error()
+	case errors.Is(err, apperr.ErrImageVariantInvalid):
+		code, message = http.StatusBadRequest, variantMessage
+	case errors.Is(err, apperr.ErrImageTooLarge):
+		code, message = http.StatusRequestEntityTooLarge, apperr.ErrImageTooLarge.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(dto.ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
 }
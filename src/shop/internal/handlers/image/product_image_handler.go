@@ -0,0 +1,143 @@
+package image
+
+import (
+	"backend2/internal/dto"
+	"backend2/internal/mapper"
+	"encoding/json"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// GetProductImages godoc
+// @Summary      Галерея товара
+// @Description  Изображения по порядку, без содержимого файлов.
+// @Tags         images
+// @Produce      json
+// @Param        id   path     string  true  "ID продукта"
+// @Success      200  {object} dto.ProductImagesResponseDTO
+// @Failure      400  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /products/{id}/images [get]
+func (i *ImageHandler) GetProductImages(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	images, err := i.img.GetProductImages(r.Context(), id)
+	if err != nil {
+		writeImageError(w, err)
+		return
+	}
+
+	res := mapper.ProductImagesEntityToDTO(images)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// ReorderProductImages godoc
+// @Summary      Изменить порядок галереи товара
+// @Tags         images
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id     path     string                            true  "ID продукта"
+// @Param        order  body     dto.ProductImagesOrderRequestDTO  true  "Все изображения галереи в новом порядке"
+// @Success      200    {object} dto.ProductImagesResponseDTO
+// @Failure      400    {object} dto.ErrorResponse
+// @Failure      404    {object} dto.ErrorResponse
+// @Failure      500    {object} dto.ErrorResponse
+// @Router       /products/{id}/images/order [put]
+func (i *ImageHandler) ReorderProductImages(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var request dto.ProductImagesOrderRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	validate := validator.New()
+	if err := validate.Struct(request); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, err.(validator.ValidationErrors).Error())
+		return
+	}
+
+	images, err := i.img.ReorderProductImages(r.Context(), id, request.ImageIds)
+	if err != nil {
+		writeImageError(w, err)
+		return
+	}
+
+	res := mapper.ProductImagesEntityToDTO(images)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// SetPrimaryImage godoc
+// @Summary      Сделать изображение основным
+// @Description  Основное изображение отдаётся по /products/{id}/image и попадает в image_id товара.
+// @Tags         images
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id        path     string  true  "ID продукта"
+// @Param        image_id  path     string  true  "ID изображения"
+// @Success      200  {object} dto.ProductImagesResponseDTO
+// @Failure      400  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /products/{id}/images/{image_id}/primary [put]
+func (i *ImageHandler) SetPrimaryImage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	if vars["id"] == "" || vars["image_id"] == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	images, err := i.img.SetPrimaryImage(r.Context(), vars["id"], vars["image_id"])
+	if err != nil {
+		writeImageError(w, err)
+		return
+	}
+
+	res := mapper.ProductImagesEntityToDTO(images)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// DetachProductImage godoc
+// @Summary      Убрать изображение из галереи товара
+// @Description  Изображение, которое больше не входит ни в одну галерею, удаляется. Если оно было основным, основным становится следующее.
+// @Tags         images
+// @Security     BearerAuth
+// @Param        id        path  string  true  "ID продукта"
+// @Param        image_id  path  string  true  "ID изображения"
+// @Success      204
+// @Failure      400  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.ErrorResponse
+// @Router       /products/{id}/images/{image_id} [delete]
+func (i *ImageHandler) DetachProductImage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	if vars["id"] == "" || vars["image_id"] == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	err := i.img.DetachProductImage(r.Context(), vars["id"], vars["image_id"])
+	if err != nil {
+		writeImageError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,283 @@
+package order
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/dto"
+	"backend2/internal/entity"
+	"backend2/internal/mapper"
+	"backend2/internal/middleware"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+type Order interface {
+	CreateOrder(ctx context.Context, order entity.Order, actor string) (entity.Order, error)
+	GetOrderById(ctx context.Context, id string) (entity.Order, error)
+	GetClientOrders(ctx context.Context, clientId string) ([]entity.Order, error)
+	UpdateOrderStatus(ctx context.Context, id, status, actor string) (entity.Order, error)
+}
+
+type OrderHandler struct {
+	order Order
+}
+
+func NewOrderHandler(order Order) *OrderHandler {
+	return &OrderHandler{order: order}
+}
+
+// CreateOrder godoc
+// @Summary      Оформить заказ
+// @Description  Проверяет клиента и остатки, списывает товар со склада и фиксирует цены и адреса. Без shipping_address_id и billing_address_id берутся адреса клиента по умолчанию.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        order  body     dto.OrderCreateRequestDTO  true  "Создаваемый заказ"
+// @Success      201    {object} dto.OrderResponseDTO
+// @Failure      400    {object} dto.Error400
+// @Failure      404    {object} dto.Error404 "client, product or address not found"
+// @Failure      409    {object} dto.ErrorResponse "insufficient stock"
+// @Failure      500    {object} dto.Error500
+// @Router       /order [post]
+func (o *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var request dto.OrderCreateRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid JSON",
+		})
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.(validator.ValidationErrors).Error(),
+		})
+		return
+	}
+
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+	order, err := o.order.CreateOrder(r.Context(), mapper.OrderCreateRequestToEntity(request), principal.Actor())
+	if err != nil {
+		switch {
+		case errors.Is(err, apperr.ErrClientNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: "client not found",
+			})
+		case errors.Is(err, apperr.ErrProductNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: "product not found",
+			})
+		case errors.Is(err, apperr.ErrAddressNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: "address not found",
+			})
+		case errors.Is(err, apperr.ErrInsufficientStock):
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusConflict,
+				Message: "insufficient stock",
+			})
+		case errors.Is(err, apperr.ErrOrderEmpty):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "order has no items",
+			})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "internal server error",
+			})
+		}
+		return
+	}
+
+	res := mapper.OrderEntityToDTO(order)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(res)
+}
+
+// GetOrderById godoc
+// @Summary      Получить заказ по ID
+// @Tags         orders
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path     string  true  "ID заказа"
+// @Success      200  {object} dto.OrderResponseDTO
+// @Failure      400  {object} dto.Error400
+// @Failure      403  {object} dto.ErrorResponse
+// @Failure      404  {object} dto.Error404
+// @Failure      500  {object} dto.Error500
+// @Router       /order/{id} [get]
+func (o *OrderHandler) GetOrderById(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	order, err := o.order.GetOrderById(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, apperr.ErrOrderNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: "order not found",
+			})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "internal server error",
+		})
+		return
+	}
+
+	res := mapper.OrderEntityToDTO(order)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// GetClientOrders godoc
+// @Summary      Получить заказы клиента
+// @Tags         orders
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path     string  true  "ID клиента"
+// @Success      200  {object} dto.OrdersResponseDTO
+// @Failure      400  {object} dto.Error400
+// @Failure      403  {object} dto.ErrorResponse
+// @Failure      500  {object} dto.Error500
+// @Router       /client/{id}/orders [get]
+func (o *OrderHandler) GetClientOrders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	orders, err := o.order.GetClientOrders(r.Context(), id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "internal server error",
+		})
+		return
+	}
+
+	res := mapper.OrdersEntityToDTO(orders)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// UpdateOrderStatus godoc
+// @Summary      Изменить статус заказа
+// @Description  new -> paid -> shipped -> delivered; new и paid можно отменить, при отмене товар возвращается на склад.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path     string                           true  "ID заказа"
+// @Param        status  body     dto.OrderStatusUpdateRequestDTO  true  "Новый статус"
+// @Success      200     {object} dto.OrderResponseDTO
+// @Failure      400     {object} dto.Error400
+// @Failure      404     {object} dto.Error404
+// @Failure      409     {object} dto.ErrorResponse "transition not allowed"
+// @Failure      500     {object} dto.Error500
+// @Router       /order/{id} [patch]
+func (o *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	var request dto.OrderStatusUpdateRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid JSON",
+		})
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.(validator.ValidationErrors).Error(),
+		})
+		return
+	}
+
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+	order, err := o.order.UpdateOrderStatus(r.Context(), id, request.Status, principal.Actor())
+	if err != nil {
+		switch {
+		case errors.Is(err, apperr.ErrOrderNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: "order not found",
+			})
+		case errors.Is(err, apperr.ErrOrderStatus), errors.Is(err, apperr.ErrOrderStatusConflict):
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusConflict,
+				Message: err.Error(),
+			})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "internal server error",
+			})
+		}
+		return
+	}
+
+	res := mapper.OrderEntityToDTO(order)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
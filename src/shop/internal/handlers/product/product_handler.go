@@ -4,7 +4,10 @@ import (
 	"backend2/internal/apperr"
 	"backend2/internal/dto"
 	"backend2/internal/entity"
+	"backend2/internal/handlers"
 	"backend2/internal/mapper"
+	"backend2/internal/middleware"
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/go-playground/validator/v10"
@@ -12,15 +15,29 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type Product interface {
-	CreateProduct(product entity.Product) (entity.Product, error)
-	GetProductById(id string) (entity.Product, error)
-	ReduceProduct(id string, count int) (entity.Product, error)
-	GetProducts() ([]entity.Product, error)
-	DeleteProduct(id string) error
+	CreateProduct(ctx context.Context, product entity.Product, actor string) (entity.Product, error)
+	GetProductById(ctx context.Context, id string) (entity.Product, error)
+	ReduceProduct(ctx context.Context, id string, count int, reason, actor string) (entity.Product, error)
+	GetProducts(ctx context.Context, filter entity.ProductFilter, cursor string) (entity.ProductPage, error)
+	GetSupplierProducts(ctx context.Context, supplierId string, filter entity.ProductFilter, cursor string) (entity.ProductPage, error)
+	DeleteProduct(ctx context.Context, id string, opts entity.DeleteOptions, actor string) error
+	RestoreProduct(ctx context.Context, id string) (entity.Product, error)
+	GetMovements(ctx context.Context, productId string, from, to time.Time) ([]entity.StockMovement, error)
+	SearchProducts(ctx context.Context, q string, limit, offset int) (entity.ProductSearchPage, error)
+	SuggestProducts(ctx context.Context, prefix string, limit int) ([]string, error)
 }
+
+// причины, с которыми остаток можно уменьшить вручную
+var reduceReasons = map[string]bool{
+	entity.StockReasonAdjustment: true,
+	entity.StockReasonSale:       true,
+	entity.StockReasonWriteOff:   true,
+}
+
 type ProductHandler struct {
 	product Product
 }
@@ -64,7 +81,8 @@ func (p *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	productEntity := mapper.ProductDTOToEntity(product)
-	productEntity, err = p.product.CreateProduct(productEntity)
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+	productEntity, err = p.product.CreateProduct(r.Context(), productEntity, principal.Actor())
 	log.Println(productEntity)
 	if err != nil {
 		if errors.Is(err, apperr.ErrSupplierNotFound) {
@@ -110,7 +128,7 @@ func (p *ProductHandler) GetProductById(w http.ResponseWriter, r *http.Request)
 		})
 		return
 	}
-	product, err := p.product.GetProductById(id)
+	product, err := p.product.GetProductById(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, apperr.ErrProductNotFound) {
 			w.WriteHeader(http.StatusNotFound)
@@ -138,9 +156,11 @@ func (p *ProductHandler) GetProductById(w http.ResponseWriter, r *http.Request)
 // @Produce      json
 // @Param        id     path     string  true  "ID товара"
 // @Param        count  query    int     true  "Количество для вычитания"
+// @Param        reason query    string  false "Причина списания: adjustment, sale, write-off" default(adjustment)
 // @Success      200    {object} dto.ProductResponse
 // @Failure      400    {object} dto.Error400
 // @Failure      404    {object} dto.Error404
+// @Failure      409    {object} dto.ErrorResponse "insufficient stock"
 // @Failure      500    {object} dto.Error500
 // @Router       /product/{id} [patch]
 func (p *ProductHandler) ReduceProduct(w http.ResponseWriter, r *http.Request) {
@@ -156,23 +176,29 @@ func (p *ProductHandler) ReduceProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	count, err := strconv.Atoi(r.URL.Query().Get("count"))
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	if err != nil || count <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: "internal server error",
+			Code:    http.StatusBadRequest,
+			Message: "invalid count",
 		})
 		return
 	}
-	if count < 0 {
+	reason := r.URL.Query().Get("reason")
+	if reason == "" {
+		reason = entity.StockReasonAdjustment
+	}
+	if !reduceReasons[reason] {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(dto.ErrorResponse{
 			Code:    http.StatusBadRequest,
-			Message: "invalid count",
+			Message: "invalid reason",
 		})
 		return
 	}
-	product, err := p.product.ReduceProduct(id, count)
+
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+	product, err := p.product.ReduceProduct(r.Context(), id, count, reason, principal.Actor())
 	if err != nil {
 		if errors.Is(err, apperr.ErrProductNotFound) {
 			w.WriteHeader(http.StatusNotFound)
@@ -180,37 +206,82 @@ func (p *ProductHandler) ReduceProduct(w http.ResponseWriter, r *http.Request) {
 				Code:    http.StatusNotFound,
 				Message: "product not found",
 			})
+			return
+		}
+		if errors.Is(err, apperr.ErrInsufficientStock) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusConflict,
+				Message: "insufficient stock",
+			})
+			return
 		}
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(dto.ErrorResponse{
 			Code:    http.StatusInternalServerError,
 			Message: "internal server error",
 		})
+		return
 	}
 	res := mapper.ProductEntityToDTO(product)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(res)
 }
 
+const (
+	defaultProductsLimit = 20
+	maxProductsLimit     = 100
+	defaultSuggestLimit  = 10
+	maxSuggestLimit      = 20
+)
+
+var productSorts = map[string]bool{
+	entity.ProductSortName:       true,
+	entity.ProductSortPrice:      true,
+	entity.ProductSortStock:      true,
+	entity.ProductSortLastUpdate: true,
+}
+
 // GetProducts   godoc
 // @Summary      Получить список товаров
+// @Description  Keyset-пагинация: для следующей страницы передайте next_cursor из ответа с той же сортировкой. Для перехода на произвольную страницу можно использовать offset вместо cursor.
 // @Tags         products
 // @Produce      json
-// @Success      200  {array}  dto.ProductResponse
-// @Success      400  {object}  dto.Error400
-// @Success      404  {object}  dto.ProductsResponse
+// @Param        limit        query    int     false  "Размер страницы (1-100)" default(20)
+// @Param        cursor       query    string  false  "next_cursor предыдущей страницы"
+// @Param        offset       query    int     false  "Смещение, если листать без курсора" default(0)
+// @Param        sort         query    string  false  "Поле сортировки: name, price, stock, last_update_date" default(name)
+// @Param        order        query    string  false  "Направление: asc или desc" default(asc)
+// @Param        category     query    string  false  "Категория"
+// @Param        supplier_id  query    string  false  "ID поставщика"
+// @Param        min_price    query    number  false  "Минимальная цена"
+// @Param        max_price    query    number  false  "Максимальная цена"
+// @Param        in_stock     query    bool    false  "Только товары в наличии"
+// @Success      200  {object} dto.ProductsPageResponse
+// @Failure      400  {object} dto.Error400
 // @Failure      500  {object} dto.Error500
 // @Router       /products [get]
 func (p *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Cache-Control", "public, max-age=60")
-	products, err := p.product.GetProducts()
+
+	filter, err := parseProductFilter(r)
 	if err != nil {
-		if errors.Is(err, apperr.ErrProductNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(dto.ProductsNotFound{
-				Products: make([]entity.Product, 0),
-				Message:  "not found",
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	page, err := p.product.GetProducts(r.Context(), filter, r.URL.Query().Get("cursor"))
+	if err != nil {
+		if errors.Is(err, apperr.ErrInvalidCursor) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid cursor",
 			})
 			return
 		}
@@ -222,18 +293,261 @@ func (p *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
-	res := mapper.ProductsEntityToDTOs(products)
+	res := mapper.ProductPageEntityToDTO(page)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(res)
 }
 
+// GetSupplierProducts godoc
+// @Summary      Товары поставщика
+// @Description  Пагинация, сортировка и фильтры как у списка товаров; supplier_id берётся из пути.
+// @Tags         suppliers
+// @Produce      json
+// @Param        id        path     string  true   "ID поставщика"
+// @Param        limit     query    int     false  "Размер страницы (1-100)" default(20)
+// @Param        cursor    query    string  false  "next_cursor предыдущей страницы"
+// @Param        offset    query    int     false  "Смещение, если листать без курсора" default(0)
+// @Param        sort      query    string  false  "Поле сортировки: name, price, stock, last_update_date" default(name)
+// @Param        order     query    string  false  "Направление: asc или desc" default(asc)
+// @Param        category  query    string  false  "Категория"
+// @Param        in_stock  query    bool    false  "Только товары в наличии"
+// @Success      200  {object} dto.ProductsPageResponse
+// @Failure      400  {object} dto.Error400
+// @Failure      404  {object} dto.Error404 "supplier not found"
+// @Failure      500  {object} dto.Error500
+// @Router       /supplier/{id}/products [get]
+func (p *ProductHandler) GetSupplierProducts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=60")
+
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	filter, err := parseProductFilter(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	page, err := p.product.GetSupplierProducts(r.Context(), id, filter, r.URL.Query().Get("cursor"))
+	if err != nil {
+		switch {
+		case errors.Is(err, apperr.ErrSupplierNotFound):
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: "supplier not found",
+			})
+		case errors.Is(err, apperr.ErrInvalidCursor):
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid cursor",
+			})
+		default:
+			log.Println(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "internal server error",
+			})
+		}
+		return
+	}
+	res := mapper.ProductPageEntityToDTO(page)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+func parseProductFilter(r *http.Request) (entity.ProductFilter, error) {
+	q := r.URL.Query()
+	filter := entity.ProductFilter{
+		Category:   q.Get("category"),
+		SupplierId: q.Get("supplier_id"),
+		Sort:       entity.ProductSortName,
+		Limit:      defaultProductsLimit,
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxProductsLimit {
+			return entity.ProductFilter{}, errors.New("invalid limit")
+		}
+		filter.Limit = limit
+	}
+	if v := q.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return entity.ProductFilter{}, errors.New("invalid offset")
+		}
+		filter.Offset = offset
+	}
+	if v := q.Get("sort"); v != "" {
+		if !productSorts[v] {
+			return entity.ProductFilter{}, errors.New("invalid sort")
+		}
+		filter.Sort = v
+	}
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return entity.ProductFilter{}, errors.New("invalid order")
+	}
+	if v := q.Get("min_price"); v != "" {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return entity.ProductFilter{}, errors.New("invalid min_price")
+		}
+		filter.MinPrice = &price
+	}
+	if v := q.Get("max_price"); v != "" {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return entity.ProductFilter{}, errors.New("invalid max_price")
+		}
+		filter.MaxPrice = &price
+	}
+	if v := q.Get("in_stock"); v != "" {
+		inStock, err := strconv.ParseBool(v)
+		if err != nil {
+			return entity.ProductFilter{}, errors.New("invalid in_stock")
+		}
+		filter.InStock = inStock
+	}
+	return filter, nil
+}
+
+// SearchProducts godoc
+// @Summary      Полнотекстовый поиск товаров
+// @Description  Ищет по названию и категории, терпит опечатки в названии. highlight — безопасный HTML: текст товара экранирован, совпадения обёрнуты в <mark>.
+// @Tags         products
+// @Produce      json
+// @Param        q       query    string  true   "Поисковый запрос"
+// @Param        limit   query    int     false  "Размер страницы (1-100)" default(20)
+// @Param        offset  query    int     false  "Смещение" default(0)
+// @Success      200  {object} dto.ProductSearchResponse
+// @Failure      400  {object} dto.Error400
+// @Failure      500  {object} dto.Error500
+// @Router       /products/search [get]
+func (p *ProductHandler) SearchProducts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	q := r.URL.Query()
+
+	limit, err := queryInt(q.Get("limit"), defaultProductsLimit)
+	if err != nil || limit < 1 || limit > maxProductsLimit {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid limit",
+		})
+		return
+	}
+	offset, err := queryInt(q.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid offset",
+		})
+		return
+	}
+
+	page, err := p.product.SearchProducts(r.Context(), q.Get("q"), limit, offset)
+	if err != nil {
+		if errors.Is(err, apperr.ErrEmptySearch) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "query parameter q is required",
+			})
+			return
+		}
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "internal server error",
+		})
+		return
+	}
+	res := mapper.ProductSearchPageEntityToDTO(page, limit, offset)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// SuggestProducts godoc
+// @Summary      Подсказки для строки поиска
+// @Description  Названия товаров, начинающиеся с q.
+// @Tags         products
+// @Produce      json
+// @Param        q      query    string  true   "Начало названия"
+// @Param        limit  query    int     false  "Число подсказок (1-20)" default(10)
+// @Success      200  {object} dto.ProductSuggestResponse
+// @Failure      400  {object} dto.Error400
+// @Failure      500  {object} dto.Error500
+// @Router       /products/suggest [get]
+func (p *ProductHandler) SuggestProducts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	q := r.URL.Query()
+
+	limit, err := queryInt(q.Get("limit"), defaultSuggestLimit)
+	if err != nil || limit < 1 || limit > maxSuggestLimit {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid limit",
+		})
+		return
+	}
+
+	names, err := p.product.SuggestProducts(r.Context(), q.Get("q"), limit)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "internal server error",
+		})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.ProductSuggestResponse{Suggestions: names})
+}
+
+// queryInt разбирает числовой query-параметр, пустое значение заменяется на def.
+func queryInt(v string, def int) (int, error) {
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
+
 // DeleteProduct godoc
 // @Summary      Удалить товар
+// @Description  По умолчанию товар помечается удалённым и может быть восстановлен. hard=true удаляет его вместе с картинкой, журнал остатков остаётся; если товар есть в заказах или закупках, возвращается 409 со списком. cascade=true (только admin) оставляет позиции отменённых заказов без ссылки на товар и убирает его из ещё не принятых закупок; если товар есть в неотменённом заказе или в принятой закупке, удаление отклоняется с 409.
 // @Tags         products
-// @Param        id   path  string  true  "ID товара"
+// @Param        id       path   string  true   "ID товара"
+// @Param        hard     query  bool    false  "Удалить физически"
+// @Param        cascade  query  bool    false  "Удалить физически, отвязав отменённые заказы и непринятые закупки"
 // @Success      200
 // @Failure      400  {object} dto.Error400
+// @Failure      403  {object} dto.ErrorResponse "cascade requires admin"
 // @Failure      404  {object} dto.Error404
+// @Failure      409  {object} dto.Error409Dependents "product is in orders or purchase orders"
 // @Failure      500  {object} dto.Error500
 // @Router       /product/{id} [delete]
 func (p *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
@@ -247,7 +561,28 @@ func (p *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
-	err := p.product.DeleteProduct(id)
+
+	opts, err := handlers.ParseDeleteOptions(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+	if opts.Cascade && !principal.HasAnyRole(entity.RoleAdmin) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "forbidden: cascade delete requires admin",
+		})
+		return
+	}
+
+	err = p.product.DeleteProduct(r.Context(), id, opts, principal.Actor())
 	if err != nil {
 		if errors.Is(err, apperr.ErrProductNotFound) {
 			w.WriteHeader(http.StatusNotFound)
@@ -257,11 +592,137 @@ func (p *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
+		var dependents *apperr.DependentsError
+		if errors.As(err, &dependents) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(mapper.DependentsErrorToDTO(dependents))
+			return
+		}
+		log.Println(err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(dto.ErrorResponse{
 			Code:    http.StatusInternalServerError,
 			Message: "internal server error",
 		})
+		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
+
+// RestoreProduct godoc
+// @Summary      Восстановить удалённый товар
+// @Tags         products
+// @Produce      json
+// @Param        id   path  string  true  "ID товара"
+// @Success      200  {object} dto.ProductResponse
+// @Failure      400  {object} dto.Error400
+// @Failure      404  {object} dto.Error404
+// @Failure      500  {object} dto.Error500
+// @Router       /product/{id}/restore [post]
+func (p *ProductHandler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid ID",
+		})
+		return
+	}
+
+	product, err := p.product.RestoreProduct(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, apperr.ErrProductNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: "product not found",
+			})
+			return
+		}
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "internal server error",
+		})
+		return
+	}
+	res := mapper.ProductEntityToDTO(product)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// GetMovements godoc
+// @Summary      История движения остатка товара
+// @Tags         products
+// @Produce      json
+// @Param        id    path     string  true   "ID товара"
+// @Param        from  query    string  false  "Начало периода (RFC3339)"
+// @Param        to    query    string  false  "Конец периода (RFC3339), не включая"
+// @Success      200   {object} dto.StockMovementsResponse
+// @Failure      400   {object} dto.Error400
+// @Failure      404   {object} dto.Error404
+// @Failure      500   {object} dto.Error500
+// @Security     BearerAuth
+// @Router       /product/{id}/movements [get]
+func (p *ProductHandler) GetMovements(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid ID",
+		})
+		return
+	}
+
+	var from, to time.Time
+	var err error
+	if v := r.URL.Query().Get("from"); v != "" {
+		from, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid from",
+			})
+			return
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		to, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid to",
+			})
+			return
+		}
+	}
+
+	movements, err := p.product.GetMovements(r.Context(), id, from, to)
+	if err != nil {
+		if errors.Is(err, apperr.ErrProductNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: "product not found",
+			})
+			return
+		}
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "internal server error",
+		})
+		return
+	}
+	res := mapper.StockMovementsEntityToDTO(movements)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,44 @@
+package product
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// reduceStub отвечает на ReduceProduct заданной ошибкой; остальные методы не нужны.
+type reduceStub struct {
+	Product
+	err error
+}
+
+func (s reduceStub) ReduceProduct(ctx context.Context, id string, count int, reason, actor string) (entity.Product, error) {
+	return entity.Product{}, s.err
+}
+
+func TestReduceProductStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, http.StatusOK},
+		{fmt.Errorf("reduce: %w", apperr.ErrInsufficientStock), http.StatusConflict},
+		{apperr.ErrProductNotFound, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPatch, "/api/v1/product/1?count=3", nil)
+		r = mux.SetURLVars(r, map[string]string{"id": "1"})
+		w := httptest.NewRecorder()
+
+		NewProductHandler(reduceStub{err: tt.err}).ReduceProduct(w, r)
+		if w.Code != tt.want {
+			t.Errorf("error %v: status %d, want %d", tt.err, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package purchaseorder
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/dto"
+	"backend2/internal/entity"
+	"backend2/internal/mapper"
+	"backend2/internal/middleware"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+)
+
+type PurchaseOrder interface {
+	CreatePurchaseOrder(ctx context.Context, order entity.PurchaseOrder) (entity.PurchaseOrder, error)
+	GetPurchaseOrderById(ctx context.Context, id string) (entity.PurchaseOrder, error)
+	GetOpenPurchaseOrders(ctx context.Context, supplierId string) ([]entity.PurchaseOrder, error)
+	ReceivePurchaseOrder(ctx context.Context, id string, items []entity.PurchaseOrderItem, actor string) (entity.PurchaseOrder, error)
+	CancelPurchaseOrder(ctx context.Context, id string) (entity.PurchaseOrder, error)
+}
+
+type PurchaseOrderHandler struct {
+	purchase PurchaseOrder
+}
+
+func NewPurchaseOrderHandler(purchase PurchaseOrder) *PurchaseOrderHandler {
+	return &PurchaseOrderHandler{purchase: purchase}
+}
+
+// CreatePurchaseOrder godoc
+// @Summary      Оформить закупку у поставщика
+// @Tags         purchase-orders
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        order  body     dto.PurchaseOrderCreateRequestDTO  true  "Создаваемая закупка"
+// @Success      201    {object} dto.PurchaseOrderResponseDTO
+// @Failure      400    {object} dto.Error400
+// @Failure      404    {object} dto.Error404 "supplier or product not found"
+// @Failure      500    {object} dto.Error500
+// @Router       /purchase-order [post]
+func (p *PurchaseOrderHandler) CreatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var request dto.PurchaseOrderCreateRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid JSON",
+		})
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.(validator.ValidationErrors).Error(),
+		})
+		return
+	}
+
+	order, err := p.purchase.CreatePurchaseOrder(r.Context(), mapper.PurchaseOrderCreateRequestToEntity(request))
+	if err != nil {
+		writePurchaseOrderError(w, err)
+		return
+	}
+
+	res := mapper.PurchaseOrderEntityToDTO(order)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(res)
+}
+
+// GetPurchaseOrderById godoc
+// @Summary      Получить закупку по ID
+// @Tags         purchase-orders
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path     string  true  "ID закупки"
+// @Success      200  {object} dto.PurchaseOrderResponseDTO
+// @Failure      400  {object} dto.Error400
+// @Failure      404  {object} dto.Error404
+// @Failure      500  {object} dto.Error500
+// @Router       /purchase-order/{id} [get]
+func (p *PurchaseOrderHandler) GetPurchaseOrderById(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	order, err := p.purchase.GetPurchaseOrderById(r.Context(), id)
+	if err != nil {
+		writePurchaseOrderError(w, err)
+		return
+	}
+
+	res := mapper.PurchaseOrderEntityToDTO(order)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// GetSupplierPurchaseOrders godoc
+// @Summary      Открытые закупки поставщика
+// @Description  Закупки в статусах open и partially_received.
+// @Tags         purchase-orders
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path     string  true  "ID поставщика"
+// @Success      200  {object} dto.PurchaseOrdersResponseDTO
+// @Failure      400  {object} dto.Error400
+// @Failure      404  {object} dto.Error404
+// @Failure      500  {object} dto.Error500
+// @Router       /supplier/{id}/purchase-orders [get]
+func (p *PurchaseOrderHandler) GetSupplierPurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	orders, err := p.purchase.GetOpenPurchaseOrders(r.Context(), id)
+	if err != nil {
+		writePurchaseOrderError(w, err)
+		return
+	}
+
+	res := mapper.PurchaseOrdersEntityToDTO(orders)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// ReceivePurchaseOrder godoc
+// @Summary      Принять товар по закупке
+// @Description  Увеличивает остатки и дату последней закупки. Без тела или с пустым items принимается всё, что ещё не пришло.
+// @Tags         purchase-orders
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id     path     string                              true   "ID закупки"
+// @Param        items  body     dto.PurchaseOrderReceiveRequestDTO  false  "Принятое количество по товарам"
+// @Success      200    {object} dto.PurchaseOrderResponseDTO
+// @Failure      400    {object} dto.Error400
+// @Failure      404    {object} dto.Error404
+// @Failure      409    {object} dto.ErrorResponse "purchase order closed or over-received"
+// @Failure      500    {object} dto.Error500
+// @Router       /purchase-order/{id}/receive [post]
+func (p *PurchaseOrderHandler) ReceivePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	var request dto.PurchaseOrderReceiveRequestDTO
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil && !errors.Is(err, io.EOF) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid JSON",
+		})
+		return
+	}
+
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.(validator.ValidationErrors).Error(),
+		})
+		return
+	}
+
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+	order, err := p.purchase.ReceivePurchaseOrder(r.Context(), id, mapper.PurchaseOrderReceiveRequestToEntity(request), principal.Actor())
+	if err != nil {
+		writePurchaseOrderError(w, err)
+		return
+	}
+
+	res := mapper.PurchaseOrderEntityToDTO(order)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// CancelPurchaseOrder godoc
+// @Summary      Отменить закупку
+// @Description  Уже принятый товар остаётся на складе.
+// @Tags         purchase-orders
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path     string  true  "ID закупки"
+// @Success      200  {object} dto.PurchaseOrderResponseDTO
+// @Failure      400  {object} dto.Error400
+// @Failure      404  {object} dto.Error404
+// @Failure      409  {object} dto.ErrorResponse "purchase order closed"
+// @Failure      500  {object} dto.Error500
+// @Router       /purchase-order/{id}/cancel [post]
+func (p *PurchaseOrderHandler) CancelPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	order, err := p.purchase.CancelPurchaseOrder(r.Context(), id)
+	if err != nil {
+		writePurchaseOrderError(w, err)
+		return
+	}
+
+	res := mapper.PurchaseOrderEntityToDTO(order)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+func writePurchaseOrderError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	message := "internal server error"
+	switch {
+	case errors.Is(err, apperr.ErrPurchaseOrderNotFound):
+		code, message = http.StatusNotFound, "purchase order not found"
+	case errors.Is(err, apperr.ErrSupplierNotFound):
+		code, message = http.StatusNotFound, "supplier not found"
+	case errors.Is(err, apperr.ErrProductNotFound):
+		code, message = http.StatusNotFound, "product not found"
+	case errors.Is(err, apperr.ErrPurchaseOrderEmpty),
+		errors.Is(err, apperr.ErrPurchaseOrderSupplierMismatch),
+		errors.Is(err, apperr.ErrPurchaseOrderItemNotFound):
+		code, message = http.StatusBadRequest, err.Error()
+	case errors.Is(err, apperr.ErrPurchaseOrderClosed), errors.Is(err, apperr.ErrPurchaseOrderOverReceive):
+		code, message = http.StatusConflict, err.Error()
+	}
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(dto.ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
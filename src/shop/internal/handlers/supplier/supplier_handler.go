@@ -4,20 +4,31 @@ import (
 	"backend2/internal/apperr"
 	"backend2/internal/dto"
 	"backend2/internal/entity"
+	"backend2/internal/handlers"
 	"backend2/internal/mapper"
+	"backend2/internal/middleware"
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type Supplier interface {
-	CreateSupplier(supplier entity.Supplier) (entity.Supplier, error)
-	GetSupplierById(id string) (entity.Supplier, error)
-	GetAllSuppliers() ([]entity.Supplier, error)
-	UpdateAddressSupplier(supplierId string, supplier entity.Supplier) (entity.Supplier, error)
-	DeleteSupplierById(id string) error
+	CreateSupplier(ctx context.Context, supplier entity.Supplier) (entity.Supplier, error)
+	GetSupplierById(ctx context.Context, id string) (entity.Supplier, error)
+	GetAllSuppliers(ctx context.Context, filter entity.SupplierFilter) (entity.Page[entity.Supplier], error)
+	UpdateSupplier(ctx context.Context, id string, patch entity.SupplierPatch) (entity.Supplier, error)
+	ReplaceSupplier(ctx context.Context, id string, supplier entity.Supplier) (entity.Supplier, error)
+	DeleteSupplierById(ctx context.Context, id string, opts entity.DeleteOptions, actor string) error
+	RestoreSupplier(ctx context.Context, id string) (entity.Supplier, error)
 }
 
 type SupplierHandler struct {
@@ -65,7 +76,7 @@ func (sh *SupplierHandler) CreateSupplier(w http.ResponseWriter, r *http.Request
 	}
 
 	supplierEntity := mapper.SupplierDTOToEntity(supplier)
-	sup, err := sh.supplier.CreateSupplier(supplierEntity)
+	sup, err := sh.supplier.CreateSupplier(r.Context(), supplierEntity)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(dto.ErrorResponse{
@@ -104,7 +115,7 @@ func (sh *SupplierHandler) GetSupplierById(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	supplier, err := sh.supplier.GetSupplierById(id)
+	supplier, err := sh.supplier.GetSupplierById(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, apperr.ErrSupplierNotFound) {
 			w.WriteHeader(http.StatusNotFound)
@@ -131,44 +142,78 @@ func (sh *SupplierHandler) GetSupplierById(w http.ResponseWriter, r *http.Reques
 // @Tags suppliers
 // @Accept       json
 // @Produce      json
-// @Success 200 {object} dto.SuppliersResponse
-// @Failure      400     {object} dto.Error400 "Bad request: invalid JSON or validation failed"
-// @Failure      404      {object} dto.SuppliersNotFound
+// @Param        limit    query  int     false  "Размер страницы, 0 — без ограничения"
+// @Param        offset   query  int     false  "Смещение выборки"
+// @Param        name     query  string  false  "Подстрока названия"
+// @Param        country  query  string  false  "Страна"
+// @Param        city     query  string  false  "Город"
+// @Success 200 {object} dto.SuppliersPageDTO
+// @Failure      400     {object} dto.Error400 "Bad request: invalid limit or offset"
 // @Failure      500     {object} dto.Error500 "internal error"
 // @Router  /suppliers [get]
 func (sh *SupplierHandler) GetAllSuppliers(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Cache-Control", "public, max-age=60")
-	suppliers, err := sh.supplier.GetAllSuppliers()
-	if err != nil {
-		if errors.Is(err, apperr.ErrSupplierNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(dto.SuppliersNotFound{
-				Suppliers: make([]entity.Supplier, 0),
-				Message:   "supplier not found",
+
+	q := r.URL.Query()
+	filter := entity.SupplierFilter{
+		Name:    strings.TrimSpace(q.Get("name")),
+		Country: q.Get("country"),
+		City:    q.Get("city"),
+	}
+	var err error
+	if v := q.Get("limit"); v != "" {
+		filter.Limit, err = strconv.Atoi(v)
+		if err != nil || filter.Limit < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid limit",
+			})
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		filter.Offset, err = strconv.Atoi(v)
+		if err != nil || filter.Offset < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(dto.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid offset",
 			})
 			return
 		}
+	}
+
+	page, err := sh.supplier.GetAllSuppliers(r.Context(), filter)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "internal server error",
+		})
 		return
 	}
 
-	res := mapper.SuppliersEntityToDTO(suppliers)
+	res := mapper.SuppliersPageToDTO(page)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(res)
 }
 
-// UpdateAddress godoc
-// @Summary обновить адрес поставщика по id
+// ReplaceSupplier godoc
+// @Summary полностью обновить поставщика по id
+// @Description Заменяет название, телефон и адрес поставщика.
 // @Tags suppliers
+// @Accept       json
 // @Produce      json
 // @Param       id   path  string  true  "ID поставщика"
-// @Param supplier body dto.SupplierUpdateAddressRequestDTO   true  "Обновляемые поля"
+// @Param supplier body dto.SupplierCreateRequestDTO   true  "Новые данные поставщика"
 // @Success 200 {object} dto.SupplierResponseDTO
 // @Failure      400     {object} dto.Error400 "Bad request: invalid JSON or validation failed"
-// @Failure      404     {object} dto.Error404 "client not found"
+// @Failure      404     {object} dto.Error404 "supplier not found"
 // @Failure      500     {object} dto.Error500 "internal error"
-// @Router  /supplier/{id} [patch]
-func (sh *SupplierHandler) UpdateAddress(w http.ResponseWriter, r *http.Request) {
+// @Router  /supplier/{id} [put]
+func (sh *SupplierHandler) ReplaceSupplier(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	id := mux.Vars(r)["id"]
 	if id == "" {
@@ -179,7 +224,7 @@ func (sh *SupplierHandler) UpdateAddress(w http.ResponseWriter, r *http.Request)
 		})
 		return
 	}
-	var supplier dto.SupplierUpdateAddressRequestDTO
+	var supplier dto.SupplierCreateRequestDTO
 	err := json.NewDecoder(r.Body).Decode(&supplier)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -199,39 +244,150 @@ func (sh *SupplierHandler) UpdateAddress(w http.ResponseWriter, r *http.Request)
 		})
 		return
 	}
-	supplierEntity := mapper.SupplierUpdateDTOToEntity(supplier)
 
-	supplierEntity, err = sh.supplier.UpdateAddressSupplier(id, supplierEntity)
+	supplierEntity, err := sh.supplier.ReplaceSupplier(r.Context(), id, mapper.SupplierDTOToEntity(supplier))
 	if err != nil {
-		if errors.Is(err, apperr.ErrSupplierNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(dto.ErrorResponse{
-				Code:    http.StatusNotFound,
-				Message: "supplier not found",
-			})
-			return
-		}
-		w.WriteHeader(http.StatusInternalServerError)
+		writeSupplierError(w, err)
+		return
+	}
+	res := mapper.SupplierEntityToDTO(supplierEntity)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// UpdateSupplier godoc
+// @Summary частично обновить поставщика по id
+// @Description JSON Merge Patch: меняются только переданные поля, поставщик и адрес обновляются в одной транзакции. null для полей не допускается.
+// @Tags suppliers
+// @Accept       json
+// @Accept       application/merge-patch+json
+// @Produce      json
+// @Param       id   path  string  true  "ID поставщика"
+// @Param supplier body dto.SupplierUpdateRequestDTO   true  "Обновляемые поля"
+// @Success 200 {object} dto.SupplierResponseDTO
+// @Failure      400     {object} dto.Error400 "Bad request: invalid JSON or validation failed"
+// @Failure      404     {object} dto.Error404 "supplier not found"
+// @Failure      415     {object} dto.ErrorResponse "unsupported content type"
+// @Failure      500     {object} dto.Error500 "internal error"
+// @Router  /supplier/{id} [patch]
+func (sh *SupplierHandler) UpdateSupplier(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: "internal server error",
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "" && mediaType != "application/json" && mediaType != mergePatchContentType {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusUnsupportedMediaType,
+			Message: "content type must be application/json or " + mergePatchContentType,
+		})
+		return
+	}
+
+	supplier, err := decodeSupplierPatch(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	validate := validator.New()
+	err = validate.Struct(supplier)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.(validator.ValidationErrors).Error(),
 		})
 		return
 	}
+
+	supplierEntity, err := sh.supplier.UpdateSupplier(r.Context(), id, mapper.SupplierUpdateDTOToEntity(supplier))
+	if err != nil {
+		writeSupplierError(w, err)
+		return
+	}
 	res := mapper.SupplierEntityToDTO(supplierEntity)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(res)
 }
 
+const mergePatchContentType = "application/merge-patch+json"
+
+// decodeSupplierPatch разбирает тело PATCH. null в merge patch означает удаление поля,
+// а удалять поля поставщика нельзя, поэтому null отклоняется, как и неизвестные поля.
+func decodeSupplierPatch(r *http.Request) (dto.SupplierUpdateRequestDTO, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return dto.SupplierUpdateRequestDTO{}, errors.New("invalid JSON")
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return dto.SupplierUpdateRequestDTO{}, errors.New("invalid JSON")
+	}
+	for name, value := range fields {
+		if string(value) == "null" {
+			return dto.SupplierUpdateRequestDTO{}, fmt.Errorf("field %s cannot be null", name)
+		}
+	}
+
+	var supplier dto.SupplierUpdateRequestDTO
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&supplier); err != nil {
+		return dto.SupplierUpdateRequestDTO{}, fmt.Errorf("invalid JSON: %v", err)
+	}
+	return supplier, nil
+}
+
+func writeSupplierError(w http.ResponseWriter, err error) {
+	var dependents *apperr.DependentsError
+	if errors.As(err, &dependents) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(mapper.DependentsErrorToDTO(dependents))
+		return
+	}
+
+	code := http.StatusInternalServerError
+	message := "internal server error"
+	switch {
+	case errors.Is(err, apperr.ErrSupplierNotFound):
+		code, message = http.StatusNotFound, "supplier not found"
+	case errors.Is(err, apperr.ErrAddressNotFound):
+		code, message = http.StatusNotFound, "supplier address not found"
+	}
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(dto.ErrorResponse{
+		Code:    code,
+		Message: message,
+	})
+}
+
 // DeleteSupplierById godoc
 // @Summary удалить поставщика по id
+// @Description По умолчанию поставщик помечается удалённым и может быть восстановлен. hard=true удаляет его вместе с адресом; если есть товары или закупки, возвращается 409 со списком, а cascade=true удаляет и их. Принятые закупки не удаляются: на них тоже отвечает 409.
 // @Tags suppliers
 // @Produce      json
-// @Param       id   path  string  true  "ID поставщика"
+// @Param       id       path   string  true   "ID поставщика"
+// @Param       hard     query  bool    false  "Удалить физически"
+// @Param       cascade  query  bool    false  "Удалить физически вместе с товарами и закупками"
 // @Success 200
-// @Failure      400     {object} dto.Error400 "Bad request: invalid JSON or validation failed"
-// @Failure      404     {object} dto.Error404 "client not found"
+// @Failure      400     {object} dto.Error400 "Bad request: invalid hard or cascade"
+// @Failure      404     {object} dto.Error404 "supplier not found"
+// @Failure      409     {object} dto.Error409Dependents "supplier has products or purchase orders"
 // @Failure      500     {object} dto.Error500 "internal error"
 // @Router  /supplier/{id} [delete]
 func (sh *SupplierHandler) DeleteSupplierById(w http.ResponseWriter, r *http.Request) {
@@ -246,23 +402,53 @@ func (sh *SupplierHandler) DeleteSupplierById(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err := sh.supplier.DeleteSupplierById(id)
+	opts, err := handlers.ParseDeleteOptions(r)
 	if err != nil {
-		if errors.Is(err, apperr.ErrSupplierNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(dto.ErrorResponse{
-				Code:    http.StatusNotFound,
-				Message: "supplier not found",
-			})
-			return
-		}
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(dto.ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: "internal server error",
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
 		})
 		return
 	}
+
+	principal, _ := middleware.PrincipalFromContext(r.Context())
+	err = sh.supplier.DeleteSupplierById(r.Context(), id, opts, principal.Actor())
+	if err != nil {
+		writeSupplierError(w, err)
+		return
+	}
 	w.WriteHeader(http.StatusOK)
+}
 
+// RestoreSupplier godoc
+// @Summary восстановить удалённого поставщика
+// @Tags suppliers
+// @Produce      json
+// @Param       id   path  string  true  "ID поставщика"
+// @Success 200 {object} dto.SupplierResponseDTO
+// @Failure      400     {object} dto.Error400 "Bad request"
+// @Failure      404     {object} dto.Error404 "supplier not found"
+// @Failure      500     {object} dto.Error500 "internal error"
+// @Router  /supplier/{id}/restore [post]
+func (sh *SupplierHandler) RestoreSupplier(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(dto.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid id",
+		})
+		return
+	}
+
+	supplier, err := sh.supplier.RestoreSupplier(r.Context(), id)
+	if err != nil {
+		writeSupplierError(w, err)
+		return
+	}
+	res := mapper.SupplierEntityToDTO(supplier)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
 }
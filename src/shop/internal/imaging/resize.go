@@ -0,0 +1,104 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+)
+
+// Fit — как изображение вписывается в заданные размеры.
+type Fit int
+
+const (
+	// FitContain уменьшает изображение целиком, сохраняя пропорции.
+	FitContain Fit = iota
+	// FitCover заполняет рамку, сохраняя пропорции, и обрезает лишнее по центру.
+	FitCover
+	// FitFill растягивает изображение точно в рамку.
+	FitFill
+)
+
+// jpegQuality — качество JPEG для производных изображений.
+const jpegQuality = 85
+
+// Resize вписывает src в рамку width×height. Нулевая сторона рамки не ограничена.
+// Изображение только уменьшается: рамка больше исходника не увеличивает его.
+// Если opaque, прозрачные области заливаются белым, как нужно для JPEG.
+func Resize(src image.Image, width, height int, fit Fit, opaque bool) image.Image {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if width <= 0 && height <= 0 {
+		width, height = sw, sh
+	}
+	if fit == FitCover && (width <= 0 || height <= 0) {
+		fit = FitContain
+	}
+
+	crop := b
+	var dw, dh int
+	switch fit {
+	case FitFill:
+		dw, dh = min(positive(width, sw), sw), min(positive(height, sh), sh)
+	case FitCover:
+		// наибольший прямоугольник с пропорциями рамки по центру исходника
+		cw, ch := sw, sw*height/width
+		if ch > sh {
+			cw, ch = sh*width/height, sh
+		}
+		cw, ch = max(cw, 1), max(ch, 1)
+		x0, y0 := b.Min.X+(sw-cw)/2, b.Min.Y+(sh-ch)/2
+		crop = image.Rect(x0, y0, x0+cw, y0+ch)
+		dw, dh = min(width, cw), min(height, ch)
+	default:
+		scale := 1.0
+		if width > 0 {
+			scale = min(scale, float64(width)/float64(sw))
+		}
+		if height > 0 {
+			scale = min(scale, float64(height)/float64(sh))
+		}
+		dw, dh = max(int(float64(sw)*scale+0.5), 1), max(int(float64(sh)*scale+0.5), 1)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	op := draw.Src
+	if opaque {
+		draw.Draw(dst, dst.Rect, image.NewUniform(color.White), image.Point{}, draw.Src)
+		op = draw.Over
+	}
+	if dw == crop.Dx() && dh == crop.Dy() {
+		draw.Draw(dst, dst.Rect, src, crop.Min, op)
+	} else {
+		draw.CatmullRom.Scale(dst, dst.Rect, src, crop, op, nil)
+	}
+	return dst
+}
+
+func positive(v, fallback int) int {
+	if v > 0 {
+		return v
+	}
+	return fallback
+}
+
+// Encode пишет m в w в формате с MIME-типом mimeType.
+func Encode(w io.Writer, m image.Image, mimeType string) error {
+	switch mimeType {
+	case "image/jpeg":
+		return jpeg.Encode(w, m, &jpeg.Options{Quality: jpegQuality})
+	case "image/png":
+		enc := png.Encoder{CompressionLevel: png.BestCompression}
+		return enc.Encode(w, m)
+	case "image/gif":
+		return gif.Encode(w, m, nil)
+	case "image/webp":
+		return EncodeWebP(w, m)
+	}
+	return fmt.Errorf("imaging: unsupported format %s", mimeType)
+}
@@ -0,0 +1,50 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestResizeDimensions(t *testing.T) {
+	tests := []struct {
+		name          string
+		srcW, srcH    int
+		width, height int
+		fit           Fit
+		wantW, wantH  int
+	}{
+		{"contain landscape", 400, 200, 100, 100, FitContain, 100, 50},
+		{"contain portrait", 200, 400, 100, 100, FitContain, 50, 100},
+		{"contain width only", 400, 200, 100, 0, FitContain, 100, 50},
+		{"contain height only", 400, 200, 0, 100, FitContain, 200, 100},
+		{"contain no frame", 400, 200, 0, 0, FitContain, 400, 200},
+		{"contain no upscale", 100, 50, 400, 400, FitContain, 100, 50},
+		{"contain keeps one pixel", 1000, 1, 10, 10, FitContain, 10, 1},
+		{"cover square", 400, 200, 100, 100, FitCover, 100, 100},
+		{"cover wide frame", 400, 400, 200, 100, FitCover, 200, 100},
+		{"cover no upscale", 100, 50, 400, 400, FitCover, 50, 50},
+		{"cover one side is contain", 400, 200, 100, 0, FitCover, 100, 50},
+		{"fill", 400, 200, 100, 100, FitFill, 100, 100},
+		{"fill width only", 400, 200, 100, 0, FitFill, 100, 200},
+		{"fill no upscale", 100, 50, 400, 400, FitFill, 100, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewRGBA(image.Rect(0, 0, tt.srcW, tt.srcH))
+			got := Resize(src, tt.width, tt.height, tt.fit, false).Bounds()
+			if got.Dx() != tt.wantW || got.Dy() != tt.wantH {
+				t.Errorf("Resize(%dx%d, %dx%d) = %dx%d, want %dx%d",
+					tt.srcW, tt.srcH, tt.width, tt.height, got.Dx(), got.Dy(), tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestResizeOpaque(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 20, 20))
+	got := Resize(src, 10, 10, FitContain, true)
+	if c := color.RGBAModel.Convert(got.At(5, 5)); c != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Errorf("transparent pixel = %v, want white", c)
+	}
+}
@@ -0,0 +1,420 @@
+package imaging
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+)
+
+// Кодировщик WebP без потерь (VP8L). В стандартной библиотеке и golang.org/x/image
+// есть только декодер WebP, поэтому здесь минимальный кодировщик на чистом Go:
+// преобразования subtract green и predictor, префиксные коды Хаффмана без LZ77 и кэша цветов.
+// Формат описан в https://developers.google.com/speed/webp/docs/webp_lossless_bitstream_specification.
+
+// maxWebPSide — наибольшая сторона изображения, которую допускает заголовок VP8L.
+const maxWebPSide = 1 << 14
+
+const (
+	vp8lSignature     = 0x2f
+	predictor         = 0
+	subtractGreen     = 2
+	predictorBits     = 5 // предсказатель выбирается для квадратов 32×32
+	greenAlphabet     = 256 + 24
+	distanceAlphabet  = 40
+	maxCodeLength     = 15
+	maxCodeLengthCode = 7
+)
+
+// codeLengthCodeOrder — порядок, в котором записываются длины кодов для длин кодов.
+var codeLengthCodeOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// EncodeWebP пишет m в w как WebP без потерь.
+func EncodeWebP(w io.Writer, m image.Image) error {
+	b := m.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width <= 0 || height <= 0 || width > maxWebPSide || height > maxWebPSide {
+		return errors.New("webp: invalid image size")
+	}
+
+	src, ok := m.(*image.NRGBA)
+	if !ok || src.Rect.Min != (image.Point{}) {
+		src = image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(src, src.Rect, m, b.Min, draw.Src)
+	}
+
+	// пиксели после subtract green: зелёный, красный, синий, альфа
+	pixels := make([][4]int, 0, width*height)
+	hasAlpha := false
+	for y := 0; y < height; y++ {
+		row := src.Pix[y*src.Stride : y*src.Stride+width*4]
+		for x := 0; x < len(row); x += 4 {
+			r, g, b, a := int(row[x]), int(row[x+1]), int(row[x+2]), int(row[x+3])
+			pixels = append(pixels, [4]int{g, (r - g) & 0xff, (b - g) & 0xff, a})
+			if a != 0xff {
+				hasAlpha = true
+			}
+		}
+	}
+	residuals, modes := predict(pixels, width, height)
+
+	bw := &bitWriter{}
+	bw.write(vp8lSignature, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	bw.write(boolBit(hasAlpha), 1)
+	bw.write(0, 3) // версия
+
+	// декодер отменяет преобразования в обратном порядке
+	bw.write(1, 1)
+	bw.write(subtractGreen, 2)
+	bw.write(1, 1)
+	bw.write(predictor, 2)
+	bw.write(predictorBits-2, 3)
+	writeEntropyImage(bw, modes, false)
+	bw.write(0, 1) // больше преобразований нет
+
+	writeEntropyImage(bw, residuals, true)
+	payload := bw.bytes()
+
+	pad := len(payload) & 1
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+8+len(payload)+pad))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(payload)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return err
+	}
+	if pad != 0 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+// writeEntropyImage пишет пиксели одной группой префиксных кодов без кэша цветов.
+func writeEntropyImage(bw *bitWriter, pixels [][4]int, main bool) {
+	var hist [4][]int
+	hist[0] = make([]int, greenAlphabet)
+	for i := 1; i < 4; i++ {
+		hist[i] = make([]int, 256)
+	}
+	for _, p := range pixels {
+		for i, v := range p {
+			hist[i][v]++
+		}
+	}
+
+	bw.write(0, 1) // без кэша цветов
+	if main {
+		bw.write(0, 1) // одна группа кодов на всё изображение
+	}
+
+	var codes [4]prefixCode
+	for i := range codes {
+		codes[i] = newPrefixCode(hist[i], maxCodeLength)
+		codes[i].writeTo(bw)
+	}
+	// расстояния не используются, но код для них обязателен
+	newPrefixCode(make([]int, distanceAlphabet), maxCodeLength).writeTo(bw)
+
+	for _, p := range pixels {
+		for i, v := range p {
+			codes[i].writeSymbol(bw, v)
+		}
+	}
+}
+
+// predictorModes — режимы предсказания, из которых выбирается лучший для квадрата:
+// L, T, Average2(L, T), Select и ClampAddSubtractFull.
+var predictorModes = []int{1, 2, 7, 11, 12}
+
+// predict заменяет пиксели остатками предсказания и возвращает их вместе
+// с картой режимов по квадратам. Как и в декодере, первая строка предсказывается
+// слева, первый столбец — сверху, первый пиксель — непрозрачным чёрным.
+func predict(pixels [][4]int, width, height int) ([][4]int, [][4]int) {
+	tilesX := (width + 1<<predictorBits - 1) >> predictorBits
+	tilesY := (height + 1<<predictorBits - 1) >> predictorBits
+	modes := make([][4]int, tilesX*tilesY)
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			best, bestCost := predictorModes[0], -1
+			for _, mode := range predictorModes {
+				cost := 0
+				for y := max(ty<<predictorBits, 1); y < min((ty+1)<<predictorBits, height); y++ {
+					for x := max(tx<<predictorBits, 1); x < min((tx+1)<<predictorBits, width); x++ {
+						i := y*width + x
+						pred := predictPixel(mode, pixels[i-1], pixels[i-width], pixels[i-width-1])
+						for c := range pred {
+							d := (pixels[i][c] - pred[c]) & 0xff
+							cost += min(d, 256-d)
+						}
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+			modes[ty*tilesX+tx] = [4]int{best, 0, 0, 0xff}
+		}
+	}
+
+	residuals := make([][4]int, len(pixels))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			var pred [4]int
+			switch {
+			case x == 0 && y == 0:
+				pred = [4]int{0, 0, 0, 0xff}
+			case y == 0:
+				pred = pixels[i-1]
+			case x == 0:
+				pred = pixels[i-width]
+			default:
+				mode := modes[(y>>predictorBits)*tilesX+x>>predictorBits][0]
+				pred = predictPixel(mode, pixels[i-1], pixels[i-width], pixels[i-width-1])
+			}
+			for c := range pred {
+				residuals[i][c] = (pixels[i][c] - pred[c]) & 0xff
+			}
+		}
+	}
+	return residuals, modes
+}
+
+func predictPixel(mode int, l, t, tl [4]int) [4]int {
+	var p [4]int
+	switch mode {
+	case 1:
+		return l
+	case 2:
+		return t
+	case 7:
+		for c := range p {
+			p[c] = (l[c] + t[c]) / 2
+		}
+	case 11:
+		pl, pt := 0, 0
+		for c := range p {
+			pl += abs(t[c] - tl[c])
+			pt += abs(l[c] - tl[c])
+		}
+		if pl < pt {
+			return l
+		}
+		return t
+	case 12:
+		for c := range p {
+			p[c] = min(max(l[c]+t[c]-tl[c], 0), 255)
+		}
+	}
+	return p
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// prefixCode — канонический код Хаффмана. Если символ один, он кодируется нулём бит.
+type prefixCode struct {
+	lengths []int
+	codes   []uint32 // коды с обратным порядком бит, поток пишется с младшего бита
+	single  bool
+}
+
+func newPrefixCode(counts []int, limit int) prefixCode {
+	lengths := huffmanLengths(counts, limit)
+	used := 0
+	for _, l := range lengths {
+		if l > 0 {
+			used++
+		}
+	}
+	if used == 0 {
+		lengths[0] = 1
+		used = 1
+	}
+	pc := prefixCode{lengths: lengths, codes: make([]uint32, len(lengths)), single: used == 1}
+
+	var blCount [maxCodeLength + 1]uint32
+	for _, l := range lengths {
+		blCount[l]++
+	}
+	blCount[0] = 0
+	var next [maxCodeLength + 2]uint32
+	code := uint32(0)
+	for l := 1; l <= maxCodeLength; l++ {
+		code = (code + blCount[l-1]) << 1
+		next[l] = code
+	}
+	for s, l := range lengths {
+		if l > 0 {
+			pc.codes[s] = reverse(next[l], l)
+			next[l]++
+		}
+	}
+	return pc
+}
+
+func (pc prefixCode) writeSymbol(bw *bitWriter, s int) {
+	if pc.single {
+		return
+	}
+	bw.write(pc.codes[s], uint(pc.lengths[s]))
+}
+
+// writeTo записывает длины кодов в обычной форме: сначала код для длин кодов,
+// затем длина каждого символа литералом 0..15.
+func (pc prefixCode) writeTo(bw *bitWriter) {
+	bw.write(0, 1) // обычный, не простой код
+
+	var counts [19]int
+	for _, l := range pc.lengths {
+		counts[l]++
+	}
+	lengthCode := newPrefixCode(counts[:], maxCodeLengthCode)
+
+	bw.write(uint32(len(codeLengthCodeOrder)-4), 4)
+	for _, s := range codeLengthCodeOrder {
+		bw.write(uint32(lengthCode.lengths[s]), 3)
+	}
+
+	bw.write(0, 1) // длины заданы для всего алфавита
+	for _, l := range pc.lengths {
+		lengthCode.writeSymbol(bw, l)
+	}
+}
+
+// huffmanLengths строит длины кодов не длиннее limit. Если дерево получается
+// глубже, частоты огрубляются, пока оно не уложится.
+func huffmanLengths(counts []int, limit int) []int {
+	scaled := append([]int(nil), counts...)
+	for {
+		lengths := treeDepths(scaled)
+		max := 0
+		for _, l := range lengths {
+			if l > max {
+				max = l
+			}
+		}
+		if max <= limit {
+			return lengths
+		}
+		for i, c := range scaled {
+			if c > 0 {
+				scaled[i] = (c + 1) / 2
+			}
+		}
+	}
+}
+
+type huffNode struct {
+	count       int
+	symbol      int
+	left, right *huffNode
+}
+
+type huffHeap []*huffNode
+
+func (h huffHeap) Len() int { return len(h) }
+func (h huffHeap) Less(i, j int) bool {
+	if h[i].count != h[j].count {
+		return h[i].count < h[j].count
+	}
+	return h[i].symbol < h[j].symbol
+}
+func (h huffHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *huffHeap) Push(x any)   { *h = append(*h, x.(*huffNode)) }
+func (h *huffHeap) Pop() any {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+func treeDepths(counts []int) []int {
+	lengths := make([]int, len(counts))
+	h := huffHeap{}
+	for s, c := range counts {
+		if c > 0 {
+			h = append(h, &huffNode{count: c, symbol: s})
+		}
+	}
+	switch len(h) {
+	case 0:
+		return lengths
+	case 1:
+		lengths[h[0].symbol] = 1
+		return lengths
+	}
+
+	heap.Init(&h)
+	for h.Len() > 1 {
+		a := heap.Pop(&h).(*huffNode)
+		b := heap.Pop(&h).(*huffNode)
+		heap.Push(&h, &huffNode{count: a.count + b.count, symbol: min(a.symbol, b.symbol), left: a, right: b})
+	}
+
+	var walk func(n *huffNode, depth int)
+	walk = func(n *huffNode, depth int) {
+		if n.left == nil {
+			lengths[n.symbol] = depth
+			return
+		}
+		walk(n.left, depth+1)
+		walk(n.right, depth+1)
+	}
+	walk(h[0], 0)
+	return lengths
+}
+
+func reverse(code uint32, n int) uint32 {
+	var r uint32
+	for i := 0; i < n; i++ {
+		r = r<<1 | code&1
+		code >>= 1
+	}
+	return r
+}
+
+func boolBit(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// bitWriter пишет биты начиная с младшего, как того требует VP8L.
+type bitWriter struct {
+	buf  []byte
+	acc  uint64
+	nacc uint
+}
+
+func (bw *bitWriter) write(v uint32, n uint) {
+	bw.acc |= uint64(v) << bw.nacc
+	bw.nacc += n
+	for bw.nacc >= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+		bw.nacc -= 8
+	}
+}
+
+func (bw *bitWriter) bytes() []byte {
+	if bw.nacc > 0 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc, bw.nacc = 0, 0
+	}
+	return bw.buf
+}
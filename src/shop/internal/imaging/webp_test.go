@@ -0,0 +1,69 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeWebPRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	tests := []struct {
+		name          string
+		width, height int
+		pixel         func(x, y int) color.NRGBA
+	}{
+		{"single pixel", 1, 1, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: 10, G: 20, B: 30, A: 255}
+		}},
+		{"solid", 16, 16, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: 200, G: 100, B: 50, A: 255}
+		}},
+		{"gradient with alpha", 37, 19, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: uint8(x * 7), G: uint8(y * 13), B: uint8(x * y), A: uint8(255 - x*3)}
+		}},
+		{"noise", 64, 33, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: uint8(rnd.Intn(256)), G: uint8(rnd.Intn(256)), B: uint8(rnd.Intn(256)), A: uint8(rnd.Intn(256))}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewNRGBA(image.Rect(0, 0, tt.width, tt.height))
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					src.SetNRGBA(x, y, tt.pixel(x, y))
+				}
+			}
+
+			var buf bytes.Buffer
+			if err := EncodeWebP(&buf, src); err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			got, err := webp.Decode(&buf)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+
+			if got.Bounds().Dx() != tt.width || got.Bounds().Dy() != tt.height {
+				t.Fatalf("decoded size %v, want %dx%d", got.Bounds().Size(), tt.width, tt.height)
+			}
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					want := src.NRGBAAt(x, y)
+					have := color.NRGBAModel.Convert(got.At(got.Bounds().Min.X+x, got.Bounds().Min.Y+y)).(color.NRGBA)
+					// у полностью прозрачного пикселя цвет не важен
+					if want.A == 0 && have.A == 0 {
+						continue
+					}
+					if have != want {
+						t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, have, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package mapper
+
+import (
+	"backend2/internal/dto"
+	"backend2/internal/entity"
+)
+
+func ClientAddressRequestToEntity(request dto.ClientAddressRequestDTO) entity.ClientAddress {
+	return entity.ClientAddress{
+		Label:             request.Label,
+		IsDefaultShipping: request.IsDefaultShipping,
+		IsDefaultBilling:  request.IsDefaultBilling,
+		Address: entity.Address{
+			Country: request.Country,
+			City:    request.City,
+			Street:  request.Street,
+		},
+	}
+}
+
+func ClientAddressEntityToDTO(ca entity.ClientAddress) dto.ClientAddressResponseDTO {
+	return dto.ClientAddressResponseDTO{
+		Id:                ca.Id,
+		ClientId:          ca.ClientId,
+		Label:             ca.Label,
+		IsDefaultShipping: ca.IsDefaultShipping,
+		IsDefaultBilling:  ca.IsDefaultBilling,
+		CreatedAt:         ca.CreatedAt,
+		Address: dto.AddressDTO{
+			ID:      ca.Address.ID,
+			Country: ca.Address.Country,
+			City:    ca.Address.City,
+			Street:  ca.Address.Street,
+		},
+	}
+}
+
+func ClientAddressesEntityToDTO(addresses []entity.ClientAddress) dto.ClientAddressesResponseDTO {
+	res := dto.ClientAddressesResponseDTO{
+		Addresses: make([]dto.ClientAddressResponseDTO, 0, len(addresses)),
+	}
+	for _, ca := range addresses {
+		res.Addresses = append(res.Addresses, ClientAddressEntityToDTO(ca))
+	}
+	return res
+}
@@ -0,0 +1,36 @@
+package mapper
+
+import (
+	"backend2/internal/dto"
+	"backend2/internal/entity"
+)
+
+func APIKeyEntityToDTO(key entity.APIKey) dto.APIKeyResponseDTO {
+	return dto.APIKeyResponseDTO{
+		Id:         key.Id,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		CreatedBy:  key.CreatedBy,
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
+
+func APIKeyCreatedToDTO(key entity.APIKey, rawKey string) dto.APIKeyCreatedResponseDTO {
+	return dto.APIKeyCreatedResponseDTO{
+		APIKeyResponseDTO: APIKeyEntityToDTO(key),
+		Key:               rawKey,
+	}
+}
+
+func APIKeysEntityToDTO(keys []entity.APIKey) dto.APIKeysResponseDTO {
+	keysDTO := dto.APIKeysResponseDTO{
+		APIKeys: make([]dto.APIKeyResponseDTO, 0, len(keys)),
+	}
+	for _, key := range keys {
+		keysDTO.APIKeys = append(keysDTO.APIKeys, APIKeyEntityToDTO(key))
+	}
+	return keysDTO
+}
@@ -0,0 +1,27 @@
+package mapper
+
+import (
+	"backend2/internal/dto"
+	"backend2/internal/entity"
+)
+
+func CartEntityToDTO(cart entity.Cart) dto.CartResponseDTO {
+	items := make([]dto.CartLineResponseDTO, 0, len(cart.Lines))
+	for _, line := range cart.Lines {
+		items = append(items, dto.CartLineResponseDTO{
+			ProductId:      line.ProductId,
+			Name:           line.Name,
+			Quantity:       line.Quantity,
+			UnitPrice:      line.UnitPrice,
+			AvailableStock: line.AvailableStock,
+			LineTotal:      line.LineTotal,
+			Problem:        line.Problem,
+		})
+	}
+	return dto.CartResponseDTO{
+		ClientId:    cart.ClientId,
+		Items:       items,
+		Total:       cart.Total,
+		CanCheckout: cart.CanCheckout,
+	}
+}
@@ -41,14 +41,15 @@ func ClientCreateResponse(client entity.Client) dto.ClientResponseDTO {
 	}
 }
 
-func ClientUpdateRequestToEntity(request dto.ClientUpdateRequestDTO) entity.Client {
-	address := entity.Address{
-		Country: request.Country,
-		City:    request.City,
-		Street:  request.Street,
-	}
-	return entity.Client{
-		Address: address,
+func ClientUpdateRequestToEntity(request dto.ClientUpdateRequestDTO) entity.ClientPatch {
+	return entity.ClientPatch{
+		ClientName:    request.ClientName,
+		ClientSurname: request.ClientSurname,
+		BirthDate:     request.BirthDate,
+		Gender:        request.Gender,
+		Country:       request.Country,
+		City:          request.City,
+		Street:        request.Street,
 	}
 }
 
@@ -99,3 +100,12 @@ func GetClientsResponse(clients []entity.Client) dto.ClientsResponseDTO {
 	}
 	return clientsResponse
 }
+
+func ClientsPageToDTO(page entity.Page[entity.Client]) dto.ClientsPageDTO {
+	return dto.ClientsPageDTO{
+		Items:  GetClientsResponse(page.Items).Clients,
+		Total:  page.Total,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	}
+}
@@ -0,0 +1,23 @@
+package mapper
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/dto"
+	"net/http"
+)
+
+func DependentsErrorToDTO(err *apperr.DependentsError) dto.Error409Dependents {
+	dependents := make([]dto.DependentDTO, 0, len(err.Dependents))
+	for _, d := range err.Dependents {
+		dependents = append(dependents, dto.DependentDTO{
+			Kind:  d.Kind,
+			Count: d.Count,
+			Ids:   d.Ids,
+		})
+	}
+	return dto.Error409Dependents{
+		Message:    apperr.ErrHasDependents.Error(),
+		Code:       http.StatusConflict,
+		Dependents: dependents,
+	}
+}
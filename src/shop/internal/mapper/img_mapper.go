@@ -3,18 +3,61 @@ package mapper
 import (
 	"backend2/internal/dto"
 	"backend2/internal/entity"
+	"net/url"
 )
 
+// imageURL — адрес файла изображения с версией содержимого. Новый файл получает
+// новый адрес, поэтому ответ по нему кэшируется навсегда.
+func imageURL(img entity.Image) string {
+	u := "/api/v1/image/" + url.PathEscape(img.Id)
+	if img.Sha256 != "" {
+		u += "?v=" + img.Sha256
+	}
+	return u
+}
+
 func ImgDTOToEntity(dto dto.ImageDTO) entity.Image {
 	return entity.Image{
-		Id:    dto.Id,
-		Image: dto.Image,
+		Id:       dto.Id,
+		Image:    dto.Image,
+		Filename: dto.Filename,
+		MimeType: dto.MimeType,
+		Size:     dto.Size,
+		Width:    dto.Width,
+		Height:   dto.Height,
+		Sha256:   dto.Sha256,
 	}
 }
 
 func ImgEntityToDTO(entity entity.Image) dto.ImageDTO {
 	return dto.ImageDTO{
-		Id:    entity.Id,
-		Image: entity.Image,
+		Id:       entity.Id,
+		Image:    entity.Image,
+		Filename: entity.Filename,
+		MimeType: entity.MimeType,
+		Size:     entity.Size,
+		Width:    entity.Width,
+		Height:   entity.Height,
+		Sha256:   entity.Sha256,
+		Url:      imageURL(entity),
+	}
+}
+
+func ProductImagesEntityToDTO(images []entity.ProductImage) dto.ProductImagesResponseDTO {
+	res := dto.ProductImagesResponseDTO{Images: make([]dto.ProductImageDTO, 0, len(images))}
+	for _, pi := range images {
+		res.Images = append(res.Images, dto.ProductImageDTO{
+			Id:        pi.Image.Id,
+			Filename:  pi.Image.Filename,
+			MimeType:  pi.Image.MimeType,
+			Size:      pi.Image.Size,
+			Width:     pi.Image.Width,
+			Height:    pi.Image.Height,
+			Sha256:    pi.Image.Sha256,
+			Url:       imageURL(pi.Image),
+			Position:  pi.Position,
+			IsPrimary: pi.IsPrimary,
+		})
 	}
+	return res
 }
@@ -0,0 +1,67 @@
+package mapper
+
+import (
+	"backend2/internal/dto"
+	"backend2/internal/entity"
+)
+
+func OrderCreateRequestToEntity(request dto.OrderCreateRequestDTO) entity.Order {
+	items := make([]entity.OrderItem, 0, len(request.Items))
+	for _, item := range request.Items {
+		items = append(items, entity.OrderItem{
+			ProductId: item.ProductId,
+			Quantity:  item.Quantity,
+		})
+	}
+	return entity.Order{
+		ClientId:          request.ClientId,
+		Items:             items,
+		ShippingAddressId: request.ShippingAddressId,
+		BillingAddressId:  request.BillingAddressId,
+	}
+}
+
+func OrderEntityToDTO(order entity.Order) dto.OrderResponseDTO {
+	items := make([]dto.OrderItemResponseDTO, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, dto.OrderItemResponseDTO{
+			Id:        item.Id,
+			ProductId: item.ProductId,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+		})
+	}
+	return dto.OrderResponseDTO{
+		Id:        order.Id,
+		ClientId:  order.ClientId,
+		Status:    order.Status,
+		Total:     order.Total,
+		CreatedAt: order.CreatedAt,
+		UpdatedAt: order.UpdatedAt,
+		Items:     items,
+
+		ShippingAddress: orderAddressToDTO(order.ShippingAddress),
+		BillingAddress:  orderAddressToDTO(order.BillingAddress),
+	}
+}
+
+func orderAddressToDTO(addr *entity.Address) *dto.OrderAddressDTO {
+	if addr == nil {
+		return nil
+	}
+	return &dto.OrderAddressDTO{
+		Country: addr.Country,
+		City:    addr.City,
+		Street:  addr.Street,
+	}
+}
+
+func OrdersEntityToDTO(orders []entity.Order) dto.OrdersResponseDTO {
+	ordersDTO := dto.OrdersResponseDTO{
+		Orders: make([]dto.OrderResponseDTO, 0, len(orders)),
+	}
+	for _, order := range orders {
+		ordersDTO.Orders = append(ordersDTO.Orders, OrderEntityToDTO(order))
+	}
+	return ordersDTO
+}
@@ -38,3 +38,48 @@ func ProductsEntityToDTOs(products []entity.Product) dto.ProductsResponse {
 	}
 	return productsResponse
 }
+
+func ProductPageEntityToDTO(page entity.ProductPage) dto.ProductsPageResponse {
+	return dto.ProductsPageResponse{
+		Items:      ProductsEntityToDTOs(page.Items).Products,
+		Total:      page.Total,
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+		NextCursor: page.NextCursor,
+	}
+}
+
+func ProductSearchPageEntityToDTO(page entity.ProductSearchPage, limit, offset int) dto.ProductSearchResponse {
+	results := make([]dto.ProductSearchResultResponse, 0, len(page.Results))
+	for _, res := range page.Results {
+		results = append(results, dto.ProductSearchResultResponse{
+			Product: ProductEntityToDTO(res.Product),
+			Rank:    res.Rank,
+			Highlight: dto.ProductHighlightResponse{
+				Name:     res.NameHighlight,
+				Category: res.CategoryHighlight,
+			},
+		})
+	}
+	return dto.ProductSearchResponse{
+		Results: results,
+		Total:   page.Total,
+		Limit:   limit,
+		Offset:  offset,
+	}
+}
+
+func StockMovementsEntityToDTO(movements []entity.StockMovement) dto.StockMovementsResponse {
+	res := make([]dto.StockMovementResponse, 0, len(movements))
+	for _, m := range movements {
+		res = append(res, dto.StockMovementResponse{
+			Id:        m.Id,
+			ProductId: m.ProductId,
+			Delta:     m.Delta,
+			Reason:    m.Reason,
+			Actor:     m.Actor,
+			CreatedAt: m.CreatedAt,
+		})
+	}
+	return dto.StockMovementsResponse{Movements: res}
+}
@@ -0,0 +1,63 @@
+package mapper
+
+import (
+	"backend2/internal/dto"
+	"backend2/internal/entity"
+)
+
+func PurchaseOrderCreateRequestToEntity(request dto.PurchaseOrderCreateRequestDTO) entity.PurchaseOrder {
+	items := make([]entity.PurchaseOrderItem, 0, len(request.Items))
+	for _, item := range request.Items {
+		items = append(items, entity.PurchaseOrderItem{
+			ProductId: item.ProductId,
+			Quantity:  item.Quantity,
+			UnitCost:  item.UnitCost,
+		})
+	}
+	return entity.PurchaseOrder{
+		SupplierId: request.SupplierId,
+		Items:      items,
+	}
+}
+
+func PurchaseOrderReceiveRequestToEntity(request dto.PurchaseOrderReceiveRequestDTO) []entity.PurchaseOrderItem {
+	items := make([]entity.PurchaseOrderItem, 0, len(request.Items))
+	for _, item := range request.Items {
+		items = append(items, entity.PurchaseOrderItem{
+			ProductId: item.ProductId,
+			Quantity:  item.Quantity,
+		})
+	}
+	return items
+}
+
+func PurchaseOrderEntityToDTO(order entity.PurchaseOrder) dto.PurchaseOrderResponseDTO {
+	items := make([]dto.PurchaseOrderItemResponseDTO, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, dto.PurchaseOrderItemResponseDTO{
+			Id:               item.Id,
+			ProductId:        item.ProductId,
+			Quantity:         item.Quantity,
+			ReceivedQuantity: item.ReceivedQuantity,
+			UnitCost:         item.UnitCost,
+		})
+	}
+	return dto.PurchaseOrderResponseDTO{
+		Id:         order.Id,
+		SupplierId: order.SupplierId,
+		Status:     order.Status,
+		CreatedAt:  order.CreatedAt,
+		UpdatedAt:  order.UpdatedAt,
+		Items:      items,
+	}
+}
+
+func PurchaseOrdersEntityToDTO(orders []entity.PurchaseOrder) dto.PurchaseOrdersResponseDTO {
+	ordersDTO := dto.PurchaseOrdersResponseDTO{
+		PurchaseOrders: make([]dto.PurchaseOrderResponseDTO, 0, len(orders)),
+	}
+	for _, order := range orders {
+		ordersDTO.PurchaseOrders = append(ordersDTO.PurchaseOrders, PurchaseOrderEntityToDTO(order))
+	}
+	return ordersDTO
+}
@@ -44,13 +44,21 @@ func SuppliersEntityToDTO(suppliers []entity.Supplier) dto.SuppliersResponse {
 	return suppliersDTO
 }
 
-func SupplierUpdateDTOToEntity(request dto.SupplierUpdateAddressRequestDTO) entity.Supplier {
-	address := entity.Address{
-		Country: request.Country,
-		City:    request.City,
-		Street:  request.Street,
+func SuppliersPageToDTO(page entity.Page[entity.Supplier]) dto.SuppliersPageDTO {
+	return dto.SuppliersPageDTO{
+		Items:  SuppliersEntityToDTO(page.Items).Suppliers,
+		Total:  page.Total,
+		Limit:  page.Limit,
+		Offset: page.Offset,
 	}
-	return entity.Supplier{
-		Address: address,
+}
+
+func SupplierUpdateDTOToEntity(request dto.SupplierUpdateRequestDTO) entity.SupplierPatch {
+	return entity.SupplierPatch{
+		Name:        request.Name,
+		PhoneNumber: request.PhoneNumber,
+		Country:     request.Country,
+		City:        request.City,
+		Street:      request.Street,
 	}
 }
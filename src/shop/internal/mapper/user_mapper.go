@@ -0,0 +1,24 @@
+package mapper
+
+import (
+	"backend2/internal/dto"
+	"backend2/internal/entity"
+	"time"
+)
+
+func UserEntityToDTO(user entity.User) dto.UserResponseDTO {
+	return dto.UserResponseDTO{
+		Id:        user.Id,
+		Login:     user.Login,
+		Role:      user.Role,
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+func TokenPairToDTO(pair entity.TokenPair) dto.TokenResponseDTO {
+	return dto.TokenResponseDTO{
+		Token:        pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresIn:    int64(time.Until(pair.AccessExpiresAt).Seconds()),
+	}
+}
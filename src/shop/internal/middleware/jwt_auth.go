@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"backend2/internal/dto"
+	"backend2/internal/entity"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type TokenValidator interface {
+	ValidateToken(ctx context.Context, tokenStr string) (entity.Principal, bool)
+	ValidateAPIKey(ctx context.Context, key string) (entity.Principal, bool)
+}
+
+const APIKeyHeader = "X-API-Key"
+
+type ctxKey string
+
+const (
+	principalKey ctxKey = "principal"
+	tokenKey     ctxKey = "token"
+)
+
+// PrincipalFromContext возвращает пользователя, положенного в контекст AuthMiddleware.
+func PrincipalFromContext(ctx context.Context) (entity.Principal, bool) {
+	principal, ok := ctx.Value(principalKey).(entity.Principal)
+	return principal, ok
+}
+
+// UserIDFromContext возвращает id пользователя, положенный в контекст AuthMiddleware.
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	principal, ok := PrincipalFromContext(ctx)
+	return principal.UserID, ok
+}
+
+// TokenFromContext возвращает токен, с которым пришёл запрос.
+func TokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(tokenKey).(string)
+	return token, ok
+}
+
+// AuthMiddleware принимает либо API-ключ в заголовке X-API-Key,
+// либо JWT в заголовке Authorization: Bearer.
+func AuthMiddleware(auth TokenValidator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if apiKey := r.Header.Get(APIKeyHeader); apiKey != "" {
+				principal, valid := auth.ValidateAPIKey(r.Context(), apiKey)
+				if !valid {
+					unauthorized(w, "unauthorized: invalid api key")
+					return
+				}
+				ctx := context.WithValue(r.Context(), principalKey, principal)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			authHeader := r.Header.Get("Authorization")
+			if !strings.HasPrefix(authHeader, "Bearer ") {
+				unauthorized(w, "unauthorized: missing token")
+				return
+			}
+
+			tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
+			principal, valid := auth.ValidateToken(r.Context(), tokenStr)
+			if !valid {
+				unauthorized(w, "unauthorized: invalid token")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), principalKey, principal)
+			ctx = context.WithValue(ctx, tokenKey, tokenStr)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(dto.ErrorResponse{
+		Code:    http.StatusUnauthorized,
+		Message: message,
+	})
+}
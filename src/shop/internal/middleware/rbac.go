@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"backend2/internal/dto"
+	"encoding/json"
+	"net/http"
+)
+
+// RequireRoles пропускает запрос, только если роль пользователя или один из
+// скоупов API-ключа входит в roles. Должен стоять после AuthMiddleware.
+func RequireRoles(roles ...string) func(http.HandlerFunc) http.Handler {
+	return func(next http.HandlerFunc) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				unauthorized(w, "unauthorized: missing token")
+				return
+			}
+
+			if !principal.HasAnyRole(roles...) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(dto.ErrorResponse{
+					Code:    http.StatusForbidden,
+					Message: "forbidden: insufficient role",
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
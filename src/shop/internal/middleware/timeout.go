@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"backend2/internal/dto"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RouteTimeouts — сколько времени даётся на обработку запроса. Ключ в Routes —
+// "METHOD /шаблон/пути" или просто "/шаблон/пути" для любого метода,
+// шаблон пути такой же, как при регистрации маршрута в mux.
+type RouteTimeouts struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+// For возвращает таймаут маршрута, на который пришёл запрос.
+func (t RouteTimeouts) For(r *http.Request) time.Duration {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return t.Default
+	}
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return t.Default
+	}
+	if d, ok := t.Routes[r.Method+" "+tpl]; ok {
+		return d
+	}
+	if d, ok := t.Routes[tpl]; ok {
+		return d
+	}
+	return t.Default
+}
+
+// ParseRouteTimeouts разбирает список вида
+// "GET /api/v1/products/search=2s,/api/v1/client/{id}/cart/checkout=10s".
+func ParseRouteTimeouts(s string) (map[string]time.Duration, error) {
+	routes := make(map[string]time.Duration)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.LastIndex(item, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("route timeout %q: missing '='", item)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(item[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("route timeout %q: %w", item, err)
+		}
+		route := strings.Join(strings.Fields(item[:i]), " ")
+		if route == "" {
+			return nil, fmt.Errorf("route timeout %q: missing route", item)
+		}
+		routes[route] = d
+	}
+	return routes, nil
+}
+
+// Timeout ограничивает время обработки запроса. Контекст запроса получает
+// дедлайн, поэтому запросы к базе прерываются, а клиент вместо зависшего
+// соединения получает 504. Когда обработчик записал заголовки, ответ уже идёт
+// клиенту как есть и дописывается до конца, поэтому тела картинок и Range-ответов
+// не копятся в памяти. Нулевой таймаут снимает ограничение.
+// Должен стоять на роутере mux, чтобы маршрут запроса был уже известен.
+func Timeout(timeouts RouteTimeouts) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d := timeouts.For(r)
+			if d <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			tw := &timeoutWriter{w: w, ctx: ctx, header: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				if tw.code == 0 {
+					tw.writeHeader(http.StatusOK)
+				}
+			case <-ctx.Done():
+				tw.mu.Lock()
+				if tw.code != 0 {
+					// ответ уже начат: заменить его на 504 нельзя, обработчик его допишет
+					tw.mu.Unlock()
+					select {
+					case p := <-panicked:
+						panic(p)
+					case <-done:
+					}
+					return
+				}
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				// если клиент ушёл сам, отвечать некому
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					gatewayTimeout(w)
+				}
+			}
+		})
+	}
+}
+
+func gatewayTimeout(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusGatewayTimeout)
+	json.NewEncoder(w).Encode(dto.ErrorResponse{
+		Code:    http.StatusGatewayTimeout,
+		Message: "request timed out",
+	})
+}
+
+// timeoutWriter держит заголовки ответа, пока обработчик не начал его писать,
+// а дальше пропускает запись в w.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	w        http.ResponseWriter
+	ctx      context.Context
+	header   http.Header
+	code     int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.code == 0 {
+		tw.writeHeader(http.StatusOK)
+		if tw.timedOut {
+			return 0, http.ErrHandlerTimeout
+		}
+	}
+	return tw.w.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.code != 0 {
+		return
+	}
+	tw.writeHeader(code)
+}
+
+// writeHeader отправляет заголовки клиенту. Вызывается под tw.mu.
+func (tw *timeoutWriter) writeHeader(code int) {
+	tw.code = code
+	// обработчик сам вернул ошибку, споткнувшись о дедлайн
+	if code >= http.StatusInternalServerError && errors.Is(tw.ctx.Err(), context.DeadlineExceeded) {
+		tw.timedOut = true
+		gatewayTimeout(tw.w)
+		return
+	}
+	dst := tw.w.Header()
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+	tw.w.WriteHeader(code)
+}
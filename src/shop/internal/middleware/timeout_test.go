@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func serveWithTimeout(h http.HandlerFunc) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	Timeout(RouteTimeouts{Default: 50 * time.Millisecond})(h).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w
+}
+
+func TestTimeoutBeforeHeaders(t *testing.T) {
+	w := serveWithTimeout(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("late"))
+	})
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("status %d, want 504", w.Code)
+	}
+}
+
+func TestTimeoutHandlerErrorAfterDeadline(t *testing.T) {
+	w := serveWithTimeout(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("status %d, want 504", w.Code)
+	}
+}
+
+// Начатый ответ дописывается, даже если дедлайн наступил посреди тела.
+func TestTimeoutPassesThroughStartedResponse(t *testing.T) {
+	w := serveWithTimeout(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("first "))
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("second"))
+	})
+	if w.Code != http.StatusPartialContent {
+		t.Errorf("status %d, want 206", w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("Content-Type %q, want image/png", got)
+	}
+	if got := w.Body.String(); got != "first second" {
+		t.Errorf("body %q, want %q", got, "first second")
+	}
+}
+
+func TestTimeoutWithinDeadline(t *testing.T) {
+	w := serveWithTimeout(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "1")
+	})
+	if w.Code != http.StatusOK || w.Header().Get("X-Test") != "1" {
+		t.Errorf("status %d, X-Test %q, want 200 and 1", w.Code, w.Header().Get("X-Test"))
+	}
+}
@@ -1,25 +1,27 @@
 package repository
 
 import (
+	"backend2/internal/apperr"
 	"backend2/internal/entity"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 )
 
 type AddressRepo struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewAddressRepo(db *sql.DB) *AddressRepo {
+func NewAddressRepo(db DBTX) *AddressRepo {
 	return &AddressRepo{db: db}
 }
 
-func (a *AddressRepo) Save(address entity.Address) (entity.Address, error) {
+func (a *AddressRepo) Save(ctx context.Context, address entity.Address) (entity.Address, error) {
 
 	query := `insert into address (id, country,city,street) values ($1, $2, $3, $4)`
 
-	_, err := a.db.Exec(query, address.ID, address.Country, address.City, address.Street)
+	_, err := a.db.ExecContext(ctx, query, address.ID, address.Country, address.City, address.Street)
 
 	if err != nil {
 		return entity.Address{}, fmt.Errorf("error save addres: %w", err)
@@ -28,33 +30,252 @@ func (a *AddressRepo) Save(address entity.Address) (entity.Address, error) {
 	return address, nil
 }
 
-func (a *AddressRepo) Update(address entity.Address) (entity.Address, error) {
+func (a *AddressRepo) Update(ctx context.Context, address entity.Address) (entity.Address, error) {
 
 	query := `UPDATE address set country = $1, city = $2, street = $3 WHERE id = $4`
 
-	_, err := a.db.Exec(query, address.Country, address.City, address.Street, address.ID)
+	_, err := a.db.ExecContext(ctx, query, address.Country, address.City, address.Street, address.ID)
 	if err != nil {
 		return entity.Address{}, fmt.Errorf("error update addres: %w", err)
 	}
 	return address, nil
 }
 
-func (a *AddressRepo) Delete(address entity.Address) error {
+func (a *AddressRepo) Delete(ctx context.Context, address entity.Address) error {
 	query := `DELETE FROM address WHERE id = $1`
-	_, err := a.db.Exec(query, address.ID)
+	_, err := a.db.ExecContext(ctx, query, address.ID)
 	if err != nil {
 		return fmt.Errorf("error delete addres: %w", err)
 	}
 	return nil
 }
-func (a *AddressRepo) GetById(address entity.Address) (entity.Address, error) {
-	query := `SELECT * FROM address WHERE id = $1`
+func (a *AddressRepo) GetById(ctx context.Context, address entity.Address) (entity.Address, error) {
+	query := `SELECT id, country, city, street FROM address WHERE id = $1`
 	var addr entity.Address
-	row := a.db.QueryRow(query, address.ID).Scan(&addr.ID, &addr.Country, &addr.City, &addr.Street)
-
-	if errors.Is(row, sql.ErrNoRows) {
-		return entity.Address{}, fmt.Errorf("address not found %w", sql.ErrNoRows)
+	err := a.db.QueryRowContext(ctx, query, address.ID).Scan(&addr.ID, &addr.Country, &addr.City, &addr.Street)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.Address{}, apperr.ErrAddressNotFound
+	}
+	if err != nil {
+		return entity.Address{}, fmt.Errorf("error get address: %w", err)
 	}
 	return addr, nil
+}
+
+const clientAddressColumns = `ca.id, ca.client_id, ca.label, ca.is_default_shipping, ca.is_default_billing, ca.created_at,
+			  a.id, a.country, a.city, a.street`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanClientAddress(row rowScanner) (entity.ClientAddress, error) {
+	var ca entity.ClientAddress
+	err := row.Scan(
+		&ca.Id,
+		&ca.ClientId,
+		&ca.Label,
+		&ca.IsDefaultShipping,
+		&ca.IsDefaultBilling,
+		&ca.CreatedAt,
+		&ca.Address.ID,
+		&ca.Address.Country,
+		&ca.Address.City,
+		&ca.Address.Street,
+	)
+	return ca, err
+}
+
+func (a *AddressRepo) GetClientAddresses(ctx context.Context, clientId string) ([]entity.ClientAddress, error) {
+	query := `SELECT ` + clientAddressColumns + `
+			  FROM client_address ca
+			  INNER JOIN address a ON a.id = ca.address_id
+			  WHERE ca.client_id = $1
+			  ORDER BY ca.created_at, ca.id`
+
+	rows, err := a.db.QueryContext(ctx, query, clientId)
+	if err != nil {
+		return nil, fmt.Errorf("error get client addresses: %w", err)
+	}
+	defer rows.Close()
+
+	addresses := make([]entity.ClientAddress, 0)
+	for rows.Next() {
+		ca, err := scanClientAddress(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning client address: %w", err)
+		}
+		addresses = append(addresses, ca)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return addresses, nil
+}
+
+func (a *AddressRepo) GetClientAddress(ctx context.Context, clientId, id string) (entity.ClientAddress, error) {
+	query := `SELECT ` + clientAddressColumns + `
+			  FROM client_address ca
+			  INNER JOIN address a ON a.id = ca.address_id
+			  WHERE ca.id = $1 AND ca.client_id = $2`
+
+	ca, err := scanClientAddress(a.db.QueryRowContext(ctx, query, id, clientId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.ClientAddress{}, apperr.ErrAddressNotFound
+	}
+	if err != nil {
+		return entity.ClientAddress{}, fmt.Errorf("error get client address: %w", err)
+	}
+	return ca, nil
+}
+
+// AddClientAddress сохраняет новый адрес и добавляет его в адресную книгу клиента.
+// Флаги по умолчанию снимаются с других адресов клиента в той же транзакции.
+func (a *AddressRepo) AddClientAddress(ctx context.Context, ca entity.ClientAddress) (entity.ClientAddress, error) {
+	tx, err := begin(ctx, a.db)
+	if err != nil {
+		return entity.ClientAddress{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO address (id, country, city, street) VALUES ($1, $2, $3, $4)`,
+		ca.Address.ID, ca.Address.Country, ca.Address.City, ca.Address.Street)
+	if err != nil {
+		return entity.ClientAddress{}, fmt.Errorf("%w: %v", apperr.ErrAddressInsert, err)
+	}
+
+	if err = linkClientAddress(ctx, tx, ca); err != nil {
+		return entity.ClientAddress{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return entity.ClientAddress{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return ca, nil
+}
+
+// LinkClientAddress добавляет в адресную книгу клиента уже сохранённый адрес.
+func (a *AddressRepo) LinkClientAddress(ctx context.Context, ca entity.ClientAddress) error {
+	tx, err := begin(ctx, a.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = linkClientAddress(ctx, tx, ca); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
 
+func linkClientAddress(ctx context.Context, ex execer, ca entity.ClientAddress) error {
+	if err := clearClientAddressDefaults(ctx, ex, ca); err != nil {
+		return err
+	}
+
+	_, err := ex.ExecContext(ctx,
+		`INSERT INTO client_address (id, client_id, address_id, label, is_default_shipping, is_default_billing, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		ca.Id, ca.ClientId, ca.Address.ID, ca.Label, ca.IsDefaultShipping, ca.IsDefaultBilling, ca.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrAddressInsert, err)
+	}
+	return nil
+}
+
+// clearClientAddressDefaults снимает флаги, которые ca забирает себе, с остальных адресов клиента.
+func clearClientAddressDefaults(ctx context.Context, ex execer, ca entity.ClientAddress) error {
+	if ca.IsDefaultShipping {
+		_, err := ex.ExecContext(ctx, `UPDATE client_address SET is_default_shipping = false WHERE client_id = $1 AND id <> $2`, ca.ClientId, ca.Id)
+		if err != nil {
+			return fmt.Errorf("%w: %v", apperr.ErrAddressUpdate, err)
+		}
+	}
+	if ca.IsDefaultBilling {
+		_, err := ex.ExecContext(ctx, `UPDATE client_address SET is_default_billing = false WHERE client_id = $1 AND id <> $2`, ca.ClientId, ca.Id)
+		if err != nil {
+			return fmt.Errorf("%w: %v", apperr.ErrAddressUpdate, err)
+		}
+	}
+	return nil
+}
+
+// UpdateClientAddress меняет метку, флаги и сам адрес. Заказы хранят копию адреса,
+// поэтому их история не меняется.
+func (a *AddressRepo) UpdateClientAddress(ctx context.Context, ca entity.ClientAddress) (entity.ClientAddress, error) {
+	tx, err := begin(ctx, a.db)
+	if err != nil {
+		return entity.ClientAddress{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		`SELECT address_id, created_at FROM client_address WHERE id = $1 AND client_id = $2 FOR UPDATE`, ca.Id, ca.ClientId,
+	).Scan(&ca.Address.ID, &ca.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.ClientAddress{}, apperr.ErrAddressNotFound
+	}
+	if err != nil {
+		return entity.ClientAddress{}, fmt.Errorf("error get client address: %w", err)
+	}
+
+	if err = clearClientAddressDefaults(ctx, tx, ca); err != nil {
+		return entity.ClientAddress{}, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE client_address SET label = $1, is_default_shipping = $2, is_default_billing = $3 WHERE id = $4`,
+		ca.Label, ca.IsDefaultShipping, ca.IsDefaultBilling, ca.Id,
+	)
+	if err != nil {
+		return entity.ClientAddress{}, fmt.Errorf("%w: %v", apperr.ErrAddressUpdate, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE address SET country = $1, city = $2, street = $3 WHERE id = $4`,
+		ca.Address.Country, ca.Address.City, ca.Address.Street, ca.Address.ID)
+	if err != nil {
+		return entity.ClientAddress{}, fmt.Errorf("%w: %v", apperr.ErrAddressUpdate, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return entity.ClientAddress{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return ca, nil
+}
+
+// DeleteClientAddress убирает адрес из адресной книги. Сама строка address удаляется,
+// только если на неё больше не ссылается карточка клиента.
+func (a *AddressRepo) DeleteClientAddress(ctx context.Context, clientId, id string) error {
+	tx, err := begin(ctx, a.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var addressId string
+	err = tx.QueryRowContext(ctx,
+		`DELETE FROM client_address WHERE id = $1 AND client_id = $2 RETURNING address_id`, id, clientId,
+	).Scan(&addressId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperr.ErrAddressNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrAddressDelete, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM address WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM client WHERE address_id = $1)`, addressId,
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrAddressDelete, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+type APIKeyRepo struct {
+	db DBTX
+}
+
+func NewAPIKeyRepo(db DBTX) *APIKeyRepo {
+	return &APIKeyRepo{db: db}
+}
+
+func (a *APIKeyRepo) CreateAPIKey(ctx context.Context, key entity.APIKey) (entity.APIKey, error) {
+	query := `INSERT INTO api_key (id, name, prefix, key_hash, scopes, created_by, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := a.db.ExecContext(ctx, query,
+		key.Id,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		pq.Array(key.Scopes),
+		key.CreatedBy,
+		key.CreatedAt,
+	)
+	if err != nil {
+		return entity.APIKey{}, fmt.Errorf("%w: %v", apperr.ErrAPIKeyInsert, err)
+	}
+	return key, nil
+}
+
+func (a *APIKeyRepo) GetAPIKeys(ctx context.Context) ([]entity.APIKey, error) {
+	query := `SELECT id, name, prefix, scopes, created_by, created_at, last_used_at, revoked_at
+			  FROM api_key ORDER BY created_at`
+
+	rows, err := a.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error getting api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]entity.APIKey, 0)
+	for rows.Next() {
+		var key entity.APIKey
+		err := rows.Scan(
+			&key.Id,
+			&key.Name,
+			&key.Prefix,
+			pq.Array(&key.Scopes),
+			&key.CreatedBy,
+			&key.CreatedAt,
+			&key.LastUsedAt,
+			&key.RevokedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return keys, nil
+}
+
+func (a *APIKeyRepo) GetAPIKeyById(ctx context.Context, id string) (entity.APIKey, error) {
+	query := `SELECT id, name, prefix, scopes, created_by, created_at, last_used_at, revoked_at
+			  FROM api_key WHERE id = $1`
+
+	var key entity.APIKey
+	err := a.db.QueryRowContext(ctx, query, id).Scan(
+		&key.Id,
+		&key.Name,
+		&key.Prefix,
+		pq.Array(&key.Scopes),
+		&key.CreatedBy,
+		&key.CreatedAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.APIKey{}, apperr.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return entity.APIKey{}, fmt.Errorf("error getting api key: %w", err)
+	}
+	return key, nil
+}
+
+// RotateAPIKey заменяет секрет действующего ключа, сохраняя его id, имя и скоупы.
+func (a *APIKeyRepo) RotateAPIKey(ctx context.Context, id, prefix, keyHash string) (entity.APIKey, error) {
+	query := `UPDATE api_key SET prefix = $1, key_hash = $2, last_used_at = NULL
+			  WHERE id = $3 AND revoked_at IS NULL`
+
+	res, err := a.db.ExecContext(ctx, query, prefix, keyHash, id)
+	if err != nil {
+		return entity.APIKey{}, fmt.Errorf("%w: %v", apperr.ErrAPIKeyUpdate, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return entity.APIKey{}, fmt.Errorf("checking update rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return entity.APIKey{}, apperr.ErrAPIKeyNotFound
+	}
+	return a.GetAPIKeyById(ctx, id)
+}
+
+func (a *APIKeyRepo) RevokeAPIKey(ctx context.Context, id string) error {
+	query := `UPDATE api_key SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL`
+
+	res, err := a.db.ExecContext(ctx, query, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrAPIKeyUpdate, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking update rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// UseAPIKey находит действующий ключ по хешу и отмечает время его использования.
+func (a *APIKeyRepo) UseAPIKey(ctx context.Context, keyHash string) (entity.APIKey, error) {
+	query := `UPDATE api_key SET last_used_at = $1
+			  WHERE key_hash = $2 AND revoked_at IS NULL
+			  RETURNING id, name, prefix, scopes, created_by, created_at, last_used_at`
+
+	var key entity.APIKey
+	err := a.db.QueryRowContext(ctx, query, time.Now().UTC(), keyHash).Scan(
+		&key.Id,
+		&key.Name,
+		&key.Prefix,
+		pq.Array(&key.Scopes),
+		&key.CreatedBy,
+		&key.CreatedAt,
+		&key.LastUsedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.APIKey{}, apperr.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return entity.APIKey{}, fmt.Errorf("error using api key: %w", err)
+	}
+	return key, nil
+}
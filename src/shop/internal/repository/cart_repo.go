@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"context"
+	"fmt"
+)
+
+type CartRepo struct {
+	db DBTX
+}
+
+func NewCartRepo(db DBTX) *CartRepo {
+	return &CartRepo{db: db}
+}
+
+func (c *CartRepo) GetCartItems(ctx context.Context, clientId string) ([]entity.CartItem, error) {
+	query := `SELECT client_id, product_id, quantity, added_at FROM cart_item WHERE client_id = $1 ORDER BY added_at`
+
+	rows, err := c.db.QueryContext(ctx, query, clientId)
+	if err != nil {
+		return nil, fmt.Errorf("error getting cart items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]entity.CartItem, 0)
+	for rows.Next() {
+		var item entity.CartItem
+		err := rows.Scan(&item.ClientId, &item.ProductId, &item.Quantity, &item.AddedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning cart item: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return items, nil
+}
+
+// AddCartItem добавляет товар в корзину, увеличивая количество, если он уже там есть.
+func (c *CartRepo) AddCartItem(ctx context.Context, item entity.CartItem) error {
+	query := `INSERT INTO cart_item (client_id, product_id, quantity, added_at) VALUES ($1, $2, $3, $4)
+			  ON CONFLICT (client_id, product_id) DO UPDATE SET quantity = cart_item.quantity + excluded.quantity`
+
+	_, err := c.db.ExecContext(ctx, query, item.ClientId, item.ProductId, item.Quantity, item.AddedAt)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrCartUpdate, err)
+	}
+	return nil
+}
+
+func (c *CartRepo) UpdateCartItem(ctx context.Context, clientId, productId string, quantity int) error {
+	query := `UPDATE cart_item SET quantity = $1 WHERE client_id = $2 AND product_id = $3`
+
+	res, err := c.db.ExecContext(ctx, query, quantity, clientId, productId)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrCartUpdate, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking update rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.ErrCartItemNotFound
+	}
+	return nil
+}
+
+func (c *CartRepo) DeleteCartItem(ctx context.Context, clientId, productId string) error {
+	query := `DELETE FROM cart_item WHERE client_id = $1 AND product_id = $2`
+
+	res, err := c.db.ExecContext(ctx, query, clientId, productId)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrCartUpdate, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking delete rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.ErrCartItemNotFound
+	}
+	return nil
+}
+
+func (c *CartRepo) ClearCart(ctx context.Context, clientId string) error {
+	_, err := c.db.ExecContext(ctx, `DELETE FROM cart_item WHERE client_id = $1`, clientId)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrCartUpdate, err)
+	}
+	return nil
+}
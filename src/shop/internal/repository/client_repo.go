@@ -3,30 +3,34 @@ package repository
 import (
 	"backend2/internal/apperr"
 	"backend2/internal/entity"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
+	"time"
 )
 
 type ClientRepo struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewClientRepo(db *sql.DB) *ClientRepo {
+func NewClientRepo(db DBTX) *ClientRepo {
 	return &ClientRepo{
 		db: db,
 	}
 }
 
-func (c *ClientRepo) CreateClient(newClient entity.Client) (entity.Client, error) {
+func (c *ClientRepo) CreateClient(ctx context.Context, newClient entity.Client) (entity.Client, error) {
 
 	query := `
         INSERT INTO client (
             id, client_name, client_surname, birthday, gender, registration_date, address_id
         ) VALUES ($1, $2, $3, $4, $5, $6, $7)
     `
-	_, err := c.db.Exec(
+	_, err := c.db.ExecContext(ctx,
 		query,
 		newClient.Id,
 		newClient.ClientName,
@@ -40,47 +44,102 @@ func (c *ClientRepo) CreateClient(newClient entity.Client) (entity.Client, error
 		log.Print(newClient.Id)
 		return entity.Client{}, fmt.Errorf("%w: %v", apperr.ErrInsertFailed, err)
 	}
-	newClient, err = c.GetClientById(newClient.Id)
+	newClient, err = c.GetClientById(ctx, newClient.Id)
 	if err != nil {
 		return entity.Client{}, fmt.Errorf("%w: %v", apperr.ErrInsertFailed, err)
 	}
 	return newClient, nil
 }
 
-func (c *ClientRepo) UpdateClient(id string, newClient entity.Client) (entity.Client, error) {
-	query := `
-        	UPDATE address
-		SET country = $1, city = $2, street = $3
-		WHERE id = (
-			SELECT address_id FROM client WHERE id = $4
-		)
-	`
-
-	result, err := c.db.Exec(
-		query,
-		newClient.Address.Country,
-		newClient.Address.City,
-		newClient.Address.Street,
-		id,
-	)
+// UpdateClient применяет patch к клиенту и его адресу в одной транзакции.
+func (c *ClientRepo) UpdateClient(ctx context.Context, id string, patch entity.ClientPatch) error {
+	tx, err := begin(ctx, c.db)
 	if err != nil {
-		return entity.Client{}, fmt.Errorf("%w: %v", apperr.ErrUpdateFailed, err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	var addressId sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT address_id FROM client WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&addressId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperr.ErrClientNotFound
+	}
 	if err != nil {
-		return entity.Client{}, fmt.Errorf("checking update rows: %w", err)
+		return fmt.Errorf("failed to lock client: %w", err)
 	}
-	if rowsAffected == 0 {
-		return entity.Client{}, apperr.ErrClientNotFound
+
+	clientSet := make(map[string]any)
+	if patch.ClientName != nil {
+		clientSet["client_name"] = *patch.ClientName
+	}
+	if patch.ClientSurname != nil {
+		clientSet["client_surname"] = *patch.ClientSurname
+	}
+	if patch.BirthDate != nil {
+		clientSet["birthday"] = *patch.BirthDate
+	}
+	if patch.Gender != nil {
+		clientSet["gender"] = *patch.Gender
+	}
+	if err = updateColumns(ctx, tx, "client", id, clientSet); err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrUpdateFailed, err)
 	}
 
-	return newClient, nil
+	addressSet := make(map[string]any)
+	if patch.Country != nil {
+		addressSet["country"] = *patch.Country
+	}
+	if patch.City != nil {
+		addressSet["city"] = *patch.City
+	}
+	if patch.Street != nil {
+		addressSet["street"] = *patch.Street
+	}
+	if len(addressSet) > 0 {
+		if !addressId.Valid {
+			return fmt.Errorf("client %s has no address: %w", id, apperr.ErrAddressNotFound)
+		}
+		if err = updateColumns(ctx, tx, "address", addressId.String, addressSet); err != nil {
+			return fmt.Errorf("%w: %v", apperr.ErrUpdateFailed, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
 }
 
-func (c *ClientRepo) DeleteClient(id string) error {
-	query := `DELETE FROM client WHERE id = $1`
-	result, err := c.db.Exec(query, id)
+// updateColumns обновляет в строке table с данным id только переданные колонки.
+// Имена таблицы и колонок задаются в коде репозитория.
+func updateColumns(ctx context.Context, ex execer, table, id string, set map[string]any) error {
+	if len(set) == 0 {
+		return nil
+	}
+
+	columns := make([]string, 0, len(set))
+	for column := range set {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	assignments := make([]string, 0, len(columns))
+	args := make([]any, 0, len(columns)+1)
+	for _, column := range columns {
+		args = append(args, set[column])
+		assignments = append(assignments, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	args = append(args, id)
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = $%d", table, strings.Join(assignments, ", "), len(args))
+	_, err := ex.ExecContext(ctx, query, args...)
+	return err
+}
+
+// SoftDeleteClient помечает клиента удалённым; он пропадает из выборок, но может быть восстановлен.
+func (c *ClientRepo) SoftDeleteClient(ctx context.Context, id string) error {
+	query := `UPDATE client SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`
+	result, err := c.db.ExecContext(ctx, query, time.Now().UTC(), id)
 	if err != nil {
 		return fmt.Errorf("%w: %v", apperr.ErrDeleteFailed, err)
 	}
@@ -92,90 +151,147 @@ func (c *ClientRepo) DeleteClient(id string) error {
 	if rowsAffected == 0 {
 		return apperr.ErrClientNotFound
 	}
+	return nil
+}
 
+// RestoreClient снимает пометку удаления. Для неудалённого клиента ничего не меняет.
+func (c *ClientRepo) RestoreClient(ctx context.Context, id string) error {
+	result, err := c.db.ExecContext(ctx, `UPDATE client SET deleted_at = NULL WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrUpdateFailed, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking update rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.ErrClientNotFound
+	}
 	return nil
 }
 
-func (c *ClientRepo) GetClients(name, surName string) ([]entity.Client, error) {
+// DeleteClient физически удаляет клиента (в том числе помеченного удалённым) вместе
+// с адресами и корзиной. Если у клиента есть заказы, без cascade возвращается
+// *apperr.DependentsError, с cascade заказы удаляются.
+func (c *ClientRepo) DeleteClient(ctx context.Context, id string, cascade bool, actor string) error {
+	tx, err := begin(ctx, c.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	query := `
-		SELECT client.id , client_name, client_surname, birthday, gender, registration_date, address_id, address.id as id, address.country as country, address.city as city, address.street as street
-				FROM client
-				inner join address  on address.id = client.address_id
-		WHERE client_name = $1 AND client_surname = $2
-	`
+	var addressId sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT address_id FROM client WHERE id = $1 FOR UPDATE`, id).Scan(&addressId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperr.ErrClientNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock client: %w", err)
+	}
 
-	rows, err := c.db.Query(query, name, surName)
+	if cascade {
+		err = deleteOrders(ctx, tx, "client_id = $1", id, actor)
+	} else {
+		err = findDependents(ctx, tx, clientDependents, id)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get clients: %w", err)
+		return err
 	}
-	defer rows.Close()
 
-	var clients []entity.Client
+	addressIds := make([]string, 0)
+	if addressId.Valid {
+		addressIds = append(addressIds, addressId.String)
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT address_id FROM client_address WHERE client_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error get client addresses: %w", err)
+	}
 	for rows.Next() {
-		var client entity.Client
-		err := rows.Scan(
-			&client.Id,
-			&client.ClientName,
-			&client.ClientSurname,
-			&client.BirthDate,
-			&client.Gender,
-			&client.RegistrationDate,
-			&client.AddressId,
-			&client.Address.ID,
-			&client.Address.Country,
-			&client.Address.City,
-			&client.Address.Street,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan client row: %w", err)
+		var bookAddressId string
+		if err := rows.Scan(&bookAddressId); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning client address: %w", err)
 		}
-		clients = append(clients, client)
+		addressIds = append(addressIds, bookAddressId)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration error: %w", err)
 	}
 
-	if len(clients) == 0 {
-
-		return make([]entity.Client, 0), apperr.ErrClientNotFound
+	_, err = tx.ExecContext(ctx, `DELETE FROM client WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrDeleteFailed, err)
+	}
+	if err = deleteOrphanAddresses(ctx, tx, addressIds); err != nil {
+		return err
 	}
 
-	return clients, nil
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
 }
 
-func (c *ClientRepo) GetAllClients(limit, offset int) ([]entity.Client, error) {
-	var (
-		query string
-		rows  *sql.Rows
-		err   error
-	)
+const clientColumns = `client.id , client_name, client_surname, birthday, gender, registration_date, address_id, address.id as id, address.country as country, address.city as city, address.street as street`
 
-	if limit == 0 && offset == 0 {
-		query = `SELECT client.id , client_name, client_surname, birthday, gender, registration_date, address_id, address.id as id, address.country as country, address.city as city, address.street as street
-				FROM client
-				inner join address  on address.id = client.address_id`
-		rows, err = c.db.Query(query)
-	} else if limit > 0 && offset > 0 {
-		query = `SELECT client.id , client_name, client_surname, birthday, gender, registration_date, address_id, address.id as id, address.country as country, address.city as city, address.street as street
-				FROM client
-				inner join address  on address.id = client.address_id LIMIT $1 OFFSET $2`
-		rows, err = c.db.Query(query, limit, offset)
-	} else if limit > 0 {
-		query = `SELECT client.id , client_name, client_surname, birthday, gender, registration_date, address_id, address.id as id, address.country as country, address.city as city, address.street as street
-				FROM client
-				inner join address  on address.id = client.address_id LIMIT $1`
-		rows, err = c.db.Query(query, limit)
-	} else {
-		query = `SELECT client.id , client_name, client_surname, birthday, gender, registration_date, address_id, address.id as id, address.country as country, address.city as city, address.street as street
-				FROM client
-				inner join address  on address.id = client.address_id OFFSET $1`
-		rows, err = c.db.Query(query, offset)
+// GetAllClients ищет клиентов по фильтру и возвращает страницу и общее число найденных.
+// Нулевой limit — без ограничения.
+func (c *ClientRepo) GetAllClients(ctx context.Context, filter entity.ClientFilter) ([]entity.Client, int, error) {
+	q := newSelect(clientColumns, "client inner join address on address.id = client.address_id")
+	q.Where("client.deleted_at IS NULL")
+
+	if filter.Query != "" {
+		pattern := "%" + escapeLike(filter.Query) + "%"
+		q.Where("(client_name ILIKE ? OR client_surname ILIKE ?)", pattern, pattern)
 	}
+	if filter.Name != "" {
+		q.Where("client_name ILIKE ?", "%"+escapeLike(filter.Name)+"%")
+	}
+	if filter.Surname != "" {
+		q.Where("client_surname ILIKE ?", "%"+escapeLike(filter.Surname)+"%")
+	}
+	if filter.Gender != "" {
+		q.Where("lower(gender) = lower(?)", filter.Gender)
+	}
+	if !filter.BirthFrom.IsZero() {
+		q.Where("birthday >= ?", filter.BirthFrom)
+	}
+	if !filter.BirthTo.IsZero() {
+		q.Where("birthday <= ?", filter.BirthTo)
+	}
+	if !filter.RegisteredFrom.IsZero() {
+		q.Where("registration_date >= ?", filter.RegisteredFrom)
+	}
+	if !filter.RegisteredTo.IsZero() {
+		q.Where("registration_date <= ?", filter.RegisteredTo)
+	}
+	if filter.Country != "" {
+		q.Where("lower(address.country) = lower(?)", filter.Country)
+	}
+	if filter.City != "" {
+		q.Where("lower(address.city) = lower(?)", filter.City)
+	}
+
+	var total int
+	countQuery, countArgs := q.BuildCount()
+	if err := c.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count clients: %w", err)
+	}
+
+	query, args := q.OrderBy("client_surname", "client_name", "client.id").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Build()
 
+	rows, err := c.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get clients: %w", err)
+		return nil, 0, fmt.Errorf("failed to get clients: %w", err)
 	}
 	defer rows.Close()
 
-	var clients []entity.Client
+	clients := make([]entity.Client, 0)
 	for rows.Next() {
 		var client entity.Client
 		err := rows.Scan(
@@ -192,27 +308,26 @@ func (c *ClientRepo) GetAllClients(limit, offset int) ([]entity.Client, error) {
 			&client.Address.Street,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan client row: %w", err)
+			return nil, 0, fmt.Errorf("failed to scan client row: %w", err)
 		}
 		clients = append(clients, client)
 	}
-
-	if len(clients) == 0 {
-		return nil, apperr.ErrClientNotFound
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows iteration error: %w", err)
 	}
 
-	return clients, nil
+	return clients, total, nil
 }
 
-func (c *ClientRepo) GetClientById(id string) (entity.Client, error) {
+func (c *ClientRepo) GetClientById(ctx context.Context, id string) (entity.Client, error) {
 	query := `
 		SELECT client.id , client_name, client_surname, birthday, gender, registration_date, address_id, address.id as id, address.country as country, address.city as city, address.street as street
 				FROM client
 				inner join address  on address.id = client.address_id
-		WHERE client.id = $1
+		WHERE client.id = $1 AND client.deleted_at IS NULL
 	`
 	var client entity.Client
-	err := c.db.QueryRow(query, id).Scan(
+	err := c.db.QueryRowContext(ctx, query, id).Scan(
 		&client.Id,
 		&client.ClientName,
 		&client.ClientSurname,
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+)
+
+// querier — общее у *sql.DB и *sql.Tx для выборок внутри транзакции.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// maxDependentIds — сколько id зависимых записей одного вида попадает в ошибку.
+const maxDependentIds = 100
+
+// dependentCheck описывает записи, которые ссылаются на удаляемую строку.
+// В where единственный параметр $1 — id удаляемой строки.
+type dependentCheck struct {
+	kind  string
+	table string
+	where string
+}
+
+var clientDependents = []dependentCheck{
+	{kind: "order", table: "orders", where: "client_id = $1"},
+}
+
+var supplierDependents = []dependentCheck{
+	{kind: "product", table: "product", where: "supplier_id = $1"},
+	{kind: "purchase_order", table: "purchase_order", where: "supplier_id = $1"},
+}
+
+var productDependents = []dependentCheck{
+	{kind: "order", table: "orders", where: "id IN (SELECT order_id FROM order_item WHERE product_id = $1)"},
+	{kind: "purchase_order", table: "purchase_order", where: "id IN (SELECT purchase_order_id FROM purchase_order_item WHERE product_id = $1)"},
+}
+
+// Записи, которые не удаляются и при cascade: закупки, по которым товар уже
+// принимался на склад, и неотменённые заказы с товаром.
+var (
+	supplierReceivedDependents = []dependentCheck{
+		{kind: "purchase_order", table: "purchase_order", where: "supplier_id = $1 AND status IN ('partially_received', 'received')"},
+	}
+	productCascadeDependents = []dependentCheck{
+		{kind: "order", table: "orders", where: "status <> 'cancelled' AND id IN (SELECT order_id FROM order_item WHERE product_id = $1)"},
+		{kind: "purchase_order", table: "purchase_order", where: "status IN ('partially_received', 'received') AND id IN (SELECT purchase_order_id FROM purchase_order_item WHERE product_id = $1)"},
+	}
+)
+
+// findDependents возвращает *apperr.DependentsError, если хоть одна проверка нашла записи.
+func findDependents(ctx context.Context, q querier, checks []dependentCheck, id string) error {
+	var dependents []apperr.Dependent
+	for _, check := range checks {
+		query := fmt.Sprintf(
+			`SELECT id, count(*) OVER() FROM %s WHERE %s ORDER BY id LIMIT %d`,
+			check.table, check.where, maxDependentIds,
+		)
+		rows, err := q.QueryContext(ctx, query, id)
+		if err != nil {
+			return fmt.Errorf("failed to check %s dependents: %w", check.kind, err)
+		}
+
+		dependent := apperr.Dependent{Kind: check.kind, Ids: make([]string, 0)}
+		for rows.Next() {
+			var dependentId string
+			if err := rows.Scan(&dependentId, &dependent.Count); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan %s dependent: %w", check.kind, err)
+			}
+			dependent.Ids = append(dependent.Ids, dependentId)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("rows iteration error: %w", err)
+		}
+		if dependent.Count > 0 {
+			dependents = append(dependents, dependent)
+		}
+	}
+
+	if len(dependents) > 0 {
+		return &apperr.DependentsError{Dependents: dependents}
+	}
+	return nil
+}
+
+// deleteOrders удаляет заказы, подходящие под where с параметром $1. Товар из заказов,
+// которые ещё не отгружены, возвращается на склад, как при отмене.
+func deleteOrders(ctx context.Context, tx DBTX, where, id, actor string) error {
+	reserved := []string{entity.OrderStatusNew, entity.OrderStatusPaid}
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE product SET available_stock = available_stock + item.quantity
+		FROM (SELECT product_id, sum(quantity) AS quantity FROM order_item
+		      WHERE order_id IN (SELECT id FROM orders WHERE (`+where+`) AND status = ANY($2))
+		      GROUP BY product_id) AS item
+		WHERE product.id = item.product_id
+		RETURNING product.id, item.quantity
+	`, id, pq.Array(reserved))
+	if err != nil {
+		return fmt.Errorf("failed to restore stock: %w", err)
+	}
+	restored := make(map[string]int)
+	for rows.Next() {
+		var productId string
+		var quantity int
+		if err := rows.Scan(&productId, &quantity); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan restored stock: %w", err)
+		}
+		restored[productId] = quantity
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	for productId, quantity := range restored {
+		err = insertStockMovement(ctx, tx, productId, quantity, entity.StockReasonReturn, actor)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM orders WHERE `+where, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete orders: %w", err)
+	}
+	return nil
+}
+
+// deleteProductItems убирает товар из закупок, которые ещё не принимались; закупка
+// удаляется, только если в ней не осталось позиций. Позиции отменённых заказов
+// остаются как есть, ссылку на товар в них обнуляет внешний ключ.
+func deleteProductItems(ctx context.Context, tx DBTX, id string) error {
+	var purchaseOrderIds []string
+	err := tx.QueryRowContext(ctx, `
+		WITH removed AS (DELETE FROM purchase_order_item WHERE product_id = $1 RETURNING purchase_order_id)
+		SELECT coalesce(array_agg(DISTINCT purchase_order_id::text), '{}') FROM removed
+	`, id).Scan(pq.Array(&purchaseOrderIds))
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrPurchaseOrderUpdate, err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM purchase_order WHERE id = ANY($1)
+		  AND NOT EXISTS (SELECT 1 FROM purchase_order_item WHERE purchase_order_id = purchase_order.id)
+	`, pq.Array(purchaseOrderIds))
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrPurchaseOrderUpdate, err)
+	}
+	return nil
+}
+
+// deleteOrphanAddresses удаляет адреса из ids, на которые больше никто не ссылается.
+func deleteOrphanAddresses(ctx context.Context, ex execer, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := ex.ExecContext(ctx, `
+		DELETE FROM address WHERE id = ANY($1)
+		  AND NOT EXISTS (SELECT 1 FROM client WHERE client.address_id = address.id)
+		  AND NOT EXISTS (SELECT 1 FROM client_address WHERE client_address.address_id = address.id)
+		  AND NOT EXISTS (SELECT 1 FROM supplier WHERE supplier.address_id = address.id)
+	`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrAddressDelete, err)
+	}
+	return nil
+}
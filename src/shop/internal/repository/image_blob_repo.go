@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"backend2/internal/apperr"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// PurgeDeletedBlobs удаляет из хранилища файлы удалённых и заменённых
+// изображений и их вариантов, не больше limit за вызов, и возвращает, сколько файлов удалено.
+func (i *ImageRepo) PurgeDeletedBlobs(ctx context.Context, limit int) (int, error) {
+	removed := 0
+	for removed < limit {
+		ok, err := i.purgeNextBlob(ctx)
+		if err != nil {
+			return removed, err
+		}
+		if !ok {
+			break
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// purgeNextBlob удаляет файл из головы очереди. Запись очереди заблокирована,
+// пока файл удаляется, поэтому UpdateImage с тем же содержимым ждёт и
+// записывает файл заново уже после удаления.
+func (i *ImageRepo) purgeNextBlob(ctx context.Context) (bool, error) {
+	tx, err := begin(ctx, i.db)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var key string
+	var inUse bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT key, EXISTS(SELECT 1 FROM images WHERE images.blob_key = image_blob_deletion.key)
+		         OR EXISTS(SELECT 1 FROM image_variant WHERE image_variant.blob_key = image_blob_deletion.key)
+		FROM image_blob_deletion
+		ORDER BY created_at, key
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`).Scan(&key, &inUse)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get deleted image file: %w", err)
+	}
+
+	if !inUse {
+		if err = i.blobs.Delete(ctx, key); err != nil {
+			return false, fmt.Errorf("failed to delete image file %s: %w", key, err)
+		}
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM image_blob_deletion WHERE key = $1`, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to dequeue image file %s: %w", key, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// BlobKeys возвращает ключи файлов изображений и их вариантов по возрастанию, начиная после after.
+func (i *ImageRepo) BlobKeys(ctx context.Context, after string, limit int) ([]string, error) {
+	rows, err := i.db.QueryContext(ctx, `
+		SELECT key FROM (
+			SELECT blob_key AS key FROM images WHERE blob_key IS NOT NULL
+			UNION ALL
+			SELECT blob_key FROM image_variant WHERE blob_key IS NOT NULL
+		) AS blob
+		WHERE key > $1
+		ORDER BY key
+		LIMIT $2
+	`, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting image file keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0, limit)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("error scanning image file key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return keys, nil
+}
+
+// MoveLegacyFiles переносит в хранилище файлы, которые ещё лежат в images.image,
+// по batch за запрос, и удаляет варианты, закэшированные до появления blob_key.
+// Возвращает, сколько файлов перенесено.
+func (i *ImageRepo) MoveLegacyFiles(ctx context.Context, batch int) (int, error) {
+	moved := 0
+	for {
+		files, err := i.legacyFiles(ctx, batch)
+		if err != nil {
+			return moved, err
+		}
+		if len(files) == 0 {
+			break
+		}
+		for _, file := range files {
+			if err := i.moveLegacyFile(ctx, file); err != nil {
+				return moved, err
+			}
+			moved++
+		}
+	}
+
+	_, err := i.db.ExecContext(ctx, `DELETE FROM image_variant WHERE blob_key IS NULL`)
+	if err != nil {
+		return moved, fmt.Errorf("failed to delete legacy image variants: %w", err)
+	}
+	return moved, nil
+}
+
+type legacyFile struct {
+	imageId string
+	blobKey sql.NullString
+	data    []byte
+}
+
+func (i *ImageRepo) legacyFiles(ctx context.Context, limit int) ([]legacyFile, error) {
+	rows, err := i.db.QueryContext(ctx,
+		`SELECT id, blob_key, image FROM images WHERE image IS NOT NULL ORDER BY id LIMIT $1`, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error getting legacy image files: %w", err)
+	}
+	defer rows.Close()
+
+	files := make([]legacyFile, 0, limit)
+	for rows.Next() {
+		var file legacyFile
+		if err := rows.Scan(&file.imageId, &file.blobKey, &file.data); err != nil {
+			return nil, fmt.Errorf("error scanning legacy image file: %w", err)
+		}
+		files = append(files, file)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return files, nil
+}
+
+// moveLegacyFile записывает файл в хранилище и очищает images.image. Если у
+// изображения уже есть ключ, файл по нему новее, и старое содержимое просто отбрасывается.
+func (i *ImageRepo) moveLegacyFile(ctx context.Context, file legacyFile) error {
+	if file.blobKey.Valid {
+		_, err := i.db.ExecContext(ctx, `UPDATE images SET image = NULL WHERE id = $1`, file.imageId)
+		if err != nil {
+			return fmt.Errorf("%w: %v", apperr.ErrImageUpdate, err)
+		}
+		return nil
+	}
+
+	sum := sha256.Sum256(file.data)
+	hash := hex.EncodeToString(sum[:])
+	key := blobKey(file.imageId, hash)
+	if err := i.blobs.Put(ctx, key, file.data); err != nil {
+		return fmt.Errorf("failed to put image file %s: %w", key, err)
+	}
+
+	res, err := i.db.ExecContext(ctx, `
+		UPDATE images SET blob_key = $1, sha256 = coalesce(sha256, $2), size = coalesce(size, $3), image = NULL
+		WHERE id = $4 AND blob_key IS NULL
+	`, key, hash, len(file.data), file.imageId)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrImageUpdate, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking update rows: %w", err)
+	}
+	// изображение успели заменить или удалить, записанный файл больше не нужен
+	if rowsAffected == 0 {
+		_, err = i.db.ExecContext(ctx,
+			`INSERT INTO image_blob_deletion (key) VALUES ($1) ON CONFLICT (key) DO NOTHING`, key,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue image file %s: %w", key, err)
+		}
+	}
+	return nil
+}
@@ -3,122 +3,292 @@ package repository
 import (
 	"backend2/internal/apperr"
 	"backend2/internal/entity"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 )
 
+// BlobStore хранит содержимое файлов изображений; в images остаются только
+// метаданные и ключ файла.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
 type ImageRepo struct {
-	db *sql.DB
+	db    DBTX
+	blobs BlobStore
 }
 
-func NewImageRepo(db *sql.DB) *ImageRepo {
+func NewImageRepo(db DBTX, blobs BlobStore) *ImageRepo {
 	return &ImageRepo{
-		db: db,
+		db:    db,
+		blobs: blobs,
 	}
 }
 
-func (i *ImageRepo) AddImage(productID string, image entity.Image) (entity.Image, error) {
-	tx, err := i.db.Begin()
+// blobKey — ключ файла изображения. Новое содержимое получает новый ключ,
+// поэтому замена файла не трогает старый, пока транзакция не закоммичена.
+func blobKey(imageId, sha256 string) string {
+	return imageId + "/" + sha256
+}
+
+// imageMetaColumns — метаданные изображения для scanImageMeta. У изображений,
+// загруженных до появления метаданных, пустые поля заменяются значениями по умолчанию.
+const imageMetaColumns = `images.id, coalesce(images.filename, ''),
+	coalesce(images.mime_type, 'application/octet-stream'), coalesce(images.size, octet_length(images.image), 0),
+	coalesce(images.width, 0), coalesce(images.height, 0), coalesce(images.sha256, ''), images.updated_at`
+
+// imageColumns — метаданные и ключ файла изображения для scanImage.
+const imageColumns = imageMetaColumns + `, images.blob_key`
+
+func scanImageMeta(row rowScanner, extra ...any) (entity.Image, error) {
+	var img entity.Image
+	dest := append([]any{&img.Id, &img.Filename, &img.MimeType, &img.Size, &img.Width, &img.Height, &img.Sha256, &img.UpdatedAt}, extra...)
+	err := row.Scan(dest...)
+	return img, err
+}
+
+func scanImage(row rowScanner) (entity.Image, string, error) {
+	var key sql.NullString
+	img, err := scanImageMeta(row, &key)
+	return img, key.String, err
+}
+
+// loadBlob читает содержимое изображения из хранилища файлов. Файл без ключа
+// ещё не перенесён командой migrate и читается из images.image.
+func (i *ImageRepo) loadBlob(ctx context.Context, img *entity.Image, key string) error {
+	if key == "" {
+		err := i.db.QueryRowContext(ctx, `SELECT image FROM images WHERE id = $1`, img.Id).Scan(&img.Image)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("error getting image file: %w", err)
+		}
+		return nil
+	}
+	data, err := i.blobs.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("error getting image file %s: %w", key, err)
+	}
+	img.Image = data
+	return nil
+}
+
+// discardBlob удаляет файл, запись о котором так и не была закоммичена.
+// Ошибка не важна: файл без записи только занимает место.
+func (i *ImageRepo) discardBlob(ctx context.Context, key string) {
+	i.blobs.Delete(context.WithoutCancel(ctx), key)
+}
+
+// AddImage добавляет изображение в конец галереи товара. Первое изображение
+// товара становится основным.
+func (i *ImageRepo) AddImage(ctx context.Context, productID string, image entity.Image) (entity.Image, error) {
+	tx, err := begin(ctx, i.db)
 	if err != nil {
 		return entity.Image{}, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// 1. Проверка существования продукта
-	var exists bool
-	err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM product WHERE id = $1)`, productID).Scan(&exists)
-	if err != nil {
-		return entity.Image{}, fmt.Errorf("failed to check product existence: %w", err)
+	if err = lockGalleryProduct(ctx, tx, productID); err != nil {
+		return entity.Image{}, err
 	}
-	if !exists {
-		return entity.Image{}, fmt.Errorf("product  not found: %w", apperr.ErrProductNotFound)
+
+	key := blobKey(image.Id, image.Sha256)
+	if err = i.blobs.Put(ctx, key, image.Image); err != nil {
+		return entity.Image{}, fmt.Errorf("%w: %v", apperr.ErrImageInsert, err)
 	}
+	committed := false
+	defer func() {
+		if !committed {
+			i.discardBlob(ctx, key)
+		}
+	}()
 
-	// 2. Вставка изображения
-	_, err = tx.Exec(`INSERT INTO images (id, image) VALUES ($1, $2)`, image.Id, image.Image)
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO images (id, blob_key, filename, mime_type, size, width, height, sha256)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		image.Id, key, image.Filename, image.MimeType, image.Size, image.Width, image.Height, image.Sha256,
+	)
 	if err != nil {
-		return entity.Image{}, fmt.Errorf("failed to insert image: %w", err)
+		return entity.Image{}, fmt.Errorf("%w: %v", apperr.ErrImageInsert, err)
 	}
 
-	// 3. Обновление поля image_id у продукта
-	_, err = tx.Exec(`UPDATE product SET image_id = $1 WHERE id = $2`, image.Id, productID)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO product_image (product_id, image_id, position, is_primary)
+		SELECT $1, $2, coalesce(max(position) + 1, 0), false FROM product_image WHERE product_id = $1
+	`, productID, image.Id)
 	if err != nil {
-		return entity.Image{}, fmt.Errorf("failed to update product with image_id: %w", err)
+		return entity.Image{}, fmt.Errorf("%w: %v", apperr.ErrImageInsert, err)
+	}
+
+	if err = normalizeGallery(ctx, tx, productID); err != nil {
+		return entity.Image{}, err
 	}
 
-	// 4. Подтверждение транзакции
 	if err = tx.Commit(); err != nil {
 		return entity.Image{}, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	committed = true
 
 	return image, nil
 }
 
-func (i *ImageRepo) GetImageById(id string) (entity.Image, error) {
-	query := `SELECT id, image FROM images WHERE id = $1`
-	row := i.db.QueryRow(query, id)
+func (i *ImageRepo) GetImageById(ctx context.Context, id string) (entity.Image, error) {
+	query := `SELECT ` + imageColumns + ` FROM images WHERE id = $1`
+	row := i.db.QueryRowContext(ctx, query, id)
 
-	var img entity.Image
-	err := row.Scan(&img.Id, &img.Image)
+	img, key, err := scanImage(row)
 	if errors.Is(err, sql.ErrNoRows) {
 		return entity.Image{}, apperr.ErrImageNotFound
 	}
 	if err != nil {
 		return entity.Image{}, fmt.Errorf("error getting image: %w", err)
 	}
+	if err = i.loadBlob(ctx, &img, key); err != nil {
+		return entity.Image{}, err
+	}
 	return img, nil
 }
 
-func (i *ImageRepo) UpdateImage(image entity.Image) (entity.Image, error) {
-	query := `UPDATE images SET image = $1 WHERE id = $2`
+// UpdateImage заменяет файл изображения и сбрасывает его кэшированные варианты.
+// Прежний файл ставится в очередь на удаление.
+func (i *ImageRepo) UpdateImage(ctx context.Context, image entity.Image) (entity.Image, error) {
+	tx, err := begin(ctx, i.db)
+	if err != nil {
+		return entity.Image{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	res, err := i.db.Exec(query, image.Image, image.Id)
+	var oldKey sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT blob_key FROM images WHERE id = $1 FOR UPDATE`, image.Id).Scan(&oldKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.Image{}, apperr.ErrImageNotFound
+	}
 	if err != nil {
+		return entity.Image{}, fmt.Errorf("failed to lock image: %w", err)
+	}
+
+	// файл с тем же содержимым мог ждать удаления; запись из очереди убирается
+	// до загрузки, чтобы очистка очереди не удалила только что записанный файл
+	key := blobKey(image.Id, image.Sha256)
+	_, err = tx.ExecContext(ctx, `DELETE FROM image_blob_deletion WHERE key = $1`, key)
+	if err != nil {
+		return entity.Image{}, fmt.Errorf("%w: %v", apperr.ErrImageUpdate, err)
+	}
+	if err = i.blobs.Put(ctx, key, image.Image); err != nil {
 		return entity.Image{}, fmt.Errorf("%w: %v", apperr.ErrImageUpdate, err)
 	}
+	committed := false
+	defer func() {
+		if !committed && key != oldKey.String {
+			i.discardBlob(ctx, key)
+		}
+	}()
 
-	rowsAffected, err := res.RowsAffected()
+	_, err = tx.ExecContext(ctx,
+		`UPDATE images SET blob_key = $1, filename = $2, mime_type = $3, size = $4, width = $5, height = $6, sha256 = $7,
+		 image = NULL, updated_at = now()
+		 WHERE id = $8`,
+		key, image.Filename, image.MimeType, image.Size, image.Width, image.Height, image.Sha256, image.Id,
+	)
 	if err != nil {
-		return entity.Image{}, fmt.Errorf("checking update rows: %w", err)
+		return entity.Image{}, fmt.Errorf("%w: %v", apperr.ErrImageUpdate, err)
 	}
-	if rowsAffected == 0 {
-		return entity.Image{}, apperr.ErrImageNotFound
+
+	if oldKey.Valid && oldKey.String != key {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO image_blob_deletion (key) VALUES ($1) ON CONFLICT (key) DO NOTHING`, oldKey.String,
+		)
+		if err != nil {
+			return entity.Image{}, fmt.Errorf("%w: %v", apperr.ErrImageUpdate, err)
+		}
 	}
 
-	newImg, err := i.GetImageById(image.Id)
+	// файлы вариантов ставит в очередь на удаление триггер image_variant_blob_deletion
+	_, err = tx.ExecContext(ctx, `DELETE FROM image_variant WHERE image_id = $1`, image.Id)
+	if err != nil {
+		return entity.Image{}, fmt.Errorf("%w: %v", apperr.ErrImageUpdate, err)
+	}
+
+	newImg, _, err := scanImage(tx.QueryRowContext(ctx, `SELECT `+imageColumns+` FROM images WHERE id = $1`, image.Id))
 	if err != nil {
 		return entity.Image{}, fmt.Errorf("error getting updated image: %w", err)
 	}
+	newImg.Image = image.Image
+
+	if err = tx.Commit(); err != nil {
+		return entity.Image{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
 	return newImg, nil
 }
 
-func (i *ImageRepo) GetProductImageById(productId string) (entity.Image, error) {
+// GetProductImageById возвращает основное изображение товара.
+func (i *ImageRepo) GetProductImageById(ctx context.Context, productId string) (entity.Image, error) {
 	query := `
-		SELECT images.id, images.image
+		SELECT ` + imageColumns + `
 		FROM product
-		JOIN images ON product.image_id = images.id
-		WHERE product.id = $1
+		JOIN product_image ON product_image.product_id = product.id AND product_image.is_primary
+		JOIN images ON product_image.image_id = images.id
+		WHERE product.id = $1 AND product.deleted_at IS NULL
 	`
 
-	row := i.db.QueryRow(query, productId)
+	row := i.db.QueryRowContext(ctx, query, productId)
 
-	var img entity.Image
-	err := row.Scan(&img.Id, &img.Image)
+	img, key, err := scanImage(row)
 	if errors.Is(err, sql.ErrNoRows) {
 		return entity.Image{}, apperr.ErrImageNotFound
 	}
 	if err != nil {
 		return entity.Image{}, fmt.Errorf("error getting product image: %w", err)
 	}
+	if err = i.loadBlob(ctx, &img, key); err != nil {
+		return entity.Image{}, err
+	}
 
 	return img, nil
 }
 
-func (i *ImageRepo) DeleteImage(id string) error {
-	query := `DELETE FROM images WHERE id = $1`
+// DeleteImage удаляет изображение и убирает его из галерей товаров. Если оно было
+// основным, основным становится следующее по порядку.
+func (i *ImageRepo) DeleteImage(ctx context.Context, id string) error {
+	tx, err := begin(ctx, i.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// товары блокируются в порядке id, как и при оформлении заказа
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM product
+		WHERE id IN (SELECT product_id FROM product_image WHERE image_id = $1) OR image_id = $1
+		ORDER BY id FOR UPDATE
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to lock image products: %w", err)
+	}
+	var productIds []string
+	for rows.Next() {
+		var productId string
+		if err := rows.Scan(&productId); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan image product: %w", err)
+		}
+		productIds = append(productIds, productId)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE product SET image_id = NULL WHERE image_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrProductUpdate, err)
+	}
 
-	res, err := i.db.Exec(query, id)
+	// ссылки из product_image удаляются каскадно, файл ставится в очередь на удаление триггером
+	res, err := tx.ExecContext(ctx, `DELETE FROM images WHERE id = $1`, id)
 	if err != nil {
 		return fmt.Errorf("%w: %v", apperr.ErrImageDelete, err)
 	}
@@ -131,5 +301,14 @@ func (i *ImageRepo) DeleteImage(id string) error {
 		return apperr.ErrImageNotFound
 	}
 
+	for _, productId := range productIds {
+		if err = normalizeGallery(ctx, tx, productId); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
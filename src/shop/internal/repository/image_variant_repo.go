@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// variantBlobKey — ключ файла варианта. Он зависит от файла исходника, поэтому
+// вариант от прежнего файла не перезаписывается новым.
+func variantBlobKey(imageId, variant, sourceSha256 string) string {
+	return imageId + "/variants/" + variant + "-" + sourceSha256
+}
+
+// GetImageVariant возвращает кэшированный вариант изображения. Вариант,
+// полученный из прежнего файла изображения, не отдаётся.
+func (i *ImageRepo) GetImageVariant(ctx context.Context, imageId, key string) (entity.Image, error) {
+	var img entity.Image
+	var fileKey string
+	err := i.db.QueryRowContext(ctx, `
+		SELECT image_variant.image_id, coalesce(images.filename, ''), image_variant.mime_type, image_variant.size,
+		       image_variant.width, image_variant.height, image_variant.blob_key, images.sha256, images.updated_at
+		FROM image_variant
+		JOIN images ON images.id = image_variant.image_id AND images.sha256 = image_variant.source_sha256
+		WHERE image_variant.image_id = $1 AND image_variant.variant = $2 AND image_variant.blob_key IS NOT NULL
+	`, imageId, key).Scan(&img.Id, &img.Filename, &img.MimeType, &img.Size, &img.Width, &img.Height, &fileKey, &img.SourceSha256, &img.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.Image{}, apperr.ErrImageNotFound
+	}
+	if err != nil {
+		return entity.Image{}, fmt.Errorf("error getting image variant: %w", err)
+	}
+
+	// потерянный файл варианта просто посчитается заново
+	err = i.loadBlob(ctx, &img, fileKey)
+	if errors.Is(err, apperr.ErrBlobNotFound) {
+		return entity.Image{}, apperr.ErrImageNotFound
+	}
+	if err != nil {
+		return entity.Image{}, err
+	}
+	return img, nil
+}
+
+// SaveImageVariant сохраняет вариант изображения, полученный из файла с хэшем sourceSha256.
+// Файл варианта пишется в хранилище, в image_variant остаются метаданные и ключ.
+func (i *ImageRepo) SaveImageVariant(ctx context.Context, key, sourceSha256 string, variant entity.Image) error {
+	tx, err := begin(ctx, i.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var oldKey sql.NullString
+	err = tx.QueryRowContext(ctx,
+		`SELECT blob_key FROM image_variant WHERE image_id = $1 AND variant = $2 FOR UPDATE`, variant.Id, key,
+	).Scan(&oldKey)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to lock image variant: %w", err)
+	}
+
+	// как и в UpdateImage, файл мог ждать удаления после замены исходника
+	fileKey := variantBlobKey(variant.Id, key, sourceSha256)
+	_, err = tx.ExecContext(ctx, `DELETE FROM image_blob_deletion WHERE key = $1`, fileKey)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrImageInsert, err)
+	}
+	if err = i.blobs.Put(ctx, fileKey, variant.Image); err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrImageInsert, err)
+	}
+	committed := false
+	defer func() {
+		if !committed && fileKey != oldKey.String {
+			i.discardBlob(ctx, fileKey)
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO image_variant (image_id, variant, source_sha256, blob_key, mime_type, size, width, height)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (image_id, variant) DO UPDATE SET
+			source_sha256 = excluded.source_sha256, blob_key = excluded.blob_key, mime_type = excluded.mime_type,
+			size = excluded.size, width = excluded.width, height = excluded.height, created_at = now()
+	`, variant.Id, key, sourceSha256, fileKey, variant.MimeType, variant.Size, variant.Width, variant.Height)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrImageInsert, err)
+	}
+
+	if oldKey.Valid && oldKey.String != fileKey {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO image_blob_deletion (key) VALUES ($1) ON CONFLICT (key) DO NOTHING`, oldKey.String,
+		)
+		if err != nil {
+			return fmt.Errorf("%w: %v", apperr.ErrImageInsert, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+	return nil
+}
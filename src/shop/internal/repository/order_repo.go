@@ -0,0 +1,335 @@
+package repository
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+type OrderRepo struct {
+	db DBTX
+}
+
+func NewOrderRepo(db DBTX) *OrderRepo {
+	return &OrderRepo{db: db}
+}
+
+// CreateOrder в одной транзакции проверяет клиента, блокирует строки товаров,
+// списывает остатки и сохраняет заказ с ценами на момент покупки.
+func (o *OrderRepo) CreateOrder(ctx context.Context, order entity.Order, actor string) (entity.Order, error) {
+	tx, err := begin(ctx, o.db)
+	if err != nil {
+		return entity.Order{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM client WHERE id = $1 AND deleted_at IS NULL)`, order.ClientId).Scan(&exists)
+	if err != nil {
+		return entity.Order{}, fmt.Errorf("failed to check client existence: %w", err)
+	}
+	if !exists {
+		return entity.Order{}, apperr.ErrClientNotFound
+	}
+
+	order.ShippingAddress, err = snapshotClientAddress(ctx, tx, order.ClientId, order.ShippingAddressId, "is_default_shipping")
+	if err != nil {
+		return entity.Order{}, err
+	}
+	order.BillingAddress, err = snapshotClientAddress(ctx, tx, order.ClientId, order.BillingAddressId, "is_default_billing")
+	if err != nil {
+		return entity.Order{}, err
+	}
+
+	// товары блокируются в порядке id, чтобы параллельные заказы не ловили deadlock
+	items := make([]entity.OrderItem, len(order.Items))
+	copy(items, order.Items)
+	sort.Slice(items, func(i, j int) bool { return items[i].ProductId < items[j].ProductId })
+
+	order.Total = 0
+	for idx, item := range items {
+		var stock int
+		var price float64
+		err = tx.QueryRowContext(ctx,
+			`SELECT available_stock, price FROM product WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, item.ProductId,
+		).Scan(&stock, &price)
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.Order{}, fmt.Errorf("product %s: %w", item.ProductId, apperr.ErrProductNotFound)
+		}
+		if err != nil {
+			return entity.Order{}, fmt.Errorf("failed to lock product: %w", err)
+		}
+		if stock < item.Quantity {
+			return entity.Order{}, fmt.Errorf("product %s: %w", item.ProductId, apperr.ErrInsufficientStock)
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE product SET available_stock = available_stock - $1 WHERE id = $2`, item.Quantity, item.ProductId)
+		if err != nil {
+			return entity.Order{}, fmt.Errorf("%w: %v", apperr.ErrProductUpdate, err)
+		}
+		err = insertStockMovement(ctx, tx, item.ProductId, -item.Quantity, entity.StockReasonSale, actor)
+		if err != nil {
+			return entity.Order{}, err
+		}
+
+		items[idx].OrderId = order.Id
+		items[idx].UnitPrice = price
+		order.Total += price * float64(item.Quantity)
+	}
+
+	shipping, billing := addressColumns(order.ShippingAddress), addressColumns(order.BillingAddress)
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO orders (id, client_id, status, total, created_at, updated_at,
+			shipping_country, shipping_city, shipping_street, billing_country, billing_city, billing_street)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		order.Id, order.ClientId, order.Status, order.Total, order.CreatedAt, order.UpdatedAt,
+		shipping[0], shipping[1], shipping[2], billing[0], billing[1], billing[2],
+	)
+	if err != nil {
+		return entity.Order{}, fmt.Errorf("%w: %v", apperr.ErrOrderInsert, err)
+	}
+
+	for _, item := range items {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO order_item (id, order_id, product_id, quantity, unit_price) VALUES ($1, $2, $3, $4, $5)`,
+			item.Id, item.OrderId, item.ProductId, item.Quantity, item.UnitPrice,
+		)
+		if err != nil {
+			return entity.Order{}, fmt.Errorf("%w: %v", apperr.ErrOrderInsert, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return entity.Order{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	order.Items = items
+	return order, nil
+}
+
+// snapshotClientAddress копирует адрес из адресной книги клиента. Без addressId берётся
+// адрес, отмеченный флагом defaultColumn; если такого нет, возвращается nil.
+func snapshotClientAddress(ctx context.Context, tx DBTX, clientId, addressId, defaultColumn string) (*entity.Address, error) {
+	query := `SELECT a.country, a.city, a.street
+			  FROM client_address ca
+			  INNER JOIN address a ON a.id = ca.address_id
+			  WHERE ca.client_id = $1 AND ca.` + defaultColumn
+	args := []any{clientId}
+	if addressId != "" {
+		query = `SELECT a.country, a.city, a.street
+				 FROM client_address ca
+				 INNER JOIN address a ON a.id = ca.address_id
+				 WHERE ca.client_id = $1 AND ca.id = $2`
+		args = append(args, addressId)
+	}
+
+	var addr entity.Address
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&addr.Country, &addr.City, &addr.Street)
+	if errors.Is(err, sql.ErrNoRows) {
+		if addressId != "" {
+			return nil, fmt.Errorf("address %s: %w", addressId, apperr.ErrAddressNotFound)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order address: %w", err)
+	}
+	return &addr, nil
+}
+
+func addressColumns(addr *entity.Address) [3]sql.NullString {
+	if addr == nil {
+		return [3]sql.NullString{}
+	}
+	return [3]sql.NullString{
+		{String: addr.Country, Valid: true},
+		{String: addr.City, Valid: true},
+		{String: addr.Street, Valid: true},
+	}
+}
+
+func addressFromColumns(cols [3]sql.NullString) *entity.Address {
+	if !cols[0].Valid {
+		return nil
+	}
+	return &entity.Address{Country: cols[0].String, City: cols[1].String, Street: cols[2].String}
+}
+
+const orderColumns = `id, client_id, status, total, created_at, updated_at,
+			  shipping_country, shipping_city, shipping_street, billing_country, billing_city, billing_street`
+
+func scanOrder(row rowScanner) (entity.Order, error) {
+	var order entity.Order
+	var shipping, billing [3]sql.NullString
+	err := row.Scan(
+		&order.Id,
+		&order.ClientId,
+		&order.Status,
+		&order.Total,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+		&shipping[0], &shipping[1], &shipping[2],
+		&billing[0], &billing[1], &billing[2],
+	)
+	order.ShippingAddress = addressFromColumns(shipping)
+	order.BillingAddress = addressFromColumns(billing)
+	return order, err
+}
+
+func (o *OrderRepo) GetOrderById(ctx context.Context, id string) (entity.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1`
+
+	order, err := scanOrder(o.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.Order{}, apperr.ErrOrderNotFound
+	}
+	if err != nil {
+		return entity.Order{}, fmt.Errorf("error getting order: %w", err)
+	}
+
+	order.Items, err = o.getOrderItems(ctx, order.Id)
+	if err != nil {
+		return entity.Order{}, err
+	}
+	return order, nil
+}
+
+func (o *OrderRepo) GetOrdersByClientId(ctx context.Context, clientId string) ([]entity.Order, error) {
+	query := `SELECT ` + orderColumns + `
+			  FROM orders WHERE client_id = $1 ORDER BY created_at DESC`
+
+	rows, err := o.db.QueryContext(ctx, query, clientId)
+	if err != nil {
+		return nil, fmt.Errorf("error getting orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := make([]entity.Order, 0)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning order: %w", err)
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	for i := range orders {
+		orders[i].Items, err = o.getOrderItems(ctx, orders[i].Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return orders, nil
+}
+
+func (o *OrderRepo) getOrderItems(ctx context.Context, orderId string) ([]entity.OrderItem, error) {
+	// у позиций отменённых заказов товар может быть уже удалён
+	query := `SELECT id, order_id, coalesce(product_id::text, ''), quantity, unit_price FROM order_item WHERE order_id = $1`
+
+	rows, err := o.db.QueryContext(ctx, query, orderId)
+	if err != nil {
+		return nil, fmt.Errorf("error getting order items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]entity.OrderItem, 0)
+	for rows.Next() {
+		var item entity.OrderItem
+		err := rows.Scan(&item.Id, &item.OrderId, &item.ProductId, &item.Quantity, &item.UnitPrice)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning order item: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return items, nil
+}
+
+// UpdateOrderStatus меняет статус, только если заказ всё ещё в статусе from.
+func (o *OrderRepo) UpdateOrderStatus(ctx context.Context, id, from, to string) error {
+	query := `UPDATE orders SET status = $1, updated_at = $2 WHERE id = $3 AND status = $4`
+
+	res, err := o.db.ExecContext(ctx, query, to, time.Now().UTC(), id, from)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrOrderUpdate, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking update rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.ErrOrderStatusConflict
+	}
+	return nil
+}
+
+// CancelOrder отменяет заказ в статусе from и возвращает товары на склад.
+func (o *OrderRepo) CancelOrder(ctx context.Context, id, from, actor string) error {
+	tx, err := begin(ctx, o.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE orders SET status = $1, updated_at = $2 WHERE id = $3 AND status = $4`,
+		entity.OrderStatusCancelled, time.Now().UTC(), id, from,
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrOrderUpdate, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking update rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.ErrOrderStatusConflict
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE product SET available_stock = available_stock + item.quantity
+		FROM (SELECT product_id, sum(quantity) AS quantity FROM order_item WHERE order_id = $1 GROUP BY product_id) AS item
+		WHERE product.id = item.product_id
+		RETURNING product.id, item.quantity
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to restore stock: %w", err)
+	}
+	restored := make(map[string]int)
+	for rows.Next() {
+		var productId string
+		var quantity int
+		if err := rows.Scan(&productId, &quantity); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan restored stock: %w", err)
+		}
+		restored[productId] = quantity
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	for productId, quantity := range restored {
+		err = insertStockMovement(ctx, tx, productId, quantity, entity.StockReasonReturn, actor)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
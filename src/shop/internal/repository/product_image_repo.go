@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+)
+
+// GetProductImages возвращает галерею товара по порядку, без содержимого файлов.
+func (i *ImageRepo) GetProductImages(ctx context.Context, productId string) ([]entity.ProductImage, error) {
+	var exists bool
+	err := i.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM product WHERE id = $1 AND deleted_at IS NULL)`, productId,
+	).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check product existence: %w", err)
+	}
+	if !exists {
+		return nil, apperr.ErrProductNotFound
+	}
+
+	return getGallery(ctx, i.db, productId)
+}
+
+// ReorderProductImages расставляет изображения галереи в порядке imageIds.
+// imageIds должен содержать каждое изображение товара ровно один раз.
+func (i *ImageRepo) ReorderProductImages(ctx context.Context, productId string, imageIds []string) error {
+	tx, err := begin(ctx, i.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = lockGalleryProduct(ctx, tx, productId); err != nil {
+		return err
+	}
+
+	var linked int
+	err = tx.QueryRowContext(ctx, `SELECT count(*) FROM product_image WHERE product_id = $1`, productId).Scan(&linked)
+	if err != nil {
+		return fmt.Errorf("failed to count product images: %w", err)
+	}
+	if linked != len(imageIds) {
+		return apperr.ErrImageOrderMismatch
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE product_image SET position = ordered.position - 1
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS ordered (image_id, position)
+		WHERE product_image.product_id = $1 AND product_image.image_id = ordered.image_id
+	`, productId, pq.Array(imageIds))
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrImageUpdate, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking update rows: %w", err)
+	}
+	if rowsAffected != int64(linked) {
+		return apperr.ErrImageOrderMismatch
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// SetPrimaryImage делает изображение галереи основным изображением товара.
+func (i *ImageRepo) SetPrimaryImage(ctx context.Context, productId, imageId string) error {
+	tx, err := begin(ctx, i.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = lockGalleryProduct(ctx, tx, productId); err != nil {
+		return err
+	}
+
+	var linked bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM product_image WHERE product_id = $1 AND image_id = $2)`, productId, imageId,
+	).Scan(&linked)
+	if err != nil {
+		return fmt.Errorf("failed to check product image: %w", err)
+	}
+	if !linked {
+		return apperr.ErrImageNotFound
+	}
+
+	// сначала снимается старый флаг: основное изображение у товара одно
+	_, err = tx.ExecContext(ctx,
+		`UPDATE product_image SET is_primary = false WHERE product_id = $1 AND is_primary AND image_id <> $2`,
+		productId, imageId,
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrImageUpdate, err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`UPDATE product_image SET is_primary = true WHERE product_id = $1 AND image_id = $2`, productId, imageId,
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrImageUpdate, err)
+	}
+
+	if err = normalizeGallery(ctx, tx, productId); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DetachProductImage убирает изображение из галереи товара. Изображение,
+// которое больше не входит ни в одну галерею, удаляется.
+func (i *ImageRepo) DetachProductImage(ctx context.Context, productId, imageId string) error {
+	tx, err := begin(ctx, i.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = lockGalleryProduct(ctx, tx, productId); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`DELETE FROM product_image WHERE product_id = $1 AND image_id = $2`, productId, imageId,
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrImageUpdate, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking delete rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.ErrImageNotFound
+	}
+
+	if err = normalizeGallery(ctx, tx, productId); err != nil {
+		return err
+	}
+	if err = deleteOrphanImages(ctx, tx, []string{imageId}); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// lockGalleryProduct блокирует товар, чтобы правки его галереи шли по очереди.
+func lockGalleryProduct(ctx context.Context, tx DBTX, productId string) error {
+	var id string
+	err := tx.QueryRowContext(ctx,
+		`SELECT id FROM product WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, productId,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperr.ErrProductNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock product: %w", err)
+	}
+	return nil
+}
+
+func getGallery(ctx context.Context, q querier, productId string) ([]entity.ProductImage, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+imageMetaColumns+`, product_image.position, product_image.is_primary
+		FROM product_image
+		JOIN images ON images.id = product_image.image_id
+		WHERE product_image.product_id = $1
+		ORDER BY product_image.position, images.id
+	`, productId)
+	if err != nil {
+		return nil, fmt.Errorf("error getting product images: %w", err)
+	}
+	defer rows.Close()
+
+	images := make([]entity.ProductImage, 0)
+	for rows.Next() {
+		var pi entity.ProductImage
+		pi.Image, err = scanImageMeta(rows, &pi.Position, &pi.IsPrimary)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning product image: %w", err)
+		}
+		images = append(images, pi)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return images, nil
+}
+
+// normalizeGallery нумерует галерею товара подряд с нуля, назначает основным
+// первое изображение, если основного нет, и копирует его id в product.image_id.
+func normalizeGallery(ctx context.Context, ex execer, productId string) error {
+	_, err := ex.ExecContext(ctx, `
+		UPDATE product_image SET position = ordered.position
+		FROM (SELECT image_id, row_number() OVER (ORDER BY position, image_id) - 1 AS position
+		      FROM product_image WHERE product_id = $1) AS ordered
+		WHERE product_image.product_id = $1 AND product_image.image_id = ordered.image_id
+		  AND product_image.position <> ordered.position
+	`, productId)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrImageUpdate, err)
+	}
+
+	_, err = ex.ExecContext(ctx, `
+		UPDATE product_image SET is_primary = true
+		WHERE product_id = $1 AND position = 0
+		  AND NOT EXISTS (SELECT 1 FROM product_image WHERE product_id = $1 AND is_primary)
+	`, productId)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrImageUpdate, err)
+	}
+
+	_, err = ex.ExecContext(ctx, `
+		UPDATE product SET image_id = (SELECT image_id FROM product_image WHERE product_id = $1 AND is_primary)
+		WHERE id = $1
+	`, productId)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrProductUpdate, err)
+	}
+	return nil
+}
+
+// deleteOrphanImages удаляет изображения из ids, которые не входят ни в одну галерею.
+func deleteOrphanImages(ctx context.Context, ex execer, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := ex.ExecContext(ctx, `
+		DELETE FROM images WHERE id = ANY($1)
+		  AND NOT EXISTS (SELECT 1 FROM product_image WHERE product_image.image_id = images.id)
+		  AND NOT EXISTS (SELECT 1 FROM product WHERE product.image_id = images.id)
+	`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrImageDelete, err)
+	}
+	return nil
+}
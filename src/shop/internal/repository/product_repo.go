@@ -3,37 +3,45 @@ package repository
 import (
 	"backend2/internal/apperr"
 	"backend2/internal/entity"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/lib/pq"
 	"log"
+	"time"
 )
 
 type ProductRepo struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewProductRepo(db *sql.DB) *ProductRepo {
+func NewProductRepo(db DBTX) *ProductRepo {
 	return &ProductRepo{
 		db: db,
 	}
 }
 
-func (p *ProductRepo) CreateProduct(product entity.Product) (entity.Product, error) {
+func (p *ProductRepo) CreateProduct(ctx context.Context, product entity.Product, actor string) (entity.Product, error) {
+	tx, err := begin(ctx, p.db)
+	if err != nil {
+		return entity.Product{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
 	var exists bool
-	err := p.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM supplier WHERE id = $1)`, product.SupplierId).Scan(&exists)
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM supplier WHERE id = $1 AND deleted_at IS NULL)`, product.SupplierId).Scan(&exists)
 	if err != nil {
 		return entity.Product{}, fmt.Errorf("failed to check supplier existence: %w", err)
 	}
 	if !exists {
-		return entity.Product{}, fmt.Errorf("product with id %s not found", apperr.ErrSupplierNotFound)
+		return entity.Product{}, fmt.Errorf("supplier with id %s: %w", product.SupplierId, apperr.ErrSupplierNotFound)
 	}
 
 	query := `INSERT INTO product (id, name, category, supplier_id, price, available_stock, last_update_date)
 			  VALUES ($1, $2, $3, $4, $5, $6, $7)`
 
-	_, err = p.db.Exec(query,
+	_, err = tx.ExecContext(ctx, query,
 		product.Id,
 		product.Name,
 		product.Category,
@@ -42,20 +50,31 @@ func (p *ProductRepo) CreateProduct(product entity.Product) (entity.Product, err
 		product.AvailableStock,
 		product.LastUpdate,
 	)
-	log.Println(err)
 	if err != nil {
+		log.Println(err)
 		return entity.Product{}, fmt.Errorf("%w: %v", apperr.ErrProductInsert, err)
 	}
 
+	// начальный остаток тоже попадает в журнал движений
+	if product.AvailableStock != 0 {
+		err = insertStockMovement(ctx, tx, product.Id, product.AvailableStock, entity.StockReasonPurchase, actor)
+		if err != nil {
+			return entity.Product{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return entity.Product{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return product, nil
 }
 
-func (p *ProductRepo) GetProductById(id string) (entity.Product, error) {
-	query := `SELECT id, name, category, supplier_id, image_id, price, available_stock, last_update_date FROM product WHERE id = $1`
+func (p *ProductRepo) GetProductById(ctx context.Context, id string) (entity.Product, error) {
+	query := `SELECT id, name, category, supplier_id, image_id, price, available_stock, last_update_date FROM product WHERE id = $1 AND deleted_at IS NULL`
 
 	var product entity.Product
 	var imageID *string
-	err := p.db.QueryRow(query, id).Scan(
+	err := p.db.QueryRowContext(ctx, query, id).Scan(
 		&product.Id,
 		&product.Name,
 		&product.Category,
@@ -79,42 +98,126 @@ func (p *ProductRepo) GetProductById(id string) (entity.Product, error) {
 	return product, nil
 }
 
-func (p *ProductRepo) ReduceProduct(id string, count int) (entity.Product, error) {
-	query := `UPDATE product SET available_stock = available_stock - $1 WHERE id = $2`
-
-	res, err := p.db.Exec(query, count, id)
+// ReduceProduct списывает count единиц одним условным UPDATE, поэтому
+// параллельные списания не уводят остаток в минус. Движение пишется в журнал
+// в той же транзакции.
+func (p *ProductRepo) ReduceProduct(ctx context.Context, id string, count int, reason, actor string) (entity.Product, error) {
+	tx, err := begin(ctx, p.db)
 	if err != nil {
-		return entity.Product{}, fmt.Errorf("%w: %v", apperr.ErrProductUpdate, err)
+		return entity.Product{}, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := res.RowsAffected()
+	query := `
+		UPDATE product SET available_stock = available_stock - $1, last_update_date = $3
+		WHERE id = $2 AND available_stock >= $1 AND deleted_at IS NULL
+		RETURNING id, name, category, supplier_id, image_id, price, available_stock, last_update_date
+	`
+
+	var product entity.Product
+	var imageID *string
+	err = tx.QueryRowContext(ctx, query, count, id, time.Now().UTC()).Scan(
+		&product.Id,
+		&product.Name,
+		&product.Category,
+		&product.SupplierId,
+		&imageID,
+		&product.Price,
+		&product.AvailableStock,
+		&product.LastUpdate,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM product WHERE id = $1 AND deleted_at IS NULL)`, id).Scan(&exists)
+		if err != nil {
+			return entity.Product{}, fmt.Errorf("failed to check product existence: %w", err)
+		}
+		if !exists {
+			return entity.Product{}, apperr.ErrProductNotFound
+		}
+		return entity.Product{}, apperr.ErrInsufficientStock
+	}
 	if err != nil {
-		return entity.Product{}, fmt.Errorf("checking update rows: %w", err)
+		return entity.Product{}, fmt.Errorf("%w: %v", apperr.ErrProductUpdate, err)
 	}
-	if rowsAffected == 0 {
-		return entity.Product{}, apperr.ErrProductNotFound
+	if imageID != nil {
+		product.ImageId = *imageID
 	}
 
-	product, err := p.GetProductById(id)
+	err = insertStockMovement(ctx, tx, id, -count, reason, actor)
 	if err != nil {
-		return entity.Product{}, fmt.Errorf("%w: %v", apperr.ErrProductUpdate, err)
+		return entity.Product{}, err
 	}
 
+	if err = tx.Commit(); err != nil {
+		return entity.Product{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return product, nil
 }
-func (p *ProductRepo) GetProducts() ([]entity.Product, error) {
-	query := `
-		SELECT id, name, category, supplier_id, image_id, price, available_stock, last_update_date
-		FROM product
-	`
 
-	rows, err := p.db.Query(query)
+// productSortColumns — колонка и тип для каждого поля сортировки.
+var productSortColumns = map[string][2]string{
+	entity.ProductSortName:       {"name", "text"},
+	entity.ProductSortPrice:      {"price", "float8"},
+	entity.ProductSortStock:      {"available_stock", "int"},
+	entity.ProductSortLastUpdate: {"last_update_date", "timestamp"},
+}
+
+// GetProducts возвращает страницу товаров и общее число товаров под фильтром.
+// С filter.After работает keyset-пагинация: строки строго после курсора
+// в порядке (поле сортировки, id); без курсора можно листать через Offset.
+func (p *ProductRepo) GetProducts(ctx context.Context, filter entity.ProductFilter) ([]entity.Product, int, error) {
+	sort, ok := productSortColumns[filter.Sort]
+	if !ok {
+		sort = productSortColumns[entity.ProductSortName]
+	}
+	column, typ := sort[0], sort[1]
+
+	q := newSelect("id, name, category, supplier_id, image_id, price, available_stock, last_update_date", "product")
+	q.Where("deleted_at IS NULL")
+	if filter.Category != "" {
+		q.Where("category = ?", filter.Category)
+	}
+	if filter.SupplierId != "" {
+		q.Where("supplier_id = ?", filter.SupplierId)
+	}
+	if filter.MinPrice != nil {
+		q.Where("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		q.Where("price <= ?", *filter.MaxPrice)
+	}
+	if filter.InStock {
+		q.Where("available_stock > 0")
+	}
+
+	// total считается до условия курсора, чтобы не зависеть от позиции на странице
+	var total int
+	countQuery, countArgs := q.BuildCount()
+	if err := p.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count products: %w", err)
+	}
+
+	cmp, direction := ">", "ASC"
+	if filter.Desc {
+		cmp, direction = "<", "DESC"
+	}
+	if filter.After != nil {
+		q.Where(fmt.Sprintf("(%s, id) %s (?::%s, ?::uuid)", column, cmp, typ), filter.After.Value, filter.After.Id)
+	}
+
+	query, args := q.OrderBy(column+" "+direction, "id "+direction).
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Build()
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query products: %w", err)
+		return nil, 0, fmt.Errorf("failed to query products: %w", err)
 	}
 	defer rows.Close()
 
-	var products []entity.Product
+	products := make([]entity.Product, 0, filter.Limit)
 
 	for rows.Next() {
 		var product entity.Product
@@ -131,35 +234,28 @@ func (p *ProductRepo) GetProducts() ([]entity.Product, error) {
 			&product.LastUpdate,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan product row: %w", err)
+			return nil, 0, fmt.Errorf("failed to scan product row: %w", err)
 		}
 
 		if imageId != nil {
 			product.ImageId = *imageId
-		} else {
-			product.ImageId = ""
 		}
 
 		products = append(products, product)
 	}
 
-	fmt.Println(products)
-
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows iteration error: %w", err)
-	}
-
-	if len(products) == 0 {
-		return nil, apperr.ErrProductNotFound
+		return nil, 0, fmt.Errorf("rows iteration error: %w", err)
 	}
 
-	return products, nil
+	return products, total, nil
 }
 
-func (p *ProductRepo) DeleteProduct(id string) error {
-	query := `DELETE FROM product WHERE id = $1`
+// SoftDeleteProduct помечает товар удалённым; он пропадает из каталога, но может быть восстановлен.
+func (p *ProductRepo) SoftDeleteProduct(ctx context.Context, id string) error {
+	query := `UPDATE product SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`
 
-	res, err := p.db.Exec(query, id)
+	res, err := p.db.ExecContext(ctx, query, time.Now().UTC(), id)
 	if err != nil {
 		return fmt.Errorf("%w: %v", apperr.ErrProductDelete, err)
 	}
@@ -174,3 +270,103 @@ func (p *ProductRepo) DeleteProduct(id string) error {
 
 	return nil
 }
+
+// RestoreProduct снимает пометку удаления. Для неудалённого товара ничего не меняет.
+func (p *ProductRepo) RestoreProduct(ctx context.Context, id string) error {
+	res, err := p.db.ExecContext(ctx, `UPDATE product SET deleted_at = NULL WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrProductUpdate, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking update rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.ErrProductNotFound
+	}
+	return nil
+}
+
+// DeleteProduct физически удаляет товар (в том числе помеченный удалённым) вместе с
+// позициями корзин и картинкой; журнал остатков остаётся. Если товар есть в заказах или
+// закупках, без cascade возвращается *apperr.DependentsError. С cascade товар убирается из
+// отменённых заказов и ещё не принятых закупок; неотменённые заказы и принятые закупки
+// по-прежнему дают *apperr.DependentsError.
+func (p *ProductRepo) DeleteProduct(ctx context.Context, id string, cascade bool, actor string) error {
+	tx, err := begin(ctx, p.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `SELECT id FROM product WHERE id = $1 FOR UPDATE`, id).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperr.ErrProductNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock product: %w", err)
+	}
+
+	if err = deleteProduct(ctx, tx, id, cascade, actor); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// deleteProduct удаляет уже заблокированный товар внутри транзакции tx.
+func deleteProduct(ctx context.Context, tx DBTX, id string, cascade bool, actor string) error {
+	var err error
+	if cascade {
+		err = findDependents(ctx, tx, productCascadeDependents, id)
+		if err == nil {
+			err = deleteProductItems(ctx, tx, id)
+		}
+	} else {
+		err = findDependents(ctx, tx, productDependents, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM cart_item WHERE product_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrCartUpdate, err)
+	}
+
+	// галерея удаляется вместе с товаром, изображения — если они больше нигде не используются
+	var imageIds []string
+	err = tx.QueryRowContext(ctx, `
+		SELECT coalesce(array_agg(image_id::text), '{}') FROM product_image WHERE product_id = $1
+	`, id).Scan(pq.Array(&imageIds))
+	if err != nil {
+		return fmt.Errorf("failed to get product images: %w", err)
+	}
+
+	// остаток списывается, чтобы журнал, который переживает товар, сходился
+	var stock int
+	err = tx.QueryRowContext(ctx, `SELECT available_stock FROM product WHERE id = $1`, id).Scan(&stock)
+	if err != nil {
+		return fmt.Errorf("failed to get product stock: %w", err)
+	}
+	if stock > 0 {
+		if err = insertStockMovement(ctx, tx, id, -stock, entity.StockReasonWriteOff, actor); err != nil {
+			return err
+		}
+	}
+
+	var imageId sql.NullString
+	err = tx.QueryRowContext(ctx, `DELETE FROM product WHERE id = $1 RETURNING image_id`, id).Scan(&imageId)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrProductDelete, err)
+	}
+	if imageId.Valid {
+		imageIds = append(imageIds, imageId.String)
+	}
+
+	return deleteOrphanImages(ctx, tx, imageIds)
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"backend2/internal/utils"
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+// testDB подключается к базе из TEST_DATABASE_URL со схемой из db/init.sql.
+// Без переменной тесты, которым нужна база, пропускаются.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err = db.Ping(); err != nil {
+		t.Fatalf("ping database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestReduceProductConcurrent(t *testing.T) {
+	const (
+		initialStock = 30
+		quantity     = 3
+		workers      = 50
+	)
+	db := testDB(t)
+	ctx := context.Background()
+
+	id, err := utils.GenerateUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.ExecContext(ctx,
+		`INSERT INTO product (id, name, category, price, available_stock, last_update_date)
+		 VALUES ($1, 'reduce test', 'test', 1, $2, now())`, id, initialStock)
+	if err != nil {
+		t.Fatalf("insert product: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM stock_movement WHERE product_id = $1`, id)
+		db.Exec(`DELETE FROM product WHERE id = $1`, id)
+	})
+
+	repo := NewProductRepo(db)
+	var (
+		wg           sync.WaitGroup
+		mu           sync.Mutex
+		succeeded    int
+		insufficient int
+		unexpected   []error
+	)
+	start := make(chan struct{})
+	for n := 0; n < workers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			product, err := repo.ReduceProduct(ctx, id, quantity, entity.StockReasonSale, "test")
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+				if product.AvailableStock < 0 {
+					unexpected = append(unexpected, errors.New("stock went negative"))
+				}
+			case errors.Is(err, apperr.ErrInsufficientStock):
+				insufficient++
+			default:
+				unexpected = append(unexpected, err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	for _, err := range unexpected {
+		t.Errorf("unexpected result: %v", err)
+	}
+	if succeeded*quantity != initialStock {
+		t.Errorf("succeeded %d times by %d, want total %d", succeeded, quantity, initialStock)
+	}
+	if succeeded+insufficient != workers {
+		t.Errorf("succeeded %d + insufficient %d, want %d calls", succeeded, insufficient, workers)
+	}
+
+	var stock, moved int
+	err = db.QueryRowContext(ctx, `SELECT available_stock FROM product WHERE id = $1`, id).Scan(&stock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stock != 0 {
+		t.Errorf("stock = %d, want 0", stock)
+	}
+	err = db.QueryRowContext(ctx, `SELECT coalesce(sum(-delta), 0) FROM stock_movement WHERE product_id = $1`, id).Scan(&moved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != initialStock {
+		t.Errorf("stock movements total %d, want %d", moved, initialStock)
+	}
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"backend2/internal/entity"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// htmlEscaped экранирует текстовую колонку для HTML так же, как html.EscapeString.
+// ts_headline получает уже экранированный текст, поэтому HTML-разметка в подсветке —
+// только добавленные сервером <mark>.
+func htmlEscaped(column string) string {
+	return `replace(replace(replace(replace(replace(` + column +
+		`, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;')`
+}
+
+// SearchProducts ищет товары полнотекстово по названию и категории, а по названию ещё
+// и нечётко через триграммы, чтобы находились запросы с опечатками.
+// Возвращает страницу результатов по убыванию релевантности и общее число найденных.
+func (p *ProductRepo) SearchProducts(ctx context.Context, q string, limit, offset int) ([]entity.ProductSearchResult, int, error) {
+	query := `
+		WITH q AS (SELECT websearch_to_tsquery('simple', $1) AS query)
+		SELECT id, name, category, supplier_id, image_id, price, available_stock, last_update_date,
+			   ts_rank(search_vector, q.query) + word_similarity($1, name) AS rank,
+			   ts_headline('simple', ` + htmlEscaped("name") + `, q.query, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'),
+			   ts_headline('simple', ` + htmlEscaped("category") + `, q.query, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'),
+			   count(*) OVER () AS total
+		FROM product, q
+		WHERE (search_vector @@ q.query OR $1 <% name) AND deleted_at IS NULL
+		ORDER BY rank DESC, id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, q, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search products: %w", err)
+	}
+	defer rows.Close()
+
+	total := 0
+	results := make([]entity.ProductSearchResult, 0, limit)
+	for rows.Next() {
+		var res entity.ProductSearchResult
+		var imageId *string
+
+		err := rows.Scan(
+			&res.Product.Id,
+			&res.Product.Name,
+			&res.Product.Category,
+			&res.Product.SupplierId,
+			&imageId,
+			&res.Product.Price,
+			&res.Product.AvailableStock,
+			&res.Product.LastUpdate,
+			&res.Rank,
+			&res.NameHighlight,
+			&res.CategoryHighlight,
+			&total,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan product search row: %w", err)
+		}
+		if imageId != nil {
+			res.Product.ImageId = *imageId
+		}
+		results = append(results, res)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	// страница за пределами выдачи пустая, но общее число всё равно нужно
+	if len(results) == 0 && offset > 0 {
+		err = p.db.QueryRowContext(ctx,
+			`SELECT count(*) FROM product WHERE (search_vector @@ websearch_to_tsquery('simple', $1) OR $1 <% name) AND deleted_at IS NULL`, q,
+		).Scan(&total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to count products: %w", err)
+		}
+	}
+	return results, total, nil
+}
+
+// SuggestProducts возвращает названия товаров, начинающиеся с prefix, без учёта регистра.
+func (p *ProductRepo) SuggestProducts(ctx context.Context, prefix string, limit int) ([]string, error) {
+	query := `
+		SELECT DISTINCT name FROM product
+		WHERE lower(name) LIKE $1 ESCAPE '\' AND deleted_at IS NULL
+		ORDER BY name
+		LIMIT $2
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, escapeLike(strings.ToLower(prefix))+"%", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest products: %w", err)
+	}
+	defer rows.Close()
+
+	names := make([]string, 0, limit)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan product name: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return names, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike экранирует спецсимволы LIKE, чтобы пользовательский ввод искался буквально.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"sort"
+	"time"
+)
+
+type PurchaseOrderRepo struct {
+	db DBTX
+}
+
+func NewPurchaseOrderRepo(db DBTX) *PurchaseOrderRepo {
+	return &PurchaseOrderRepo{db: db}
+}
+
+func (p *PurchaseOrderRepo) CreatePurchaseOrder(ctx context.Context, order entity.PurchaseOrder) (entity.PurchaseOrder, error) {
+	tx, err := begin(ctx, p.db)
+	if err != nil {
+		return entity.PurchaseOrder{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO purchase_order (id, supplier_id, status, created_at, updated_at) VALUES ($1, $2, $3, $4, $5)`,
+		order.Id, order.SupplierId, order.Status, order.CreatedAt, order.UpdatedAt,
+	)
+	if err != nil {
+		return entity.PurchaseOrder{}, fmt.Errorf("%w: %v", apperr.ErrPurchaseOrderInsert, err)
+	}
+
+	for _, item := range order.Items {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO purchase_order_item (id, purchase_order_id, product_id, quantity, received_quantity, unit_cost)
+			 VALUES ($1, $2, $3, $4, $5, $6)`,
+			item.Id, item.PurchaseOrderId, item.ProductId, item.Quantity, item.ReceivedQuantity, item.UnitCost,
+		)
+		if err != nil {
+			return entity.PurchaseOrder{}, fmt.Errorf("%w: %v", apperr.ErrPurchaseOrderInsert, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return entity.PurchaseOrder{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return order, nil
+}
+
+func (p *PurchaseOrderRepo) GetPurchaseOrderById(ctx context.Context, id string) (entity.PurchaseOrder, error) {
+	query := `SELECT id, supplier_id, status, created_at, updated_at FROM purchase_order WHERE id = $1`
+
+	var order entity.PurchaseOrder
+	err := p.db.QueryRowContext(ctx, query, id).Scan(
+		&order.Id,
+		&order.SupplierId,
+		&order.Status,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.PurchaseOrder{}, apperr.ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return entity.PurchaseOrder{}, fmt.Errorf("error getting purchase order: %w", err)
+	}
+
+	order.Items, err = p.getPurchaseOrderItems(ctx, order.Id)
+	if err != nil {
+		return entity.PurchaseOrder{}, err
+	}
+	return order, nil
+}
+
+// GetPurchaseOrdersBySupplierId возвращает закупки поставщика в одном из статусов statuses.
+func (p *PurchaseOrderRepo) GetPurchaseOrdersBySupplierId(ctx context.Context, supplierId string, statuses []string) ([]entity.PurchaseOrder, error) {
+	query := `SELECT id, supplier_id, status, created_at, updated_at
+			  FROM purchase_order WHERE supplier_id = $1 AND status = ANY($2) ORDER BY created_at`
+
+	rows, err := p.db.QueryContext(ctx, query, supplierId, pq.Array(statuses))
+	if err != nil {
+		return nil, fmt.Errorf("error getting purchase orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := make([]entity.PurchaseOrder, 0)
+	for rows.Next() {
+		var order entity.PurchaseOrder
+		err := rows.Scan(
+			&order.Id,
+			&order.SupplierId,
+			&order.Status,
+			&order.CreatedAt,
+			&order.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning purchase order: %w", err)
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	for i := range orders {
+		orders[i].Items, err = p.getPurchaseOrderItems(ctx, orders[i].Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return orders, nil
+}
+
+func (p *PurchaseOrderRepo) getPurchaseOrderItems(ctx context.Context, orderId string) ([]entity.PurchaseOrderItem, error) {
+	query := `SELECT id, purchase_order_id, product_id, quantity, received_quantity, unit_cost
+			  FROM purchase_order_item WHERE purchase_order_id = $1 ORDER BY product_id`
+
+	rows, err := p.db.QueryContext(ctx, query, orderId)
+	if err != nil {
+		return nil, fmt.Errorf("error getting purchase order items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]entity.PurchaseOrderItem, 0)
+	for rows.Next() {
+		var item entity.PurchaseOrderItem
+		err := rows.Scan(&item.Id, &item.PurchaseOrderId, &item.ProductId, &item.Quantity, &item.ReceivedQuantity, &item.UnitCost)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning purchase order item: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return items, nil
+}
+
+// ReceivePurchaseOrder в одной транзакции принимает товар по закупке: увеличивает остатки,
+// обновляет дату последней закупки и статус закупки. received — количество по product_id,
+// пустой received означает приёмку всего, что ещё не пришло.
+func (p *PurchaseOrderRepo) ReceivePurchaseOrder(ctx context.Context, id string, received map[string]int, actor string) error {
+	tx, err := begin(ctx, p.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx, `SELECT status FROM purchase_order WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperr.ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock purchase order: %w", err)
+	}
+	if status != entity.PurchaseOrderStatusOpen && status != entity.PurchaseOrderStatusPartiallyReceived {
+		return apperr.ErrPurchaseOrderClosed
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, product_id, quantity, received_quantity FROM purchase_order_item WHERE purchase_order_id = $1`, id,
+	)
+	if err != nil {
+		return fmt.Errorf("error getting purchase order items: %w", err)
+	}
+	items := make(map[string]entity.PurchaseOrderItem)
+	for rows.Next() {
+		var item entity.PurchaseOrderItem
+		if err := rows.Scan(&item.Id, &item.ProductId, &item.Quantity, &item.ReceivedQuantity); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning purchase order item: %w", err)
+		}
+		items[item.ProductId] = item
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	if len(received) == 0 {
+		received = make(map[string]int, len(items))
+		for productId, item := range items {
+			if remaining := item.Quantity - item.ReceivedQuantity; remaining > 0 {
+				received[productId] = remaining
+			}
+		}
+	}
+
+	// товары обновляются в порядке id, как и при оформлении заказа
+	productIds := make([]string, 0, len(received))
+	for productId := range received {
+		productIds = append(productIds, productId)
+	}
+	sort.Strings(productIds)
+
+	now := time.Now().UTC()
+	for _, productId := range productIds {
+		quantity := received[productId]
+		item, ok := items[productId]
+		if !ok {
+			return fmt.Errorf("product %s: %w", productId, apperr.ErrPurchaseOrderItemNotFound)
+		}
+		if item.ReceivedQuantity+quantity > item.Quantity {
+			return fmt.Errorf("product %s: %w", productId, apperr.ErrPurchaseOrderOverReceive)
+		}
+
+		res, err := tx.ExecContext(ctx,
+			`UPDATE product SET available_stock = available_stock + $1, last_update_date = $2 WHERE id = $3`,
+			quantity, now, productId,
+		)
+		if err != nil {
+			return fmt.Errorf("%w: %v", apperr.ErrProductUpdate, err)
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("checking update rows: %w", err)
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("product %s: %w", productId, apperr.ErrProductNotFound)
+		}
+		err = insertStockMovement(ctx, tx, productId, quantity, entity.StockReasonPurchase, actor)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE purchase_order_item SET received_quantity = received_quantity + $1 WHERE id = $2`,
+			quantity, item.Id,
+		)
+		if err != nil {
+			return fmt.Errorf("%w: %v", apperr.ErrPurchaseOrderUpdate, err)
+		}
+		item.ReceivedQuantity += quantity
+		items[productId] = item
+	}
+
+	status = entity.PurchaseOrderStatusReceived
+	for _, item := range items {
+		if item.ReceivedQuantity < item.Quantity {
+			status = entity.PurchaseOrderStatusPartiallyReceived
+			break
+		}
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE purchase_order SET status = $1, updated_at = $2 WHERE id = $3`, status, now, id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrPurchaseOrderUpdate, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// CancelPurchaseOrder закрывает закупку; уже принятый товар остаётся на складе.
+func (p *PurchaseOrderRepo) CancelPurchaseOrder(ctx context.Context, id string) error {
+	query := `UPDATE purchase_order SET status = $1, updated_at = $2 WHERE id = $3 AND status = ANY($4)`
+
+	open := []string{entity.PurchaseOrderStatusOpen, entity.PurchaseOrderStatusPartiallyReceived}
+	res, err := p.db.ExecContext(ctx, query, entity.PurchaseOrderStatusCancelled, time.Now().UTC(), id, pq.Array(open))
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrPurchaseOrderUpdate, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking update rows: %w", err)
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	var exists bool
+	err = p.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM purchase_order WHERE id = $1)`, id).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check purchase order existence: %w", err)
+	}
+	if !exists {
+		return apperr.ErrPurchaseOrderNotFound
+	}
+	return apperr.ErrPurchaseOrderClosed
+}
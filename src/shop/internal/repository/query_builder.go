@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+)
+
+// selectQuery собирает SELECT из фиксированных частей и условий. Значения попадают
+// в запрос только через плейсхолдеры, а имена колонок и выражения сортировки
+// передаются из кода репозиториев, не из пользовательского ввода.
+type selectQuery struct {
+	columns string
+	from    string
+	where   []string
+	args    []any
+	orderBy []string
+	limit   int
+	offset  int
+}
+
+func newSelect(columns, from string) *selectQuery {
+	return &selectQuery{columns: columns, from: from}
+}
+
+// Where добавляет условие через AND. Каждый ? в cond заменяется на очередной $n.
+func (q *selectQuery) Where(cond string, args ...any) *selectQuery {
+	if strings.Count(cond, "?") != len(args) {
+		panic(fmt.Sprintf("query builder: %q expects %d args, got %d", cond, strings.Count(cond, "?"), len(args)))
+	}
+
+	var b strings.Builder
+	for _, part := range strings.SplitAfter(cond, "?") {
+		if !strings.HasSuffix(part, "?") {
+			b.WriteString(part)
+			continue
+		}
+		b.WriteString(strings.TrimSuffix(part, "?"))
+		b.WriteString(q.placeholder(args[0]))
+		args = args[1:]
+	}
+	q.where = append(q.where, b.String())
+	return q
+}
+
+func (q *selectQuery) OrderBy(exprs ...string) *selectQuery {
+	q.orderBy = append(q.orderBy, exprs...)
+	return q
+}
+
+// Limit ограничивает выборку; 0 — без ограничения.
+func (q *selectQuery) Limit(n int) *selectQuery {
+	q.limit = n
+	return q
+}
+
+func (q *selectQuery) Offset(n int) *selectQuery {
+	q.offset = n
+	return q
+}
+
+// Build возвращает запрос страницы с сортировкой, LIMIT и OFFSET.
+func (q *selectQuery) Build() (string, []any) {
+	args := make([]any, len(q.args), len(q.args)+2)
+	copy(args, q.args)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var b strings.Builder
+	b.WriteString("SELECT " + q.columns + " FROM " + q.from)
+	q.writeWhere(&b)
+	if len(q.orderBy) > 0 {
+		b.WriteString(" ORDER BY " + strings.Join(q.orderBy, ", "))
+	}
+	if q.limit > 0 {
+		b.WriteString(" LIMIT " + arg(q.limit))
+	}
+	if q.offset > 0 {
+		b.WriteString(" OFFSET " + arg(q.offset))
+	}
+	return b.String(), args
+}
+
+// BuildCount возвращает запрос числа строк под теми же условиями, без страницы.
+func (q *selectQuery) BuildCount() (string, []any) {
+	var b strings.Builder
+	b.WriteString("SELECT count(*) FROM " + q.from)
+	q.writeWhere(&b)
+	return b.String(), q.args
+}
+
+func (q *selectQuery) writeWhere(b *strings.Builder) {
+	if len(q.where) > 0 {
+		b.WriteString(" WHERE " + strings.Join(q.where, " AND "))
+	}
+}
+
+func (q *selectQuery) placeholder(v any) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", len(q.args))
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"backend2/internal/utils"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// execer — общее у *sql.DB и *sql.Tx, чтобы движение склада писалось
+// в той же транзакции, что и изменение остатка.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func insertStockMovement(ctx context.Context, ex execer, productId string, delta int, reason, actor string) error {
+	id, err := utils.GenerateUUID()
+	if err != nil {
+		return fmt.Errorf("failed to generate stock movement id: %w", err)
+	}
+
+	query := `INSERT INTO stock_movement (id, product_id, delta, reason, actor, created_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err = ex.ExecContext(ctx, query, id, productId, delta, reason, actor, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert stock movement: %w", err)
+	}
+	return nil
+}
+
+type StockMovementRepo struct {
+	db DBTX
+}
+
+func NewStockMovementRepo(db DBTX) *StockMovementRepo {
+	return &StockMovementRepo{db: db}
+}
+
+// GetMovements возвращает движения товара за период. Нулевые from и to не ограничивают выборку.
+func (s *StockMovementRepo) GetMovements(ctx context.Context, productId string, from, to time.Time) ([]entity.StockMovement, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM product WHERE id = $1)`, productId).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check product existence: %w", err)
+	}
+	if !exists {
+		return nil, apperr.ErrProductNotFound
+	}
+
+	query := `
+		SELECT id, product_id, delta, reason, actor, created_at
+		FROM stock_movement
+		WHERE product_id = $1
+		  AND ($2::timestamp IS NULL OR created_at >= $2)
+		  AND ($3::timestamp IS NULL OR created_at < $3)
+		ORDER BY created_at
+	`
+	rows, err := s.db.QueryContext(ctx, query, productId, nullTime(from), nullTime(to))
+	if err != nil {
+		return nil, fmt.Errorf("error getting stock movements: %w", err)
+	}
+	defer rows.Close()
+
+	movements := make([]entity.StockMovement, 0)
+	for rows.Next() {
+		var m entity.StockMovement
+		err := rows.Scan(&m.Id, &m.ProductId, &m.Delta, &m.Reason, &m.Actor, &m.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning stock movement: %w", err)
+		}
+		movements = append(movements, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return movements, nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
@@ -3,36 +3,38 @@ package repository
 import (
 	"backend2/internal/apperr"
 	"backend2/internal/entity"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 )
 
 type SupplierRepo struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewSupplier(db *sql.DB) *SupplierRepo {
+func NewSupplier(db DBTX) *SupplierRepo {
 	return &SupplierRepo{db: db}
 }
 
-func (s *SupplierRepo) CreateSupplier(supplier entity.Supplier) (entity.Supplier, error) {
+func (s *SupplierRepo) CreateSupplier(ctx context.Context, supplier entity.Supplier) (entity.Supplier, error) {
 	query := `INSERT INTO supplier (id, name, address_id, phone_number) VALUES ($1, $2, $3, $4)`
 
-	_, err := s.db.Exec(query, supplier.Id, supplier.Name, supplier.AddressId, supplier.PhoneNumber)
+	_, err := s.db.ExecContext(ctx, query, supplier.Id, supplier.Name, supplier.AddressId, supplier.PhoneNumber)
 	if err != nil {
 		return entity.Supplier{}, fmt.Errorf("%w: %v", apperr.ErrSupplierInsert, err)
 	}
 	return supplier, nil
 }
 
-func (s *SupplierRepo) GetSupplierById(id string) (entity.Supplier, error) {
+func (s *SupplierRepo) GetSupplierById(ctx context.Context, id string) (entity.Supplier, error) {
 	query := `SELECT supplier.id, name, address_id, phone_number,address.id as id, address.country as country, address.city as city, address.street as street
 				FROM supplier 
 				inner join address  on address.id = supplier.address_id 
-				WHERE supplier.id = $1`
+				WHERE supplier.id = $1 AND supplier.deleted_at IS NULL`
 	var supplier entity.Supplier
-	err := s.db.QueryRow(query, id).Scan(
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
 		&supplier.Id,
 		&supplier.Name,
 		&supplier.AddressId,
@@ -51,47 +53,64 @@ func (s *SupplierRepo) GetSupplierById(id string) (entity.Supplier, error) {
 	return supplier, nil
 }
 
-func (s *SupplierRepo) UpdateSupplier(id string, supplier entity.Supplier) (entity.Supplier, error) {
-
-	query := `
-		UPDATE address
-		SET country = $1, city = $2, street = $3
-		WHERE id = (
-			SELECT address_id FROM supplier WHERE id = $4
-		)
-	`
-	res, err := s.db.Exec(
-		query,
-		supplier.Address.Country,
-		supplier.Address.City,
-		supplier.Address.Street,
-		id,
-	)
+// UpdateSupplier применяет patch к поставщику и его адресу в одной транзакции.
+func (s *SupplierRepo) UpdateSupplier(ctx context.Context, id string, patch entity.SupplierPatch) error {
+	tx, err := begin(ctx, s.db)
 	if err != nil {
-		return entity.Supplier{}, fmt.Errorf("%w: %v", apperr.ErrSupplierUpdate, err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		return entity.Supplier{}, fmt.Errorf("checking update rows: %w", err)
+	var addressId sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT address_id FROM supplier WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&addressId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperr.ErrSupplierNotFound
 	}
-	if rowsAffected == 0 {
-		return entity.Supplier{}, apperr.ErrSupplierNotFound
+	if err != nil {
+		return fmt.Errorf("failed to lock supplier: %w", err)
 	}
 
-	updatedSupplier, err := s.GetSupplierById(id)
+	supplierSet := make(map[string]any)
+	if patch.Name != nil {
+		supplierSet["name"] = *patch.Name
+	}
+	if patch.PhoneNumber != nil {
+		supplierSet["phone_number"] = *patch.PhoneNumber
+	}
+	if err = updateColumns(ctx, tx, "supplier", id, supplierSet); err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrSupplierUpdate, err)
+	}
 
-	if err != nil {
-		return entity.Supplier{}, fmt.Errorf("error getting updated supplier: %w", err)
+	addressSet := make(map[string]any)
+	if patch.Country != nil {
+		addressSet["country"] = *patch.Country
+	}
+	if patch.City != nil {
+		addressSet["city"] = *patch.City
+	}
+	if patch.Street != nil {
+		addressSet["street"] = *patch.Street
+	}
+	if len(addressSet) > 0 {
+		if !addressId.Valid {
+			return fmt.Errorf("supplier %s has no address: %w", id, apperr.ErrAddressNotFound)
+		}
+		if err = updateColumns(ctx, tx, "address", addressId.String, addressSet); err != nil {
+			return fmt.Errorf("%w: %v", apperr.ErrSupplierUpdate, err)
+		}
 	}
 
-	return updatedSupplier, nil
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
 }
 
-func (s *SupplierRepo) DeleteSupplierById(id string) error {
-	query := `DELETE FROM supplier WHERE id = $1`
+// SoftDeleteSupplier помечает поставщика удалённым; он пропадает из выборок, но может быть восстановлен.
+func (s *SupplierRepo) SoftDeleteSupplier(ctx context.Context, id string) error {
+	query := `UPDATE supplier SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`
 
-	res, err := s.db.Exec(query, id)
+	res, err := s.db.ExecContext(ctx, query, time.Now().UTC(), id)
 	if err != nil {
 		return fmt.Errorf("%w: %v", apperr.ErrSupplierDelete, err)
 	}
@@ -107,30 +126,140 @@ func (s *SupplierRepo) DeleteSupplierById(id string) error {
 	return nil
 }
 
-func (s *SupplierRepo) GetAllSuppliers() ([]entity.Supplier, error) {
-	query := `SELECT supplier.id, name, address_id, phone_number,address.id as id, address.country as country, address.city as city, address.street as street
-				FROM supplier 
-				inner join address  on address.id = supplier.address_id`
+// RestoreSupplier снимает пометку удаления. Для неудалённого поставщика ничего не меняет.
+func (s *SupplierRepo) RestoreSupplier(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE supplier SET deleted_at = NULL WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperr.ErrSupplierUpdate, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("checking update rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return apperr.ErrSupplierNotFound
+	}
+	return nil
+}
+
+// DeleteSupplierById физически удаляет поставщика (в том числе помеченного удалённым)
+// и его адрес. Если у поставщика есть товары или закупки, без cascade возвращается
+// *apperr.DependentsError, с cascade удаляются его закупки и товары со всеми их зависимостями.
+// Закупки, по которым уже что-то принято, и товары из неотменённых заказов не удаляются и при cascade.
+func (s *SupplierRepo) DeleteSupplierById(ctx context.Context, id string, cascade bool, actor string) error {
+	tx, err := begin(ctx, s.db)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var addressId sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT address_id FROM supplier WHERE id = $1 FOR UPDATE`, id).Scan(&addressId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperr.ErrSupplierNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock supplier: %w", err)
+	}
+
+	if !cascade {
+		if err = findDependents(ctx, tx, supplierDependents, id); err != nil {
+			return err
+		}
+	} else {
+		if err = findDependents(ctx, tx, supplierReceivedDependents, id); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM purchase_order WHERE supplier_id = $1`, id)
+		if err != nil {
+			return fmt.Errorf("%w: %v", apperr.ErrPurchaseOrderUpdate, err)
+		}
+
+		// товары блокируются в порядке id, как и при оформлении заказа
+		rows, err := tx.QueryContext(ctx, `SELECT id FROM product WHERE supplier_id = $1 ORDER BY id FOR UPDATE`, id)
+		if err != nil {
+			return fmt.Errorf("failed to lock supplier products: %w", err)
+		}
+		productIds := make([]string, 0)
+		for rows.Next() {
+			var productId string
+			if err := rows.Scan(&productId); err != nil {
+				rows.Close()
+				return fmt.Errorf("error scanning product: %w", err)
+			}
+			productIds = append(productIds, productId)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("rows iteration error: %w", err)
+		}
+
+		for _, productId := range productIds {
+			if err := deleteProduct(ctx, tx, productId, true, actor); err != nil {
+				return fmt.Errorf("product %s: %w", productId, err)
+			}
+		}
+	}
 
-	rows, err := s.db.Query(query)
+	_, err = tx.ExecContext(ctx, `DELETE FROM supplier WHERE id = $1`, id)
 	if err != nil {
-		return nil, fmt.Errorf("error getting suppliers: %w", err)
+		return fmt.Errorf("%w: %v", apperr.ErrSupplierDelete, err)
+	}
+	if addressId.Valid {
+		if err = deleteOrphanAddresses(ctx, tx, []string{addressId.String}); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetAllSuppliers возвращает страницу поставщиков по фильтру и общее число найденных.
+func (s *SupplierRepo) GetAllSuppliers(ctx context.Context, filter entity.SupplierFilter) ([]entity.Supplier, int, error) {
+	q := newSelect(
+		`supplier.id, name, address_id, phone_number,address.id as id, address.country as country, address.city as city, address.street as street`,
+		"supplier inner join address on address.id = supplier.address_id",
+	)
+	q.Where("supplier.deleted_at IS NULL")
+	if filter.Name != "" {
+		q.Where("name ILIKE ?", "%"+escapeLike(filter.Name)+"%")
+	}
+	if filter.Country != "" {
+		q.Where("lower(address.country) = lower(?)", filter.Country)
+	}
+	if filter.City != "" {
+		q.Where("lower(address.city) = lower(?)", filter.City)
+	}
+
+	var total int
+	countQuery, countArgs := q.BuildCount()
+	if err := s.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting suppliers: %w", err)
+	}
+
+	query, args := q.OrderBy("name", "supplier.id").Limit(filter.Limit).Offset(filter.Offset).Build()
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting suppliers: %w", err)
 	}
 	defer rows.Close()
 
-	var suppliers []entity.Supplier
+	suppliers := make([]entity.Supplier, 0)
 	for rows.Next() {
 		var supplier entity.Supplier
 		err := rows.Scan(&supplier.Id, &supplier.Name, &supplier.AddressId, &supplier.PhoneNumber, &supplier.Address.ID, &supplier.Address.Country, &supplier.Address.City, &supplier.Address.Street)
 		if err != nil {
-			return nil, fmt.Errorf("error scanning supplier: %w", err)
+			return nil, 0, fmt.Errorf("error scanning supplier: %w", err)
 		}
 		suppliers = append(suppliers, supplier)
 	}
-
-	if len(suppliers) == 0 {
-		return nil, apperr.ErrSupplierNotFound
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows iteration error: %w", err)
 	}
 
-	return suppliers, nil
+	return suppliers, total, nil
 }
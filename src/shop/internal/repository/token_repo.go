@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// TokenRepo хранит выданные токены в Postgres. В таблицу пишется только
+// sha256 от токена, поэтому утечка таблицы не даёт рабочих токенов.
+type TokenRepo struct {
+	db DBTX
+}
+
+func NewTokenRepo(db DBTX) *TokenRepo {
+	return &TokenRepo{db: db}
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (t *TokenRepo) Save(ctx context.Context, token entity.Token) error {
+	query := `INSERT INTO auth_token (token_hash, kind, user_id, family_id, expires_at, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := t.db.ExecContext(ctx, query,
+		hashToken(token.Token),
+		token.Kind,
+		token.UserID,
+		token.FamilyID,
+		token.ExpiresAt.UTC(),
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("error saving token: %w", err)
+	}
+	return nil
+}
+
+func (t *TokenRepo) IsValid(ctx context.Context, token string) bool {
+	_, ok := t.GetUserID(ctx, token)
+	return ok
+}
+
+func (t *TokenRepo) GetUserID(ctx context.Context, token string) (string, bool) {
+	query := `SELECT user_id FROM auth_token WHERE token_hash = $1 AND kind = $2 AND expires_at > $3`
+
+	var userID string
+	err := t.db.QueryRowContext(ctx, query, hashToken(token), entity.TokenKindAccess, time.Now().UTC()).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false
+	}
+	if err != nil {
+		log.Printf("error getting token: %v", err)
+		return "", false
+	}
+	return userID, true
+}
+
+func (t *TokenRepo) Delete(ctx context.Context, token string) error {
+	_, err := t.db.ExecContext(ctx, `DELETE FROM auth_token WHERE token_hash = $1`, hashToken(token))
+	if err != nil {
+		return fmt.Errorf("error deleting token: %w", err)
+	}
+	return nil
+}
+
+func (t *TokenRepo) DeleteFamily(ctx context.Context, familyID string) error {
+	_, err := t.db.ExecContext(ctx, `DELETE FROM auth_token WHERE family_id = $1`, familyID)
+	if err != nil {
+		return fmt.Errorf("error deleting token family: %w", err)
+	}
+	return nil
+}
+
+func (t *TokenRepo) DeleteByUserID(ctx context.Context, userID string) error {
+	_, err := t.db.ExecContext(ctx, `DELETE FROM auth_token WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("error deleting user tokens: %w", err)
+	}
+	return nil
+}
+
+func (t *TokenRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := t.db.ExecContext(ctx, `DELETE FROM auth_token WHERE expires_at <= $1`, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired tokens: %w", err)
+	}
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("checking delete rows: %w", err)
+	}
+	return removed, nil
+}
+
+func (t *TokenRepo) UseRefreshToken(ctx context.Context, token string) (entity.Token, error) {
+	now := time.Now().UTC()
+	hash := hashToken(token)
+
+	query := `
+		UPDATE auth_token SET used_at = $1
+		WHERE token_hash = $2 AND kind = $3 AND used_at IS NULL AND expires_at > $1
+		RETURNING user_id, family_id, expires_at
+	`
+	used := entity.Token{Token: token, Kind: entity.TokenKindRefresh, UsedAt: &now}
+	err := t.db.QueryRowContext(ctx, query, now, hash, entity.TokenKindRefresh).Scan(&used.UserID, &used.FamilyID, &used.ExpiresAt)
+	if err == nil {
+		return used, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return entity.Token{}, fmt.Errorf("error using refresh token: %w", err)
+	}
+
+	// токен не обновился: либо его нет, либо он истёк, либо уже был использован
+	var stored entity.Token
+	err = t.db.QueryRowContext(ctx,
+		`SELECT user_id, family_id, expires_at, used_at FROM auth_token WHERE token_hash = $1 AND kind = $2`,
+		hash, entity.TokenKindRefresh,
+	).Scan(&stored.UserID, &stored.FamilyID, &stored.ExpiresAt, &stored.UsedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.Token{}, apperr.ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return entity.Token{}, fmt.Errorf("error getting refresh token: %w", err)
+	}
+	if stored.UsedAt != nil {
+		stored.Token = token
+		stored.Kind = entity.TokenKindRefresh
+		return stored, apperr.ErrRefreshTokenReused
+	}
+	return entity.Token{}, apperr.ErrRefreshTokenInvalid
+}
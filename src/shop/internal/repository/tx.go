@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"backend2/internal/usecases"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// DBTX — общее у *sql.DB и *sql.Tx. Репозиторий, созданный на *sql.Tx,
+// выполняет все запросы внутри этой транзакции.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// transaction — транзакция, которую открывает метод репозитория.
+type transaction interface {
+	DBTX
+	Commit() error
+	Rollback() error
+}
+
+// begin открывает транзакцию на db. Если db уже транзакция, запросы идут в неё,
+// а Commit и Rollback остаются за тем, кто её открыл.
+func begin(ctx context.Context, db DBTX) (transaction, error) {
+	switch db := db.(type) {
+	case *sql.DB:
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		return tx, nil
+	case *sql.Tx:
+		return outerTx{db}, nil
+	default:
+		return nil, fmt.Errorf("unsupported executor %T", db)
+	}
+}
+
+// outerTx — чужая транзакция, которой метод репозитория не управляет.
+type outerTx struct {
+	*sql.Tx
+}
+
+func (outerTx) Commit() error   { return nil }
+func (outerTx) Rollback() error { return nil }
+
+// TxManager выполняет несколько операций репозиториев в одной транзакции.
+type TxManager struct {
+	db    *sql.DB
+	blobs BlobStore
+}
+
+func NewTxManager(db *sql.DB, blobs BlobStore) *TxManager {
+	return &TxManager{db: db, blobs: blobs}
+}
+
+// WithinTx вызывает fn с репозиториями на общей транзакции. Транзакция
+// коммитится, если fn вернула nil, иначе откатывается.
+func (m *TxManager) WithinTx(ctx context.Context, fn func(tx usecases.Tx) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = fn(txRepos{tx: tx, blobs: m.blobs}); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// txRepos — репозитории, привязанные к одной транзакции.
+type txRepos struct {
+	tx    *sql.Tx
+	blobs BlobStore
+}
+
+func (r txRepos) Addresses() usecases.AddressRepo {
+	return NewAddressRepo(r.tx)
+}
+
+func (r txRepos) Clients() usecases.ClientRepository {
+	return NewClientRepo(r.tx)
+}
+
+func (r txRepos) Suppliers() usecases.SupplierRepository {
+	return NewSupplier(r.tx)
+}
+
+func (r txRepos) Products() usecases.ProductRepository {
+	return NewProductRepo(r.tx)
+}
+
+func (r txRepos) Images() usecases.ImageRepo {
+	return NewImageRepo(r.tx, r.blobs)
+}
+
+func (r txRepos) Orders() usecases.OrderRepository {
+	return NewOrderRepo(r.tx)
+}
+
+func (r txRepos) Carts() usecases.CartRepository {
+	return NewCartRepo(r.tx)
+}
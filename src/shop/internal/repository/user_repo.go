@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+)
+
+type UserRepo struct {
+	db DBTX
+}
+
+func NewUserRepo(db DBTX) *UserRepo {
+	return &UserRepo{db: db}
+}
+
+func (u *UserRepo) CreateUser(ctx context.Context, user entity.User) (entity.User, error) {
+	query := `INSERT INTO users (id, login, password_hash, role, created_at) VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := u.db.ExecContext(ctx, query, user.Id, user.Login, user.PasswordHash, user.Role, user.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return entity.User{}, apperr.ErrUserExists
+		}
+		return entity.User{}, fmt.Errorf("%w: %v", apperr.ErrUserInsert, err)
+	}
+	return user, nil
+}
+
+func (u *UserRepo) GetUserByLogin(ctx context.Context, login string) (entity.User, error) {
+	query := `SELECT id, login, password_hash, role, created_at FROM users WHERE login = $1`
+
+	var user entity.User
+	err := u.db.QueryRowContext(ctx, query, login).Scan(&user.Id, &user.Login, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.User{}, apperr.ErrUserNotFound
+	}
+	if err != nil {
+		return entity.User{}, fmt.Errorf("error getting user: %w", err)
+	}
+	return user, nil
+}
+
+func (u *UserRepo) GetUserById(ctx context.Context, id string) (entity.User, error) {
+	query := `SELECT id, login, password_hash, role, created_at FROM users WHERE id = $1`
+
+	var user entity.User
+	err := u.db.QueryRowContext(ctx, query, id).Scan(&user.Id, &user.Login, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.User{}, apperr.ErrUserNotFound
+	}
+	if err != nil {
+		return entity.User{}, fmt.Errorf("error getting user: %w", err)
+	}
+	return user, nil
+}
@@ -1,15 +1,115 @@
 package usecases
 
-import "backend2/internal/entity"
+import (
+	"backend2/internal/entity"
+	"backend2/internal/utils"
+	"context"
+	"fmt"
+	"time"
+)
 
 type AddressRepo interface {
-	Save(address entity.Address) (entity.Address, error)
+	Save(ctx context.Context, address entity.Address) (entity.Address, error)
 
-	Update(address entity.Address) (entity.Address, error)
-	Delete(address entity.Address) error
-	GetById(address entity.Address) (entity.Address, error)
+	Update(ctx context.Context, address entity.Address) (entity.Address, error)
+	Delete(ctx context.Context, address entity.Address) error
+	GetById(ctx context.Context, address entity.Address) (entity.Address, error)
+	// Добавить сохранённый адрес в адресную книгу клиента
+	LinkClientAddress(ctx context.Context, ca entity.ClientAddress) error
 }
 
-//TODO:
-// 1) проверка существования
-// 2) автоматическое добавление
+type ClientAddressRepository interface {
+	GetClientAddresses(ctx context.Context, clientId string) ([]entity.ClientAddress, error)
+	GetClientAddress(ctx context.Context, clientId, id string) (entity.ClientAddress, error)
+	AddClientAddress(ctx context.Context, ca entity.ClientAddress) (entity.ClientAddress, error)
+	UpdateClientAddress(ctx context.Context, ca entity.ClientAddress) (entity.ClientAddress, error)
+	DeleteClientAddress(ctx context.Context, clientId, id string) error
+}
+
+// ClientAddress — адресная книга клиента.
+type ClientAddress struct {
+	repo    ClientAddressRepository
+	clients ClientRepository
+}
+
+func NewClientAddress(repo ClientAddressRepository, clients ClientRepository) *ClientAddress {
+	return &ClientAddress{repo: repo, clients: clients}
+}
+
+func (c *ClientAddress) GetAddresses(ctx context.Context, clientId string) ([]entity.ClientAddress, error) {
+	_, err := c.clients.GetClientById(ctx, clientId)
+	if err != nil {
+		return nil, fmt.Errorf("usecase: failed to get client: %w", err)
+	}
+
+	addresses, err := c.repo.GetClientAddresses(ctx, clientId)
+	if err != nil {
+		return nil, fmt.Errorf("usecase: failed to get addresses: %w", err)
+	}
+	return addresses, nil
+}
+
+func (c *ClientAddress) GetAddress(ctx context.Context, clientId, id string) (entity.ClientAddress, error) {
+	ca, err := c.repo.GetClientAddress(ctx, clientId, id)
+	if err != nil {
+		return entity.ClientAddress{}, fmt.Errorf("usecase: failed to get address: %w", err)
+	}
+	return ca, nil
+}
+
+// AddAddress добавляет адрес клиенту. Первый адрес клиента становится адресом
+// доставки и платёжным по умолчанию.
+func (c *ClientAddress) AddAddress(ctx context.Context, clientId string, ca entity.ClientAddress) (entity.ClientAddress, error) {
+	_, err := c.clients.GetClientById(ctx, clientId)
+	if err != nil {
+		return entity.ClientAddress{}, fmt.Errorf("usecase: failed to get client: %w", err)
+	}
+
+	existing, err := c.repo.GetClientAddresses(ctx, clientId)
+	if err != nil {
+		return entity.ClientAddress{}, fmt.Errorf("usecase: failed to get addresses: %w", err)
+	}
+	if len(existing) == 0 {
+		ca.IsDefaultShipping = true
+		ca.IsDefaultBilling = true
+	}
+
+	id, err := utils.GenerateUUID()
+	if err != nil {
+		return entity.ClientAddress{}, fmt.Errorf("usecase: generate client address id: %w", err)
+	}
+	addrId, err := utils.GenerateUUID()
+	if err != nil {
+		return entity.ClientAddress{}, fmt.Errorf("usecase: generate address id: %w", err)
+	}
+
+	ca.Id = id
+	ca.ClientId = clientId
+	ca.Address.ID = addrId
+	ca.CreatedAt = time.Now().UTC()
+
+	res, err := c.repo.AddClientAddress(ctx, ca)
+	if err != nil {
+		return entity.ClientAddress{}, fmt.Errorf("usecase: failed to add address: %w", err)
+	}
+	return res, nil
+}
+
+func (c *ClientAddress) UpdateAddress(ctx context.Context, clientId, id string, ca entity.ClientAddress) (entity.ClientAddress, error) {
+	ca.Id = id
+	ca.ClientId = clientId
+
+	res, err := c.repo.UpdateClientAddress(ctx, ca)
+	if err != nil {
+		return entity.ClientAddress{}, fmt.Errorf("usecase: failed to update address: %w", err)
+	}
+	return res, nil
+}
+
+func (c *ClientAddress) DeleteAddress(ctx context.Context, clientId, id string) error {
+	err := c.repo.DeleteClientAddress(ctx, clientId, id)
+	if err != nil {
+		return fmt.Errorf("usecase: failed to delete address: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package usecases
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type CartRepository interface {
+	GetCartItems(ctx context.Context, clientId string) ([]entity.CartItem, error)
+	AddCartItem(ctx context.Context, item entity.CartItem) error
+	UpdateCartItem(ctx context.Context, clientId, productId string, quantity int) error
+	DeleteCartItem(ctx context.Context, clientId, productId string) error
+	ClearCart(ctx context.Context, clientId string) error
+}
+
+type Cart struct {
+	repo     CartRepository
+	products ProductRepository
+	clients  ClientRepository
+	tx       TxManager
+}
+
+func NewCart(repo CartRepository, products ProductRepository, clients ClientRepository, tx TxManager) *Cart {
+	return &Cart{repo: repo, products: products, clients: clients, tx: tx}
+}
+
+// GetCart собирает корзину с актуальными ценами. Удалённые товары и товары,
+// которых на складе меньше, чем в корзине, помечаются, а не выбрасываются.
+func (c *Cart) GetCart(ctx context.Context, clientId string) (entity.Cart, error) {
+	_, err := c.clients.GetClientById(ctx, clientId)
+	if err != nil {
+		return entity.Cart{}, fmt.Errorf("usecase: failed to get client: %w", err)
+	}
+
+	items, err := c.repo.GetCartItems(ctx, clientId)
+	if err != nil {
+		return entity.Cart{}, fmt.Errorf("usecase: failed to get cart: %w", err)
+	}
+
+	cart := entity.Cart{
+		ClientId:    clientId,
+		Lines:       make([]entity.CartLine, 0, len(items)),
+		CanCheckout: len(items) > 0,
+	}
+	for _, item := range items {
+		line := entity.CartLine{
+			ProductId: item.ProductId,
+			Quantity:  item.Quantity,
+		}
+
+		product, err := c.products.GetProductById(ctx, item.ProductId)
+		switch {
+		case errors.Is(err, apperr.ErrProductNotFound):
+			line.Problem = entity.CartProblemProductDeleted
+		case err != nil:
+			return entity.Cart{}, fmt.Errorf("usecase: failed to get cart product: %w", err)
+		default:
+			line.Name = product.Name
+			line.UnitPrice = product.Price
+			line.AvailableStock = product.AvailableStock
+			line.LineTotal = product.Price * float64(item.Quantity)
+			if product.AvailableStock < item.Quantity {
+				line.Problem = entity.CartProblemInsufficientStock
+			}
+		}
+
+		if line.Problem != "" {
+			cart.CanCheckout = false
+		} else {
+			cart.Total += line.LineTotal
+		}
+		cart.Lines = append(cart.Lines, line)
+	}
+	return cart, nil
+}
+
+func (c *Cart) AddItem(ctx context.Context, clientId, productId string, quantity int) (entity.Cart, error) {
+	_, err := c.clients.GetClientById(ctx, clientId)
+	if err != nil {
+		return entity.Cart{}, fmt.Errorf("usecase: failed to get client: %w", err)
+	}
+
+	_, err = c.products.GetProductById(ctx, productId)
+	if err != nil {
+		return entity.Cart{}, fmt.Errorf("usecase: failed to get product: %w", err)
+	}
+
+	err = c.repo.AddCartItem(ctx, entity.CartItem{
+		ClientId:  clientId,
+		ProductId: productId,
+		Quantity:  quantity,
+		AddedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		return entity.Cart{}, fmt.Errorf("usecase: failed to add cart item: %w", err)
+	}
+	return c.GetCart(ctx, clientId)
+}
+
+func (c *Cart) UpdateItem(ctx context.Context, clientId, productId string, quantity int) (entity.Cart, error) {
+	err := c.repo.UpdateCartItem(ctx, clientId, productId, quantity)
+	if err != nil {
+		return entity.Cart{}, fmt.Errorf("usecase: failed to update cart item: %w", err)
+	}
+	return c.GetCart(ctx, clientId)
+}
+
+func (c *Cart) RemoveItem(ctx context.Context, clientId, productId string) (entity.Cart, error) {
+	err := c.repo.DeleteCartItem(ctx, clientId, productId)
+	if err != nil {
+		return entity.Cart{}, fmt.Errorf("usecase: failed to remove cart item: %w", err)
+	}
+	return c.GetCart(ctx, clientId)
+}
+
+// Checkout оформляет заказ из корзины и очищает её в одной транзакции, чтобы
+// повтор запроса не создал второй заказ. Если в корзине есть помеченные позиции,
+// заказ не создаётся.
+func (c *Cart) Checkout(ctx context.Context, clientId, actor string) (entity.Order, entity.Cart, error) {
+	cart, err := c.GetCart(ctx, clientId)
+	if err != nil {
+		return entity.Order{}, entity.Cart{}, err
+	}
+	if len(cart.Lines) == 0 {
+		return entity.Order{}, cart, apperr.ErrCartEmpty
+	}
+	if !cart.CanCheckout {
+		return entity.Order{}, cart, apperr.ErrCartUnavailable
+	}
+
+	order := entity.Order{
+		ClientId: clientId,
+		Items:    make([]entity.OrderItem, 0, len(cart.Lines)),
+	}
+	for _, line := range cart.Lines {
+		order.Items = append(order.Items, entity.OrderItem{
+			ProductId: line.ProductId,
+			Quantity:  line.Quantity,
+		})
+	}
+
+	order, err = newOrder(order)
+	if err != nil {
+		return entity.Order{}, cart, err
+	}
+
+	err = c.tx.WithinTx(ctx, func(tx Tx) error {
+		order, err = tx.Orders().CreateOrder(ctx, order, actor)
+		if err != nil {
+			return fmt.Errorf("usecase: failed to checkout cart: %w", err)
+		}
+		if err = tx.Carts().ClearCart(ctx, clientId); err != nil {
+			return fmt.Errorf("usecase: failed to clear cart: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return entity.Order{}, cart, err
+	}
+	return order, entity.Cart{ClientId: clientId, Lines: make([]entity.CartLine, 0)}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"backend2/internal/apperr"
 	"backend2/internal/entity"
 	"backend2/internal/utils"
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -11,27 +12,28 @@ import (
 )
 
 type ClientRepository interface {
-	CreateClient(newClient entity.Client) (entity.Client, error)
-	UpdateClient(id string, newClient entity.Client) (entity.Client, error)
-	DeleteClient(id string) error
-	GetAllClients(limit, offset int) ([]entity.Client, error)
-	GetClientById(id string) (entity.Client, error)
-	GetClients(name, surName string) ([]entity.Client, error)
+	CreateClient(ctx context.Context, newClient entity.Client) (entity.Client, error)
+	UpdateClient(ctx context.Context, id string, patch entity.ClientPatch) error
+	SoftDeleteClient(ctx context.Context, id string) error
+	RestoreClient(ctx context.Context, id string) error
+	DeleteClient(ctx context.Context, id string, cascade bool, actor string) error
+	GetAllClients(ctx context.Context, filter entity.ClientFilter) ([]entity.Client, int, error)
+	GetClientById(ctx context.Context, id string) (entity.Client, error)
 }
 
 type Client struct {
 	repo ClientRepository
-	adr  AddressRepo
+	tx   TxManager
 }
 
-func NewClient(repo ClientRepository, adrRepo AddressRepo) *Client {
+func NewClient(repo ClientRepository, tx TxManager) *Client {
 	return &Client{
 		repo: repo,
-		adr:  adrRepo,
+		tx:   tx,
 	}
 }
 
-func (c *Client) CreateClient(client entity.Client) (entity.Client, error) {
+func (c *Client) CreateClient(ctx context.Context, client entity.Client) (entity.Client, error) {
 
 	id, err := utils.GenerateUUID()
 
@@ -51,61 +53,116 @@ func (c *Client) CreateClient(client entity.Client) (entity.Client, error) {
 		Street:  client.Street,
 	}
 
-	_, err = c.adr.Save(newAdr)
+	caId, err := utils.GenerateUUID()
 	if err != nil {
-		return entity.Client{}, fmt.Errorf("usecase: failed to add address: %w", err)
+		return entity.Client{}, fmt.Errorf("usecase: generate client address id: %w", err)
 	}
 
 	client.Id = id
 	client.AddressId = addrId
 	client.RegistrationDate = time.Now().UTC()
 
-	res, err := c.repo.CreateClient(client)
+	// адрес, клиент и запись в адресной книге создаются вместе или не создаются вовсе
+	var res entity.Client
+	err = c.tx.WithinTx(ctx, func(tx Tx) error {
+		_, err := tx.Addresses().Save(ctx, newAdr)
+		if err != nil {
+			return fmt.Errorf("usecase: failed to add address: %w", err)
+		}
+
+		res, err = tx.Clients().CreateClient(ctx, client)
+		if err != nil {
+			log.Printf("usecase: failed to create client: %v", err)
+			return fmt.Errorf("usecase: failed to add client: %w", err)
+		}
+
+		// адрес из карточки становится первым адресом в адресной книге клиента
+		err = tx.Addresses().LinkClientAddress(ctx, entity.ClientAddress{
+			Id:                caId,
+			ClientId:          id,
+			Label:             entity.AddressLabelHome,
+			IsDefaultShipping: true,
+			IsDefaultBilling:  true,
+			CreatedAt:         client.RegistrationDate,
+			Address:           newAdr,
+		})
+		if err != nil {
+			return fmt.Errorf("usecase: failed to link client address: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		log.Printf("usecase: failed to create client: %w", err)
-		return entity.Client{}, fmt.Errorf("usecase: failed to add client: %w", err)
+		return entity.Client{}, err
 	}
 	return res, nil
 }
 
-func (c *Client) UpdateClient(id string, client entity.Client) (entity.Client, error) {
+// UpdateClient меняет только переданные поля клиента и его адреса.
+func (c *Client) UpdateClient(ctx context.Context, id string, patch entity.ClientPatch) (entity.Client, error) {
 
-	client, err := c.repo.UpdateClient(id, client)
+	err := c.repo.UpdateClient(ctx, id, patch)
 	if err != nil {
 		if errors.Is(err, apperr.ErrClientNotFound) {
 			return entity.Client{}, apperr.ErrClientNotFound
 		}
-		return entity.Client{}, fmt.Errorf("usecase: get client: %w", err)
+		return entity.Client{}, fmt.Errorf("usecase: update client: %w", err)
 	}
 
-	newClient, err := c.repo.GetClientById(id)
+	newClient, err := c.repo.GetClientById(ctx, id)
 	if err != nil {
 		return entity.Client{}, fmt.Errorf("usecase: get updated client: %w", err)
 	}
 	return newClient, nil
 }
 
-func (c *Client) DeleteClient(id string) error {
-	err := c.repo.DeleteClient(id)
+// DeleteClient по умолчанию помечает клиента удалённым, с opts.Hard удаляет физически.
+func (c *Client) DeleteClient(ctx context.Context, id string, opts entity.DeleteOptions, actor string) error {
+	var err error
+	if opts.Hard {
+		err = c.repo.DeleteClient(ctx, id, opts.Cascade, actor)
+	} else {
+		err = c.repo.SoftDeleteClient(ctx, id)
+	}
 	if err != nil {
 		return fmt.Errorf("usecase: failed to delete client: %w", err)
 	}
 	return nil
 }
 
-func (c *Client) GetAllClients(limit, offset int) ([]entity.Client, error) {
+func (c *Client) RestoreClient(ctx context.Context, id string) (entity.Client, error) {
+	err := c.repo.RestoreClient(ctx, id)
+	if err != nil {
+		return entity.Client{}, fmt.Errorf("usecase: failed to restore client: %w", err)
+	}
 
-	clients, err := c.repo.GetAllClients(limit, offset)
+	client, err := c.repo.GetClientById(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("usecase: failed to get all clients: %w", err)
+		return entity.Client{}, fmt.Errorf("usecase: get restored client: %w", err)
 	}
-	return clients, nil
+	return client, nil
 }
 
-func (c *Client) GetClientsByNameSurname(name, surname string) ([]entity.Client, error) {
-	clients, err := c.repo.GetClients(name, surname)
+func (c *Client) GetAllClients(ctx context.Context, filter entity.ClientFilter) (entity.Page[entity.Client], error) {
+
+	clients, total, err := c.repo.GetAllClients(ctx, filter)
+	if err != nil {
+		return entity.Page[entity.Client]{}, fmt.Errorf("usecase: failed to get all clients: %w", err)
+	}
+	return entity.Page[entity.Client]{
+		Items:  clients,
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}, nil
+}
+
+func (c *Client) GetClientsByNameSurname(ctx context.Context, name, surname string) ([]entity.Client, error) {
+	clients, _, err := c.repo.GetAllClients(ctx, entity.ClientFilter{Name: name, Surname: surname})
 	if err != nil {
 		return nil, fmt.Errorf("usecase: failed to get clients by name and surname: %w", err)
 	}
+	if len(clients) == 0 {
+		return nil, apperr.ErrClientNotFound
+	}
 	return clients, nil
 }
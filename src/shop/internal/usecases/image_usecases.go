@@ -1,9 +1,25 @@
 package usecases
 
 import (
+	"backend2/internal/apperr"
 	"backend2/internal/entity"
 	"backend2/internal/utils"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	_ "golang.org/x/image/webp"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"log"
+	"path"
+	"runtime"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 //добавление изображения (на вход подается byte array изображения и id товара).
@@ -17,73 +33,208 @@ import (
 //Получение изображения по id изображения
 
 type ImageRepo interface {
-	AddImage(productId string, image entity.Image) (entity.Image, error)
-	GetImageById(id string) (entity.Image, error)
-	UpdateImage(image entity.Image) (entity.Image, error)
-	GetProductImageById(productId string) (entity.Image, error)
-	DeleteImage(id string) error
+	AddImage(ctx context.Context, productId string, image entity.Image) (entity.Image, error)
+	GetImageById(ctx context.Context, id string) (entity.Image, error)
+	UpdateImage(ctx context.Context, image entity.Image) (entity.Image, error)
+	GetProductImageById(ctx context.Context, productId string) (entity.Image, error)
+	DeleteImage(ctx context.Context, id string) error
+	GetProductImages(ctx context.Context, productId string) ([]entity.ProductImage, error)
+	ReorderProductImages(ctx context.Context, productId string, imageIds []string) error
+	SetPrimaryImage(ctx context.Context, productId, imageId string) error
+	DetachProductImage(ctx context.Context, productId, imageId string) error
+	GetImageVariant(ctx context.Context, imageId, key string) (entity.Image, error)
+	SaveImageVariant(ctx context.Context, key, sourceSha256 string, variant entity.Image) error
+	PurgeDeletedBlobs(ctx context.Context, limit int) (int, error)
 }
 
 type Image struct {
 	img ImageRepo
+	// resize ограничивает число одновременно пересчитываемых вариантов
+	resize chan struct{}
 }
 
 func NewImage(img ImageRepo) *Image {
-	return &Image{img: img}
+	return &Image{img: img, resize: make(chan struct{}, runtime.NumCPU())}
 }
 
-func (i *Image) AddImage(productId string, img []byte) (entity.Image, error) {
-
-	id, err := utils.GenerateUUID()
+// AddImage проверяет загруженный файл и добавляет изображение в конец галереи товара.
+func (i *Image) AddImage(ctx context.Context, productId, filename string, img []byte) (entity.Image, error) {
+	newImg, err := inspectImage(filename, img)
+	if err != nil {
+		return entity.Image{}, err
+	}
 
+	newImg.Id, err = utils.GenerateUUID()
 	if err != nil {
 		return entity.Image{}, fmt.Errorf("error generating id: %w", err)
 	}
 
-	newImg := entity.Image{
-		Id:    id,
-		Image: img,
-	}
-	newImg, err = i.img.AddImage(productId, newImg)
+	newImg, err = i.img.AddImage(ctx, productId, newImg)
 	if err != nil {
 		return entity.Image{}, fmt.Errorf("error adding image: %w", err)
 	}
 	return newImg, nil
 }
 
-func (i *Image) GetImageById(id string) (entity.Image, error) {
-	img, err := i.img.GetImageById(id)
+func (i *Image) GetImageById(ctx context.Context, id string) (entity.Image, error) {
+	img, err := i.img.GetImageById(ctx, id)
 	if err != nil {
 		return entity.Image{}, fmt.Errorf("error getting image: %w", err)
 	}
 	return img, nil
 }
 
-func (i *Image) UpdateImage(id string, img []byte) (entity.Image, error) {
-
-	newImg := entity.Image{
-		Id:    id,
-		Image: img,
+// UpdateImage заменяет файл изображения вместе с его метаданными.
+func (i *Image) UpdateImage(ctx context.Context, id, filename string, img []byte) (entity.Image, error) {
+	newImg, err := inspectImage(filename, img)
+	if err != nil {
+		return entity.Image{}, err
 	}
-	newImg, err := i.img.UpdateImage(newImg)
+
+	newImg.Id = id
+	newImg, err = i.img.UpdateImage(ctx, newImg)
 	if err != nil {
 		return entity.Image{}, fmt.Errorf("error updating image: %w", err)
 	}
 	return newImg, nil
 }
 
-func (i *Image) GetProductImageById(productId string) (entity.Image, error) {
-	img, err := i.img.GetProductImageById(productId)
+func (i *Image) GetProductImageById(ctx context.Context, productId string) (entity.Image, error) {
+	img, err := i.img.GetProductImageById(ctx, productId)
 	if err != nil {
 		return entity.Image{}, fmt.Errorf("error getting product image: %w", err)
 	}
 	return img, nil
 }
 
-func (i *Image) DeleteImage(id string) error {
-	err := i.img.DeleteImage(id)
+func (i *Image) DeleteImage(ctx context.Context, id string) error {
+	err := i.img.DeleteImage(ctx, id)
 	if err != nil {
 		return fmt.Errorf("error deleting image: %w", err)
 	}
 	return nil
 }
+
+// GetProductImages возвращает галерею товара по порядку.
+func (i *Image) GetProductImages(ctx context.Context, productId string) ([]entity.ProductImage, error) {
+	images, err := i.img.GetProductImages(ctx, productId)
+	if err != nil {
+		return nil, fmt.Errorf("error getting product images: %w", err)
+	}
+	return images, nil
+}
+
+// ReorderProductImages расставляет галерею товара в порядке imageIds и возвращает её.
+func (i *Image) ReorderProductImages(ctx context.Context, productId string, imageIds []string) ([]entity.ProductImage, error) {
+	seen := make(map[string]struct{}, len(imageIds))
+	for _, id := range imageIds {
+		if _, ok := seen[id]; ok {
+			return nil, apperr.ErrImageOrderMismatch
+		}
+		seen[id] = struct{}{}
+	}
+
+	err := i.img.ReorderProductImages(ctx, productId, imageIds)
+	if err != nil {
+		return nil, fmt.Errorf("error reordering product images: %w", err)
+	}
+	return i.GetProductImages(ctx, productId)
+}
+
+// SetPrimaryImage делает изображение основным и возвращает галерею товара.
+func (i *Image) SetPrimaryImage(ctx context.Context, productId, imageId string) ([]entity.ProductImage, error) {
+	err := i.img.SetPrimaryImage(ctx, productId, imageId)
+	if err != nil {
+		return nil, fmt.Errorf("error setting primary image: %w", err)
+	}
+	return i.GetProductImages(ctx, productId)
+}
+
+func (i *Image) DetachProductImage(ctx context.Context, productId, imageId string) error {
+	err := i.img.DetachProductImage(ctx, productId, imageId)
+	if err != nil {
+		return fmt.Errorf("error detaching product image: %w", err)
+	}
+	return nil
+}
+
+// blobPurgeBatch — сколько файлов удалённых изображений очищается за один проход.
+const blobPurgeBatch = 100
+
+// RunBlobSweeper периодически удаляет из хранилища файлы удалённых и заменённых
+// изображений, пока не отменён ctx.
+func (i *Image) RunBlobSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			removed, err := i.img.PurgeDeletedBlobs(ctx, blobPurgeBatch)
+			if err != nil {
+				log.Printf("image blob sweeper: %v", err)
+			}
+			if removed > 0 {
+				log.Printf("image blob sweeper: removed %d files", removed)
+			}
+		}
+	}
+}
+
+// maxImageFilename — сколько символов имени файла сохраняется.
+const maxImageFilename = 255
+
+// maxImagePixels — наибольшее число пикселей изображения. Без ограничения маленький
+// файл с огромными размерами занял бы при декодировании гигабайты памяти.
+const maxImagePixels = 25_000_000
+
+// imageFormats — поддерживаемые форматы: имя формата из image.DecodeConfig и MIME-тип.
+// Декодеры других форматов, даже если они зарегистрированы, не принимаются.
+var imageFormats = map[string]string{
+	"jpeg": entity.ImageMimeJPEG,
+	"png":  entity.ImageMimePNG,
+	"webp": entity.ImageMimeWebP,
+	"gif":  entity.ImageMimeGIF,
+}
+
+// inspectImage определяет формат по содержимому файла, а не по имени или
+// заголовкам запроса, и заполняет метаданные изображения.
+func inspectImage(filename string, data []byte) (entity.Image, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return entity.Image{}, fmt.Errorf("%w: %v", apperr.ErrImageUnsupported, err)
+	}
+	mimeType, ok := imageFormats[format]
+	if !ok {
+		return entity.Image{}, fmt.Errorf("%w: %s", apperr.ErrImageUnsupported, format)
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return entity.Image{}, fmt.Errorf("%w: %dx%d", apperr.ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+
+	sum := sha256.Sum256(data)
+	return entity.Image{
+		Image:    data,
+		Filename: cleanImageFilename(filename),
+		MimeType: mimeType,
+		Size:     int64(len(data)),
+		Width:    cfg.Width,
+		Height:   cfg.Height,
+		Sha256:   hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// cleanImageFilename оставляет от присланного имени только последний элемент пути.
+func cleanImageFilename(filename string) string {
+	name := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if name == "." || name == "/" || !utf8.ValidString(name) {
+		return ""
+	}
+	for utf8.RuneCountInString(name) > maxImageFilename {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
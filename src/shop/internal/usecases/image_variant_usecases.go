@@ -0,0 +1,166 @@
+package usecases
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"backend2/internal/imaging"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"path"
+	"slices"
+	"strings"
+)
+
+// variantFormats — форматы, в которые можно перекодировать вариант.
+var variantFormats = map[string]string{
+	"jpeg": entity.ImageMimeJPEG,
+	"jpg":  entity.ImageMimeJPEG,
+	"png":  entity.ImageMimePNG,
+	"webp": entity.ImageMimeWebP,
+	"gif":  entity.ImageMimeGIF,
+}
+
+var variantFits = map[string]imaging.Fit{
+	entity.ImageFitContain: imaging.FitContain,
+	entity.ImageFitCover:   imaging.FitCover,
+	entity.ImageFitFill:    imaging.FitFill,
+}
+
+// GetImageVariant возвращает уменьшенное изображение. Вариант считается при
+// первом запросе и сохраняется; пока файл изображения не заменён, дальше он
+// берётся из кэша.
+func (i *Image) GetImageVariant(ctx context.Context, id string, variant entity.ImageVariant) (entity.Image, error) {
+	variant, err := normalizeImageVariant(variant)
+	if err != nil {
+		return entity.Image{}, err
+	}
+	key := variant.Key()
+
+	cached, err := i.img.GetImageVariant(ctx, id, key)
+	if err == nil {
+		cached.Filename = variantFilename(cached.Filename, cached.MimeType)
+		sum := sha256.Sum256(cached.Image)
+		cached.Sha256 = hex.EncodeToString(sum[:])
+		return cached, nil
+	}
+	if !errors.Is(err, apperr.ErrImageNotFound) {
+		return entity.Image{}, fmt.Errorf("error getting image variant: %w", err)
+	}
+
+	src, err := i.img.GetImageById(ctx, id)
+	if err != nil {
+		return entity.Image{}, fmt.Errorf("error getting image: %w", err)
+	}
+	if variant.Format == "" {
+		variant.Format = src.MimeType
+	}
+	if variant.Width == 0 && variant.Height == 0 && variant.Format == src.MimeType {
+		return src, nil
+	}
+
+	select {
+	case i.resize <- struct{}{}:
+		defer func() { <-i.resize }()
+	case <-ctx.Done():
+		return entity.Image{}, ctx.Err()
+	}
+
+	out, err := renderImageVariant(src, variant)
+	if err != nil {
+		return entity.Image{}, err
+	}
+	// не сохранённый вариант просто посчитается ещё раз
+	if err := i.img.SaveImageVariant(ctx, key, src.Sha256, out); err != nil {
+		log.Printf("usecase: image %s variant %s was not cached: %v", id, key, err)
+	}
+	return out, nil
+}
+
+// normalizeImageVariant проверяет параметры варианта и приводит формат к MIME-типу.
+func normalizeImageVariant(v entity.ImageVariant) (entity.ImageVariant, error) {
+	for _, side := range []int{v.Width, v.Height} {
+		if side != 0 && !slices.Contains(entity.ImageVariantSizes, side) {
+			return entity.ImageVariant{}, fmt.Errorf("%w: size %d is not allowed", apperr.ErrImageVariantInvalid, side)
+		}
+	}
+
+	if v.Fit == "" {
+		v.Fit = entity.ImageFitContain
+	}
+	if _, ok := variantFits[v.Fit]; !ok {
+		return entity.ImageVariant{}, fmt.Errorf("%w: fit %q", apperr.ErrImageVariantInvalid, v.Fit)
+	}
+	// у изображения в исходном размере вписывать нечего
+	if v.Width == 0 && v.Height == 0 {
+		v.Fit = entity.ImageFitContain
+	}
+
+	if v.Format != "" {
+		mimeType, ok := variantFormats[strings.ToLower(strings.TrimPrefix(v.Format, "image/"))]
+		if !ok {
+			return entity.ImageVariant{}, fmt.Errorf("%w: format %q", apperr.ErrImageVariantInvalid, v.Format)
+		}
+		v.Format = mimeType
+	}
+	return v, nil
+}
+
+// renderImageVariant декодирует исходник, уменьшает его и кодирует в формат варианта.
+func renderImageVariant(src entity.Image, v entity.ImageVariant) (entity.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(src.Image))
+	if err != nil {
+		return entity.Image{}, fmt.Errorf("%w: %v", apperr.ErrImageUnsupported, err)
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return entity.Image{}, fmt.Errorf("%w: %dx%d", apperr.ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+	m, _, err := image.Decode(bytes.NewReader(src.Image))
+	if err != nil {
+		return entity.Image{}, fmt.Errorf("%w: %v", apperr.ErrImageUnsupported, err)
+	}
+
+	resized := imaging.Resize(m, v.Width, v.Height, variantFits[v.Fit], v.Format == entity.ImageMimeJPEG)
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, resized, v.Format); err != nil {
+		return entity.Image{}, fmt.Errorf("error encoding image variant: %w", err)
+	}
+
+	data := buf.Bytes()
+	sum := sha256.Sum256(data)
+	return entity.Image{
+		Id:           src.Id,
+		Image:        data,
+		Filename:     variantFilename(src.Filename, v.Format),
+		MimeType:     v.Format,
+		Size:         int64(len(data)),
+		Width:        resized.Bounds().Dx(),
+		Height:       resized.Bounds().Dy(),
+		Sha256:       hex.EncodeToString(sum[:]),
+		SourceSha256: src.Sha256,
+		UpdatedAt:    src.UpdatedAt,
+	}, nil
+}
+
+// variantFilename меняет расширение имени файла под формат варианта.
+func variantFilename(filename, mimeType string) string {
+	if filename == "" {
+		return ""
+	}
+	ext := "." + strings.TrimPrefix(mimeType, "image/")
+	if strings.EqualFold(path.Ext(filename), ext) {
+		return filename
+	}
+	if mimeType == entity.ImageMimeJPEG {
+		switch strings.ToLower(path.Ext(filename)) {
+		case ".jpg", ".jpeg":
+			return filename
+		}
+	}
+	return strings.TrimSuffix(filename, path.Ext(filename)) + ext
+}
@@ -0,0 +1,143 @@
+package usecases
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"backend2/internal/utils"
+	"context"
+	"fmt"
+	"time"
+)
+
+type OrderRepository interface {
+	CreateOrder(ctx context.Context, order entity.Order, actor string) (entity.Order, error)
+	GetOrderById(ctx context.Context, id string) (entity.Order, error)
+	GetOrdersByClientId(ctx context.Context, clientId string) ([]entity.Order, error)
+	UpdateOrderStatus(ctx context.Context, id, from, to string) error
+	CancelOrder(ctx context.Context, id, from, actor string) error
+}
+
+// orderTransitions — допустимые переходы статусов заказа.
+var orderTransitions = map[string][]string{
+	entity.OrderStatusNew:     {entity.OrderStatusPaid, entity.OrderStatusCancelled},
+	entity.OrderStatusPaid:    {entity.OrderStatusShipped, entity.OrderStatusCancelled},
+	entity.OrderStatusShipped: {entity.OrderStatusDelivered},
+}
+
+type Order struct {
+	repo OrderRepository
+}
+
+func NewOrder(repo OrderRepository) *Order {
+	return &Order{repo: repo}
+}
+
+func (o *Order) CreateOrder(ctx context.Context, order entity.Order, actor string) (entity.Order, error) {
+	order, err := newOrder(order)
+	if err != nil {
+		return entity.Order{}, err
+	}
+
+	res, err := o.repo.CreateOrder(ctx, order, actor)
+	if err != nil {
+		return entity.Order{}, fmt.Errorf("usecase: failed to create order: %w", err)
+	}
+	return res, nil
+}
+
+// newOrder готовит заказ к сохранению: выдаёт id, схлопывает одинаковые товары
+// и ставит начальный статус.
+func newOrder(order entity.Order) (entity.Order, error) {
+	if len(order.Items) == 0 {
+		return entity.Order{}, apperr.ErrOrderEmpty
+	}
+
+	id, err := utils.GenerateUUID()
+	if err != nil {
+		return entity.Order{}, fmt.Errorf("usecase: generate order id: %w", err)
+	}
+
+	// одинаковые товары схлопываются в одну позицию
+	quantities := make(map[string]int, len(order.Items))
+	productIds := make([]string, 0, len(order.Items))
+	for _, item := range order.Items {
+		if _, ok := quantities[item.ProductId]; !ok {
+			productIds = append(productIds, item.ProductId)
+		}
+		quantities[item.ProductId] += item.Quantity
+	}
+
+	items := make([]entity.OrderItem, 0, len(productIds))
+	for _, productId := range productIds {
+		itemId, err := utils.GenerateUUID()
+		if err != nil {
+			return entity.Order{}, fmt.Errorf("usecase: generate order item id: %w", err)
+		}
+		items = append(items, entity.OrderItem{
+			Id:        itemId,
+			OrderId:   id,
+			ProductId: productId,
+			Quantity:  quantities[productId],
+		})
+	}
+
+	now := time.Now().UTC()
+	order.Id = id
+	order.Status = entity.OrderStatusNew
+	order.CreatedAt = now
+	order.UpdatedAt = now
+	order.Items = items
+	return order, nil
+}
+
+func (o *Order) GetOrderById(ctx context.Context, id string) (entity.Order, error) {
+	order, err := o.repo.GetOrderById(ctx, id)
+	if err != nil {
+		return entity.Order{}, fmt.Errorf("usecase: failed to get order: %w", err)
+	}
+	return order, nil
+}
+
+func (o *Order) GetClientOrders(ctx context.Context, clientId string) ([]entity.Order, error) {
+	orders, err := o.repo.GetOrdersByClientId(ctx, clientId)
+	if err != nil {
+		return nil, fmt.Errorf("usecase: failed to get client orders: %w", err)
+	}
+	return orders, nil
+}
+
+// UpdateOrderStatus переводит заказ в новый статус. Отмена возвращает товары на склад.
+func (o *Order) UpdateOrderStatus(ctx context.Context, id, status, actor string) (entity.Order, error) {
+	order, err := o.repo.GetOrderById(ctx, id)
+	if err != nil {
+		return entity.Order{}, fmt.Errorf("usecase: failed to get order: %w", err)
+	}
+
+	if !canTransition(order.Status, status) {
+		return entity.Order{}, fmt.Errorf("usecase: %s -> %s: %w", order.Status, status, apperr.ErrOrderStatus)
+	}
+
+	if status == entity.OrderStatusCancelled {
+		err = o.repo.CancelOrder(ctx, id, order.Status, actor)
+	} else {
+		err = o.repo.UpdateOrderStatus(ctx, id, order.Status, status)
+	}
+	if err != nil {
+		return entity.Order{}, fmt.Errorf("usecase: failed to update order status: %w", err)
+	}
+
+	updated, err := o.repo.GetOrderById(ctx, id)
+	if err != nil {
+		return entity.Order{}, fmt.Errorf("usecase: failed to get updated order: %w", err)
+	}
+	return updated, nil
+}
+
+func canTransition(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
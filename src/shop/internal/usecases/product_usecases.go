@@ -4,8 +4,12 @@ import (
 	"backend2/internal/apperr"
 	"backend2/internal/entity"
 	"backend2/internal/utils"
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
-	"log"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -22,25 +26,39 @@ import (
 //Удаление товара по id
 
 type Product struct {
-	repo ProductRepository
-	sup  SupplierRepository
-	img  ImageRepo
+	repo      ProductRepository
+	sup       SupplierRepository
+	img       ImageRepo
+	movements StockMovementRepository
+	tx        TxManager
 }
 type ProductRepository interface {
 	// Добавить новый продукт
-	CreateProduct(product entity.Product) (entity.Product, error)
+	CreateProduct(ctx context.Context, product entity.Product, actor string) (entity.Product, error)
 	// Получить продукт по ID
-	GetProductById(id string) (entity.Product, error)
+	GetProductById(ctx context.Context, id string) (entity.Product, error)
 	// Уменьшить остаток по ID на count единиц
-	ReduceProduct(id string, count int) (entity.Product, error)
-	// Получить все продукты
-	GetProducts() ([]entity.Product, error)
-	// Удалить продукт по ID
-	DeleteProduct(id string) error
+	ReduceProduct(ctx context.Context, id string, count int, reason, actor string) (entity.Product, error)
+	// Получить страницу продуктов по фильтру
+	GetProducts(ctx context.Context, filter entity.ProductFilter) ([]entity.Product, int, error)
+	// Пометить продукт удалённым
+	SoftDeleteProduct(ctx context.Context, id string) error
+	// Снять пометку удаления
+	RestoreProduct(ctx context.Context, id string) error
+	// Удалить продукт физически
+	DeleteProduct(ctx context.Context, id string, cascade bool, actor string) error
+	// Полнотекстовый поиск с опечатками
+	SearchProducts(ctx context.Context, q string, limit, offset int) ([]entity.ProductSearchResult, int, error)
+	// Названия товаров по префиксу
+	SuggestProducts(ctx context.Context, prefix string, limit int) ([]string, error)
 }
 
-func NewProduct(repo ProductRepository, supplier SupplierRepository, img ImageRepo) *Product {
-	return &Product{repo, supplier, img}
+type StockMovementRepository interface {
+	GetMovements(ctx context.Context, productId string, from, to time.Time) ([]entity.StockMovement, error)
+}
+
+func NewProduct(repo ProductRepository, supplier SupplierRepository, img ImageRepo, movements StockMovementRepository, tx TxManager) *Product {
+	return &Product{repo, supplier, img, movements, tx}
 }
 
 //{
@@ -54,58 +72,202 @@ func NewProduct(repo ProductRepository, supplier SupplierRepository, img ImageRe
 //image_id: UUID
 //}
 
-func (p *Product) CreateProduct(product entity.Product) (entity.Product, error) {
+func (p *Product) CreateProduct(ctx context.Context, product entity.Product, actor string) (entity.Product, error) {
 
 	id, err := utils.GenerateUUID()
 	if err != nil {
 		return entity.Product{}, fmt.Errorf("error generating UUID: %w", err)
 	}
 
-	_, err = p.sup.GetSupplierById(product.SupplierId)
-	if err != nil {
-		return entity.Product{}, apperr.ErrSupplierNotFound
-	}
-
 	product.Id = id
 	product.LastUpdate = time.Now()
-	
-	product, err = p.repo.CreateProduct(product)
-	log.Println(product)
+
+	// поставщик проверяется в той же транзакции, в которой создаётся товар
+	err = p.tx.WithinTx(ctx, func(tx Tx) error {
+		_, err := tx.Suppliers().GetSupplierById(ctx, product.SupplierId)
+		if err != nil {
+			return fmt.Errorf("error getting supplier: %w", err)
+		}
+
+		product, err = tx.Products().CreateProduct(ctx, product, actor)
+		if err != nil {
+			return fmt.Errorf("error creating product: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return entity.Product{}, fmt.Errorf("error creating product: %w", err)
+		return entity.Product{}, err
 	}
 	return product, nil
 }
 
-func (p *Product) GetProductById(id string) (entity.Product, error) {
-	product, err := p.repo.GetProductById(id)
+func (p *Product) GetProductById(ctx context.Context, id string) (entity.Product, error) {
+	product, err := p.repo.GetProductById(ctx, id)
 	if err != nil {
 		return entity.Product{}, err
 	}
 	return product, nil
 }
 
-func (p *Product) ReduceProduct(id string, count int) (entity.Product, error) {
-	product, err := p.repo.ReduceProduct(id, count)
-	product.LastUpdate = time.Now()
+func (p *Product) ReduceProduct(ctx context.Context, id string, count int, reason, actor string) (entity.Product, error) {
+	product, err := p.repo.ReduceProduct(ctx, id, count, reason, actor)
 	if err != nil {
 		return entity.Product{}, err
 	}
 	return product, nil
 }
 
-func (p *Product) GetProducts() ([]entity.Product, error) {
-	products, err := p.repo.GetProducts()
+// GetProducts отдаёт страницу товаров. cursor — next_cursor предыдущей страницы,
+// он действителен только с той же сортировкой и без offset.
+func (p *Product) GetProducts(ctx context.Context, filter entity.ProductFilter, cursor string) (entity.ProductPage, error) {
+	if cursor != "" {
+		if filter.Offset != 0 {
+			return entity.ProductPage{}, apperr.ErrInvalidCursor
+		}
+		after, err := decodeProductCursor(cursor)
+		if err != nil {
+			return entity.ProductPage{}, err
+		}
+		if after.Sort != filter.Sort || after.Desc != filter.Desc || !validProductSortValue(after.Value, after.Sort) {
+			return entity.ProductPage{}, apperr.ErrInvalidCursor
+		}
+		filter.After = &after
+	}
+
+	// лишняя строка показывает, есть ли следующая страница
+	limit := filter.Limit
+	filter.Limit++
+	products, total, err := p.repo.GetProducts(ctx, filter)
+	if err != nil {
+		return entity.ProductPage{}, err
+	}
+
+	page := entity.ProductPage{Page: entity.Page[entity.Product]{
+		Items:  products,
+		Total:  total,
+		Limit:  limit,
+		Offset: filter.Offset,
+	}}
+	if len(products) > limit {
+		page.Items = products[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor, err = encodeProductCursor(entity.ProductCursor{
+			Sort:  filter.Sort,
+			Desc:  filter.Desc,
+			Value: productSortValue(last, filter.Sort),
+			Id:    last.Id,
+		})
+		if err != nil {
+			return entity.ProductPage{}, err
+		}
+	}
+	return page, nil
+}
+
+// GetSupplierProducts отдаёт страницу товаров поставщика по тем же правилам, что и GetProducts.
+func (p *Product) GetSupplierProducts(ctx context.Context, supplierId string, filter entity.ProductFilter, cursor string) (entity.ProductPage, error) {
+	_, err := p.sup.GetSupplierById(ctx, supplierId)
+	if err != nil {
+		return entity.ProductPage{}, fmt.Errorf("failed to get supplier: %w", err)
+	}
+
+	filter.SupplierId = supplierId
+	return p.GetProducts(ctx, filter, cursor)
+}
+
+func productSortValue(product entity.Product, sort string) string {
+	switch sort {
+	case entity.ProductSortPrice:
+		return strconv.FormatFloat(product.Price, 'g', -1, 64)
+	case entity.ProductSortStock:
+		return strconv.Itoa(product.AvailableStock)
+	case entity.ProductSortLastUpdate:
+		// last_update_date хранится без часового пояса
+		return product.LastUpdate.Format("2006-01-02T15:04:05.999999")
+	default:
+		return product.Name
+	}
+}
+
+func validProductSortValue(value, sort string) bool {
+	var err error
+	switch sort {
+	case entity.ProductSortPrice:
+		_, err = strconv.ParseFloat(value, 64)
+	case entity.ProductSortStock:
+		_, err = strconv.Atoi(value)
+	case entity.ProductSortLastUpdate:
+		_, err = time.Parse("2006-01-02T15:04:05.999999", value)
+	}
+	return err == nil
+}
+
+func encodeProductCursor(cursor entity.ProductCursor) (string, error) {
+	raw, err := json.Marshal(cursor)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodeProductCursor(cursor string) (entity.ProductCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return entity.ProductCursor{}, apperr.ErrInvalidCursor
+	}
+	var res entity.ProductCursor
+	if err := json.Unmarshal(raw, &res); err != nil || res.Id == "" {
+		return entity.ProductCursor{}, apperr.ErrInvalidCursor
+	}
+	return res, nil
+}
+
+func (p *Product) SearchProducts(ctx context.Context, q string, limit, offset int) (entity.ProductSearchPage, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return entity.ProductSearchPage{}, apperr.ErrEmptySearch
+	}
+
+	results, total, err := p.repo.SearchProducts(ctx, q, limit, offset)
+	if err != nil {
+		return entity.ProductSearchPage{}, err
+	}
+	return entity.ProductSearchPage{Results: results, Total: total}, nil
+}
+
+func (p *Product) SuggestProducts(ctx context.Context, prefix string, limit int) ([]string, error) {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return []string{}, nil
+	}
+
+	names, err := p.repo.SuggestProducts(ctx, prefix, limit)
 	if err != nil {
 		return nil, err
 	}
-	return products, nil
+	return names, nil
+}
+
+// DeleteProduct по умолчанию помечает товар удалённым, с opts.Hard удаляет физически.
+func (p *Product) DeleteProduct(ctx context.Context, id string, opts entity.DeleteOptions, actor string) error {
+	if opts.Hard {
+		return p.repo.DeleteProduct(ctx, id, opts.Cascade, actor)
+	}
+	return p.repo.SoftDeleteProduct(ctx, id)
 }
 
-func (p *Product) DeleteProduct(id string) error {
-	err := p.repo.DeleteProduct(id)
+func (p *Product) RestoreProduct(ctx context.Context, id string) (entity.Product, error) {
+	err := p.repo.RestoreProduct(ctx, id)
 	if err != nil {
-		return err
+		return entity.Product{}, err
+	}
+	return p.repo.GetProductById(ctx, id)
+}
+
+func (p *Product) GetMovements(ctx context.Context, productId string, from, to time.Time) ([]entity.StockMovement, error) {
+	movements, err := p.movements.GetMovements(ctx, productId, from, to)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return movements, nil
 }
@@ -0,0 +1,148 @@
+package usecases
+
+import (
+	"backend2/internal/apperr"
+	"backend2/internal/entity"
+	"backend2/internal/utils"
+	"context"
+	"fmt"
+	"time"
+)
+
+type PurchaseOrderRepository interface {
+	CreatePurchaseOrder(ctx context.Context, order entity.PurchaseOrder) (entity.PurchaseOrder, error)
+	GetPurchaseOrderById(ctx context.Context, id string) (entity.PurchaseOrder, error)
+	GetPurchaseOrdersBySupplierId(ctx context.Context, supplierId string, statuses []string) ([]entity.PurchaseOrder, error)
+	ReceivePurchaseOrder(ctx context.Context, id string, received map[string]int, actor string) error
+	CancelPurchaseOrder(ctx context.Context, id string) error
+}
+
+type PurchaseOrder struct {
+	repo      PurchaseOrderRepository
+	suppliers SupplierRepository
+	products  ProductRepository
+}
+
+func NewPurchaseOrder(repo PurchaseOrderRepository, suppliers SupplierRepository, products ProductRepository) *PurchaseOrder {
+	return &PurchaseOrder{repo: repo, suppliers: suppliers, products: products}
+}
+
+// CreatePurchaseOrder оформляет закупку у поставщика. Все товары должны быть его товарами.
+func (p *PurchaseOrder) CreatePurchaseOrder(ctx context.Context, order entity.PurchaseOrder) (entity.PurchaseOrder, error) {
+	if len(order.Items) == 0 {
+		return entity.PurchaseOrder{}, apperr.ErrPurchaseOrderEmpty
+	}
+
+	_, err := p.suppliers.GetSupplierById(ctx, order.SupplierId)
+	if err != nil {
+		return entity.PurchaseOrder{}, fmt.Errorf("usecase: failed to get supplier: %w", err)
+	}
+
+	id, err := utils.GenerateUUID()
+	if err != nil {
+		return entity.PurchaseOrder{}, fmt.Errorf("usecase: generate purchase order id: %w", err)
+	}
+
+	// одинаковые товары схлопываются в одну позицию, цена берётся из первой
+	merged := make(map[string]entity.PurchaseOrderItem, len(order.Items))
+	productIds := make([]string, 0, len(order.Items))
+	for _, item := range order.Items {
+		existing, ok := merged[item.ProductId]
+		if !ok {
+			productIds = append(productIds, item.ProductId)
+			existing = item
+			existing.Quantity = 0
+		}
+		existing.Quantity += item.Quantity
+		merged[item.ProductId] = existing
+	}
+
+	items := make([]entity.PurchaseOrderItem, 0, len(productIds))
+	for _, productId := range productIds {
+		product, err := p.products.GetProductById(ctx, productId)
+		if err != nil {
+			return entity.PurchaseOrder{}, fmt.Errorf("usecase: product %s: %w", productId, err)
+		}
+		if product.SupplierId != order.SupplierId {
+			return entity.PurchaseOrder{}, fmt.Errorf("usecase: product %s: %w", productId, apperr.ErrPurchaseOrderSupplierMismatch)
+		}
+
+		itemId, err := utils.GenerateUUID()
+		if err != nil {
+			return entity.PurchaseOrder{}, fmt.Errorf("usecase: generate purchase order item id: %w", err)
+		}
+		item := merged[productId]
+		item.Id = itemId
+		item.PurchaseOrderId = id
+		item.ReceivedQuantity = 0
+		items = append(items, item)
+	}
+
+	now := time.Now().UTC()
+	order.Id = id
+	order.Status = entity.PurchaseOrderStatusOpen
+	order.CreatedAt = now
+	order.UpdatedAt = now
+	order.Items = items
+
+	res, err := p.repo.CreatePurchaseOrder(ctx, order)
+	if err != nil {
+		return entity.PurchaseOrder{}, fmt.Errorf("usecase: failed to create purchase order: %w", err)
+	}
+	return res, nil
+}
+
+func (p *PurchaseOrder) GetPurchaseOrderById(ctx context.Context, id string) (entity.PurchaseOrder, error) {
+	order, err := p.repo.GetPurchaseOrderById(ctx, id)
+	if err != nil {
+		return entity.PurchaseOrder{}, fmt.Errorf("usecase: failed to get purchase order: %w", err)
+	}
+	return order, nil
+}
+
+// GetOpenPurchaseOrders возвращает закупки поставщика, по которым ещё ждём товар.
+func (p *PurchaseOrder) GetOpenPurchaseOrders(ctx context.Context, supplierId string) ([]entity.PurchaseOrder, error) {
+	_, err := p.suppliers.GetSupplierById(ctx, supplierId)
+	if err != nil {
+		return nil, fmt.Errorf("usecase: failed to get supplier: %w", err)
+	}
+
+	statuses := []string{entity.PurchaseOrderStatusOpen, entity.PurchaseOrderStatusPartiallyReceived}
+	orders, err := p.repo.GetPurchaseOrdersBySupplierId(ctx, supplierId, statuses)
+	if err != nil {
+		return nil, fmt.Errorf("usecase: failed to get purchase orders: %w", err)
+	}
+	return orders, nil
+}
+
+// ReceivePurchaseOrder принимает товар на склад. Пустой items — приёмка всего остатка закупки.
+func (p *PurchaseOrder) ReceivePurchaseOrder(ctx context.Context, id string, items []entity.PurchaseOrderItem, actor string) (entity.PurchaseOrder, error) {
+	received := make(map[string]int, len(items))
+	for _, item := range items {
+		received[item.ProductId] += item.Quantity
+	}
+
+	err := p.repo.ReceivePurchaseOrder(ctx, id, received, actor)
+	if err != nil {
+		return entity.PurchaseOrder{}, fmt.Errorf("usecase: failed to receive purchase order: %w", err)
+	}
+
+	order, err := p.repo.GetPurchaseOrderById(ctx, id)
+	if err != nil {
+		return entity.PurchaseOrder{}, fmt.Errorf("usecase: failed to get received purchase order: %w", err)
+	}
+	return order, nil
+}
+
+func (p *PurchaseOrder) CancelPurchaseOrder(ctx context.Context, id string) (entity.PurchaseOrder, error) {
+	err := p.repo.CancelPurchaseOrder(ctx, id)
+	if err != nil {
+		return entity.PurchaseOrder{}, fmt.Errorf("usecase: failed to cancel purchase order: %w", err)
+	}
+
+	order, err := p.repo.GetPurchaseOrderById(ctx, id)
+	if err != nil {
+		return entity.PurchaseOrder{}, fmt.Errorf("usecase: failed to get cancelled purchase order: %w", err)
+	}
+	return order, nil
+}
@@ -3,27 +3,30 @@ package usecases
 import (
 	"backend2/internal/entity"
 	"backend2/internal/utils"
+	"context"
 	"fmt"
 )
 
 type Supplier struct {
 	repo SupplierRepository
-	adr  AddressRepo
+	tx   TxManager
 }
 
 type SupplierRepository interface {
-	CreateSupplier(supplier entity.Supplier) (entity.Supplier, error)
-	GetSupplierById(id string) (entity.Supplier, error)
-	UpdateSupplier(id string, supplier entity.Supplier) (entity.Supplier, error)
-	DeleteSupplierById(id string) error
-	GetAllSuppliers() ([]entity.Supplier, error)
+	CreateSupplier(ctx context.Context, supplier entity.Supplier) (entity.Supplier, error)
+	GetSupplierById(ctx context.Context, id string) (entity.Supplier, error)
+	UpdateSupplier(ctx context.Context, id string, patch entity.SupplierPatch) error
+	SoftDeleteSupplier(ctx context.Context, id string) error
+	RestoreSupplier(ctx context.Context, id string) error
+	DeleteSupplierById(ctx context.Context, id string, cascade bool, actor string) error
+	GetAllSuppliers(ctx context.Context, filter entity.SupplierFilter) ([]entity.Supplier, int, error)
 }
 
-func NewSupplier(repo SupplierRepository, addressRepo AddressRepo) *Supplier {
-	return &Supplier{repo, addressRepo}
+func NewSupplier(repo SupplierRepository, tx TxManager) *Supplier {
+	return &Supplier{repo, tx}
 }
 
-func (s *Supplier) CreateSupplier(supplier entity.Supplier) (entity.Supplier, error) {
+func (s *Supplier) CreateSupplier(ctx context.Context, supplier entity.Supplier) (entity.Supplier, error) {
 	id, err := utils.GenerateUUID()
 	if err != nil {
 		return entity.Supplier{}, fmt.Errorf("failed to generate supplier id: %w", err)
@@ -34,59 +37,103 @@ func (s *Supplier) CreateSupplier(supplier entity.Supplier) (entity.Supplier, er
 		return entity.Supplier{}, fmt.Errorf("failed to generate address id: %w", err)
 	}
 
-	_, err = s.adr.Save(entity.Address{
-		ID:      adrId,
-		Country: supplier.Address.Country,
-		City:    supplier.Address.City,
-		Street:  supplier.Address.Street,
-	})
-
-	if err != nil {
-		return entity.Supplier{}, fmt.Errorf("failed to save address: %w", err)
-	}
-
 	supplier.Id = id
 	supplier.AddressId = adrId
 
-	_, err = s.repo.CreateSupplier(supplier)
-	resp, err := s.repo.GetSupplierById(supplier.Id)
+	// без поставщика его адрес не нужен, поэтому оба создаются в одной транзакции
+	var resp entity.Supplier
+	err = s.tx.WithinTx(ctx, func(tx Tx) error {
+		_, err := tx.Addresses().Save(ctx, entity.Address{
+			ID:      adrId,
+			Country: supplier.Address.Country,
+			City:    supplier.Address.City,
+			Street:  supplier.Address.Street,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save address: %w", err)
+		}
+
+		_, err = tx.Suppliers().CreateSupplier(ctx, supplier)
+		if err != nil {
+			return fmt.Errorf("failed to save supplier: %w", err)
+		}
+
+		resp, err = tx.Suppliers().GetSupplierById(ctx, supplier.Id)
+		if err != nil {
+			return fmt.Errorf("failed to get created supplier: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return entity.Supplier{}, fmt.Errorf("failed to save supplier: %w", err)
+		return entity.Supplier{}, err
 	}
 	return resp, nil
 }
 
-func (s *Supplier) GetSupplierById(id string) (entity.Supplier, error) {
-	supplier, err := s.repo.GetSupplierById(id)
+func (s *Supplier) GetSupplierById(ctx context.Context, id string) (entity.Supplier, error) {
+	supplier, err := s.repo.GetSupplierById(ctx, id)
 	if err != nil {
 		return entity.Supplier{}, fmt.Errorf("failed to get supplier: %w", err)
 	}
 	return supplier, nil
 }
 
-func (s *Supplier) GetAllSuppliers() ([]entity.Supplier, error) {
-	suppliers, err := s.repo.GetAllSuppliers()
+func (s *Supplier) GetAllSuppliers(ctx context.Context, filter entity.SupplierFilter) (entity.Page[entity.Supplier], error) {
+	suppliers, total, err := s.repo.GetAllSuppliers(ctx, filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get all suppliers: %w", err)
+		return entity.Page[entity.Supplier]{}, fmt.Errorf("failed to get all suppliers: %w", err)
 	}
-	return suppliers, nil
+	return entity.Page[entity.Supplier]{
+		Items:  suppliers,
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}, nil
 }
 
-func (s *Supplier) UpdateAddressSupplier(supplierId string, supplier entity.Supplier) (entity.Supplier, error) {
-
-	supplier, err := s.repo.UpdateSupplier(supplierId, supplier)
-
+// UpdateSupplier меняет только переданные поля поставщика и его адреса.
+func (s *Supplier) UpdateSupplier(ctx context.Context, id string, patch entity.SupplierPatch) (entity.Supplier, error) {
+	err := s.repo.UpdateSupplier(ctx, id, patch)
 	if err != nil {
-		return entity.Supplier{}, fmt.Errorf("failed to update address: %w", err)
+		return entity.Supplier{}, fmt.Errorf("failed to update supplier: %w", err)
 	}
 
+	supplier, err := s.repo.GetSupplierById(ctx, id)
+	if err != nil {
+		return entity.Supplier{}, fmt.Errorf("failed to get updated supplier: %w", err)
+	}
 	return supplier, nil
 }
 
-func (s *Supplier) DeleteSupplierById(id string) error {
-	err := s.repo.DeleteSupplierById(id)
+// ReplaceSupplier заменяет название, телефон и адрес поставщика целиком.
+func (s *Supplier) ReplaceSupplier(ctx context.Context, id string, supplier entity.Supplier) (entity.Supplier, error) {
+	return s.UpdateSupplier(ctx, id, entity.SupplierPatch{
+		Name:        &supplier.Name,
+		PhoneNumber: &supplier.PhoneNumber,
+		Country:     &supplier.Address.Country,
+		City:        &supplier.Address.City,
+		Street:      &supplier.Address.Street,
+	})
+}
+
+// DeleteSupplierById по умолчанию помечает поставщика удалённым, с opts.Hard удаляет физически.
+func (s *Supplier) DeleteSupplierById(ctx context.Context, id string, opts entity.DeleteOptions, actor string) error {
+	var err error
+	if opts.Hard {
+		err = s.repo.DeleteSupplierById(ctx, id, opts.Cascade, actor)
+	} else {
+		err = s.repo.SoftDeleteSupplier(ctx, id)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete supplier: %w", err)
 	}
 	return nil
 }
+
+func (s *Supplier) RestoreSupplier(ctx context.Context, id string) (entity.Supplier, error) {
+	err := s.repo.RestoreSupplier(ctx, id)
+	if err != nil {
+		return entity.Supplier{}, fmt.Errorf("failed to restore supplier: %w", err)
+	}
+	return s.GetSupplierById(ctx, id)
+}
@@ -0,0 +1,20 @@
+package usecases
+
+import "context"
+
+// TxManager выполняет fn в одной транзакции: если fn вернула ошибку,
+// все изменения, сделанные через tx, откатываются.
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(tx Tx) error) error
+}
+
+// Tx — репозитории, работающие внутри одной транзакции.
+type Tx interface {
+	Addresses() AddressRepo
+	Clients() ClientRepository
+	Suppliers() SupplierRepository
+	Products() ProductRepository
+	Images() ImageRepo
+	Orders() OrderRepository
+	Carts() CartRepository
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
 	"github.com/google/uuid"
 )
@@ -14,3 +16,12 @@ func GenerateUUID() (string, error) {
 	}
 	return uuidV4.String(), nil
 }
+
+// GenerateOpaqueToken возвращает случайную строку из 32 байт в base64url.
+func GenerateOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.New("token generation failed")
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}